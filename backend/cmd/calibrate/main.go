@@ -0,0 +1,75 @@
+// Command calibrate recomputes cross-provider score calibration: it scores
+// the reference set with every source still missing a sample, fits a new
+// calibration version and recalibrates saved analyses. Running servers pick
+// the new version up within CALIBRATION_RELOAD_INTERVAL and recalibrate what
+// they saved in the meantime.
+//
+//	go run ./cmd/calibrate [-rescore]
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"log"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/ahmetcoskunkizilkaya/mewify/backend/internal/config"
+	"github.com/ahmetcoskunkizilkaya/mewify/backend/internal/database"
+	"github.com/ahmetcoskunkizilkaya/mewify/backend/internal/events"
+	"github.com/ahmetcoskunkizilkaya/mewify/backend/internal/imaging"
+	"github.com/ahmetcoskunkizilkaya/mewify/backend/internal/llm"
+	"github.com/ahmetcoskunkizilkaya/mewify/backend/internal/prompts"
+	"github.com/ahmetcoskunkizilkaya/mewify/backend/internal/services"
+)
+
+func main() {
+	rescore := flag.Bool("rescore", false, "score every reference image again, not only missing samples")
+	flag.Parse()
+
+	cfg := config.Load()
+	if cfg.DBPassword == "" {
+		log.Fatal("DB_PASSWORD environment variable is required")
+	}
+	database.InitDB(cfg)
+
+	promptRegistry, err := prompts.Default()
+	if err != nil {
+		log.Fatalf("Failed to load built-in prompt templates: %v", err)
+	}
+	if cfg.PromptTemplateDir != "" {
+		if err := promptRegistry.LoadDir(cfg.PromptTemplateDir); err != nil {
+			log.Fatalf("Failed to load prompt templates: %v", err)
+		}
+	}
+	if err := services.NewPromptService(database.DB, promptRegistry).Load(); err != nil {
+		log.Printf("Failed to load prompt templates from database: %v", err)
+	}
+
+	llmClient := llm.NewClientFromConfig(cfg)
+	imagePipeline := imaging.NewPipeline(imaging.Options{
+		MinDimension:  cfg.ImageMinDimension,
+		MaxDimension:  cfg.ImageMaxDimension,
+		CanonicalSize: cfg.ImageCanonicalSize,
+		MaxBytes:      cfg.ImageMaxBytes,
+	})
+	llmSpendService := services.NewLLMSpendService(database.DB, cfg)
+	aiAnalysisService := services.NewAiAnalysisService(database.DB, llmClient, events.NewBus(), promptRegistry, llmSpendService)
+	calibrationService := services.NewCalibrationService(database.DB, aiAnalysisService, llmClient, imagePipeline, cfg)
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	result, err := calibrationService.Recompute(ctx, *rescore)
+	if err != nil {
+		log.Fatalf("Calibration failed: %v", err)
+	}
+
+	enc := json.NewEncoder(os.Stdout)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(result); err != nil {
+		log.Fatal(err)
+	}
+}
@@ -1,6 +1,7 @@
 package main
 
 import (
+	"context"
 	"log"
 	"os"
 	"os/signal"
@@ -9,10 +10,16 @@ import (
 
 	"github.com/ahmetcoskunkizilkaya/mewify/backend/internal/config"
 	"github.com/ahmetcoskunkizilkaya/mewify/backend/internal/database"
+	"github.com/ahmetcoskunkizilkaya/mewify/backend/internal/events"
 	"github.com/ahmetcoskunkizilkaya/mewify/backend/internal/handlers"
+	"github.com/ahmetcoskunkizilkaya/mewify/backend/internal/imaging"
+	"github.com/ahmetcoskunkizilkaya/mewify/backend/internal/llm"
 	"github.com/ahmetcoskunkizilkaya/mewify/backend/internal/middleware"
+	"github.com/ahmetcoskunkizilkaya/mewify/backend/internal/prompts"
 	"github.com/ahmetcoskunkizilkaya/mewify/backend/internal/routes"
+	"github.com/ahmetcoskunkizilkaya/mewify/backend/internal/seeds"
 	"github.com/ahmetcoskunkizilkaya/mewify/backend/internal/services"
+	"github.com/ahmetcoskunkizilkaya/mewify/backend/internal/storage"
 	"github.com/gofiber/fiber/v2"
 	"github.com/gofiber/fiber/v2/middleware/limiter"
 	fiberlogger "github.com/gofiber/fiber/v2/middleware/logger"
@@ -33,31 +40,144 @@ func main() {
 	// Database
 	database.InitDB(cfg)
 
+	// Domain events
+	bus := events.NewBus()
+
+	// LLM providers shared by every AI feature
+	llmClient := llm.NewClientFromConfig(cfg)
+	if !llmClient.HasVisionProvider() {
+		log.Println("WARNING: no configured LLM provider accepts images (see LLM_VISION_MODELS); every face scan will get deterministic scores")
+	}
+
+	// Photo ingestion and storage
+	imagePipeline := imaging.NewPipeline(imaging.Options{
+		MinDimension:  cfg.ImageMinDimension,
+		MaxDimension:  cfg.ImageMaxDimension,
+		MaxPixels:     cfg.ImageMaxPixels,
+		CanonicalSize: cfg.ImageCanonicalSize,
+		MaxBytes:      cfg.ImageMaxBytes,
+	})
+	var qualityGate *imaging.QualityGate
+	if cfg.QualityGateEnabled {
+		var detector imaging.FaceDetector
+		if cfg.QualityFaceDetector == "skin" {
+			detector = imaging.NewSkinDetector()
+		}
+		qualityGate = imaging.NewQualityGate(imaging.QualityOptions{
+			MinResolution: cfg.QualityMinResolution,
+			MinSharpness:  cfg.QualityMinSharpness,
+			Detector:      detector,
+		})
+	}
+	blobStore, err := storage.NewFromConfig(cfg)
+	if err != nil {
+		log.Fatalf("Failed to initialize blob storage: %v", err)
+	}
+
+	// Prompt templates: built-in, then files, then database rows
+	promptRegistry, err := prompts.Default()
+	if err != nil {
+		log.Fatalf("Failed to load built-in prompt templates: %v", err)
+	}
+	if cfg.PromptTemplateDir != "" {
+		if err := promptRegistry.LoadDir(cfg.PromptTemplateDir); err != nil {
+			log.Fatalf("Failed to load prompt templates: %v", err)
+		}
+	}
+
 	// Services
-	authService := services.NewAuthService(database.DB, cfg)
-	subscriptionService := services.NewSubscriptionService(database.DB)
+	blobService := services.NewBlobService(database.DB, blobStore, cfg)
+	authService := services.NewAuthService(database.DB, cfg, blobService)
+	subscriptionService := services.NewSubscriptionService(database.DB, bus, cfg)
 	moderationService := services.NewModerationService(database.DB)
-	faceAnalysisService := services.NewFaceAnalysisService(database.DB)
-	mewingService := services.NewMewingService(database.DB)
-	glowPlanService := services.NewGlowPlanService(database.DB, cfg)
-	aiAnalysisService := services.NewAiAnalysisService(database.DB, cfg)
-	usageService := services.NewUsageService(database.DB)
+	faceAnalysisService := services.NewFaceAnalysisService(database.DB, bus, blobService)
+	mewingService := services.NewMewingService(database.DB, bus, imagePipeline, blobService)
+	llmSpendService := services.NewLLMSpendService(database.DB, cfg)
+	glowPlanService := services.NewGlowPlanService(database.DB, llmClient, bus, promptRegistry, llmSpendService)
+	aiAnalysisService := services.NewAiAnalysisService(database.DB, llmClient, bus, promptRegistry, llmSpendService)
+	calibrationService := services.NewCalibrationService(database.DB, aiAnalysisService, llmClient, imagePipeline, cfg)
+	usageService := services.NewUsageService(database.DB, subscriptionService, cfg)
+	gamificationService := services.NewGamificationService(database.DB)
+	monetizationService := services.NewMonetizationService(database.DB, gamificationService)
+	premiumContentService := services.NewPremiumContentService(database.DB, subscriptionService)
+	outboxService := services.NewOutboxService(database.DB, cfg)
+	promptService := services.NewPromptService(database.DB, promptRegistry)
+	analysisJobService := services.NewAnalysisJobService(database.DB, aiAnalysisService, calibrationService, usageService, imagePipeline, qualityGate, blobService, cfg)
+
+	if err := promptService.Load(); err != nil {
+		log.Printf("Failed to load prompt templates from database: %v", err)
+	}
+	if err := calibrationService.Load(); err != nil {
+		log.Printf("Failed to load score calibration: %v", err)
+	}
+
+	// Event subscribers
+	gamificationService.RegisterEventHandlers(bus)
+	monetizationService.RegisterEventHandlers(bus)
+
+	// Outbox delivery
+	gamificationService.RegisterOutboxHandlers(outboxService)
+	monetizationService.RegisterOutboxHandlers(outboxService)
+	blobService.RegisterOutboxHandlers(outboxService)
+	dispatchCtx, stopDispatcher := context.WithCancel(context.Background())
+	dispatcherDone := make(chan struct{})
+	go func() {
+		outboxService.Run(dispatchCtx, cfg.OutboxPollInterval)
+		close(dispatcherDone)
+	}()
+
+	// Analysis workers
+	workerCtx, stopWorkers := context.WithCancel(context.Background())
+	workersDone := make(chan struct{})
+	go func() {
+		analysisJobService.Run(workerCtx, cfg.AnalysisJobPollInterval)
+		close(workersDone)
+	}()
+
+	// Calibration versions written by cmd/calibrate or other servers
+	calibrationCtx, stopCalibration := context.WithCancel(context.Background())
+	go calibrationService.Watch(calibrationCtx, cfg.CalibrationReloadInterval)
+
+	// Seed data
+	if err := seeds.SeedAchievements(database.DB); err != nil {
+		log.Printf("Failed to seed achievements: %v", err)
+	}
+	if err := monetizationService.SeedIntroOffers(); err != nil {
+		log.Printf("Failed to seed intro offers: %v", err)
+	}
+	if err := monetizationService.SeedAvatarItems(); err != nil {
+		log.Printf("Failed to seed avatar items: %v", err)
+	}
+	if err := premiumContentService.SeedSubscriptionTiers(); err != nil {
+		log.Printf("Failed to seed subscription tiers: %v", err)
+	}
+	if err := premiumContentService.SeedExercises(); err != nil {
+		log.Printf("Failed to seed exercises: %v", err)
+	}
 
 	// Handlers
 	authHandler := handlers.NewAuthHandler(authService)
-	healthHandler := handlers.NewHealthHandler()
+	healthHandler := handlers.NewHealthHandler(llmClient)
 	webhookHandler := handlers.NewWebhookHandler(subscriptionService, cfg)
 	moderationHandler := handlers.NewModerationHandler(moderationService)
-	faceAnalysisHandler := handlers.NewFaceAnalysisHandler(faceAnalysisService)
+	faceAnalysisHandler := handlers.NewFaceAnalysisHandler(faceAnalysisService, blobService)
 	mewingHandler := handlers.NewMewingHandler(mewingService)
 	glowPlanHandler := handlers.NewGlowPlanHandler(glowPlanService)
-	aiAnalysisHandler := handlers.NewAiAnalysisHandler(aiAnalysisService, usageService)
+	aiAnalysisHandler := handlers.NewAiAnalysisHandler(analysisJobService, usageService, blobService)
 	usageHandler := handlers.NewUsageHandler(usageService)
 	legalHandler := handlers.NewLegalHandler()
+	gamificationHandler := handlers.NewGamificationHandler(gamificationService)
+	monetizationHandler := handlers.NewMonetizationHandler(monetizationService, gamificationService)
+	premiumContentHandler := handlers.NewPremiumContentHandler(premiumContentService)
+	outboxHandler := handlers.NewOutboxHandler(outboxService)
+	blobHandler := handlers.NewBlobHandler(blobStore)
+	promptHandler := handlers.NewPromptHandler(promptService)
+	llmSpendHandler := handlers.NewLLMSpendHandler(llmSpendService)
+	calibrationHandler := handlers.NewCalibrationHandler(calibrationService)
 
 	// Fiber app
 	app := fiber.New(fiber.Config{
-		BodyLimit:    4 * 1024 * 1024, // 4MB
+		BodyLimit:    12 * 1024 * 1024, // 12MB, a multi-angle scan carries three photos
 		ErrorHandler: customErrorHandler,
 	})
 
@@ -68,6 +188,7 @@ func main() {
 		Format: "${time} | ${status} | ${latency} | ${ip} | ${method} | ${path}\n",
 	}))
 	app.Use(middleware.CORS(cfg))
+	app.Use(middleware.Locale())
 
 	// Rate limiter on auth endpoints
 	authLimiter := limiter.New(limiter.Config{
@@ -78,7 +199,7 @@ func main() {
 	app.Use("/api/auth", authLimiter)
 
 	// Routes
-	routes.Setup(app, cfg, authHandler, healthHandler, webhookHandler, moderationHandler, faceAnalysisHandler, mewingHandler, glowPlanHandler, aiAnalysisHandler, usageHandler, legalHandler)
+	routes.Setup(app, cfg, subscriptionService, authHandler, healthHandler, webhookHandler, moderationHandler, faceAnalysisHandler, mewingHandler, glowPlanHandler, aiAnalysisHandler, usageHandler, legalHandler, gamificationHandler, monetizationHandler, premiumContentHandler, outboxHandler, blobHandler, promptHandler, llmSpendHandler, calibrationHandler)
 
 	// Graceful shutdown
 	quit := make(chan os.Signal, 1)
@@ -94,9 +215,16 @@ func main() {
 
 	<-quit
 	log.Println("Shutting down server...")
-	if err := app.Shutdown(); err != nil {
-		log.Fatalf("Server shutdown error: %v", err)
+	// Analysis event streams stay open for minutes, so in-flight requests get
+	// a bounded grace period instead of holding shutdown up
+	if err := app.ShutdownWithTimeout(10 * time.Second); err != nil {
+		log.Printf("Server shutdown: %v", err)
 	}
+	stopWorkers()
+	<-workersDone
+	stopCalibration()
+	stopDispatcher()
+	<-dispatcherDone
 	log.Println("Server stopped")
 }
 
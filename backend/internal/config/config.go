@@ -2,6 +2,7 @@ package config
 
 import (
 	"os"
+	"strconv"
 	"time"
 )
 
@@ -24,6 +25,7 @@ type Config struct {
 	AdminToken   string
 
 	RevenueCatWebhookAuth string
+	RevenueCatProducts    string
 	GLMAPIKey             string
 	GLMAPIURL             string
 	GLMModel              string
@@ -31,10 +33,76 @@ type Config struct {
 	DeepSeekAPIURL        string
 	DeepSeekModel         string
 	LLMTimeout            time.Duration
+	LLMVisionModels       string
+	LLMProviders          string
+	LLMMaxRetries         int
+
+	LLMBreakerWindow       time.Duration
+	LLMBreakerMinRequests  int
+	LLMBreakerFailureRate  float64
+	LLMBreakerOpenDuration time.Duration
+
+	LLMPrices               string
+	LLMBudgetDailyUSD       float64
+	LLMBudgetMonthlyUSD     float64
+	LLMUserBudgetDailyUSD   float64
+	LLMUserBudgetMonthlyUSD float64
 
 	OpenAIAPIKey string
+	OpenAIAPIURL string
 	OpenAIModel  string
 
+	AnthropicAPIKey string
+	AnthropicAPIURL string
+	AnthropicModel  string
+
+	OllamaURL   string
+	OllamaModel string
+
+	OutboxPollInterval time.Duration
+	OutboxBatchSize    int
+	OutboxMaxAttempts  int
+
+	AnalysisWorkers         int
+	AnalysisJobPollInterval time.Duration
+	AnalysisJobMaxAttempts  int
+	AnalysisJobLease        time.Duration
+	DuplicateWindow         time.Duration
+	DuplicateMaxDistance    int
+	FallbackConsumesQuota   bool
+
+	PromptTemplateDir string
+
+	CalibrationMethod     string
+	CalibrationTarget     string
+	CalibrationMinSamples int
+	// CalibrationReloadInterval is how often servers check for a calibration
+	// version written by another process
+	CalibrationReloadInterval time.Duration
+
+	ImageMinDimension  int
+	ImageMaxDimension  int
+	ImageMaxPixels     int
+	ImageCanonicalSize int
+	ImageMaxBytes      int
+
+	QualityGateEnabled   bool
+	QualityMinResolution int
+	QualityMinSharpness  float64
+	QualityFaceDetector  string
+
+	StorageDriver        string
+	StorageLocalPath     string
+	StoragePublicURL     string
+	StorageSigningSecret string
+	StorageURLTTL        time.Duration
+	S3Endpoint           string
+	S3Region             string
+	S3Bucket             string
+	S3AccessKey          string
+	S3SecretKey          string
+	S3PathStyle          bool
+
 	Port        string
 	CORSOrigins string
 }
@@ -59,19 +127,115 @@ func Load() *Config {
 		AdminToken:   getEnv("ADMIN_TOKEN", ""),
 
 		RevenueCatWebhookAuth: getEnv("REVENUECAT_WEBHOOK_AUTH", ""),
-		// GLM is primary provider.
+		RevenueCatProducts:    getEnv("REVENUECAT_TIER_PRODUCTS", "monthly_premium:premium,yearly_premium:premium"),
+		// GLM is primary provider. The default model reads images, so face
+		// scans are model-scored out of the box.
 		GLMAPIKey: getEnv("GLM_API_KEY", getEnv("MEWIFY_GLM_API_KEY", "")),
 		GLMAPIURL: getEnv("GLM_API_URL", getEnv("MEWIFY_GLM_API_URL", "https://api.z.ai/api/paas/v4/chat/completions")),
-		GLMModel:  getEnv("GLM_MODEL", getEnv("MEWIFY_GLM_MODEL", "glm-4.7")),
+		GLMModel:  getEnv("GLM_MODEL", getEnv("MEWIFY_GLM_MODEL", "glm-4.5v")),
 		// DeepSeek is secondary fallback provider.
 		DeepSeekAPIKey: getEnv("DEEPSEEK_API_KEY", getEnv("MEWIFY_DEEPSEEK_API_KEY", "")),
 		DeepSeekAPIURL: getEnv("DEEPSEEK_API_URL", getEnv("MEWIFY_DEEPSEEK_API_URL", "https://api.deepseek.com/chat/completions")),
 		DeepSeekModel:  getEnv("DEEPSEEK_MODEL", getEnv("MEWIFY_DEEPSEEK_MODEL", "deepseek-chat")),
 		LLMTimeout:     parseDuration(getEnv("LLM_TIMEOUT", "20s")),
+		// Models that accept image_url content parts; everything else is text-only.
+		LLMVisionModels: getEnv("LLM_VISION_MODELS", "glm-4.5v,glm-4.6v,gpt-4o,gpt-4o-mini,gpt-4.1,gpt-4.1-mini,claude-3-5-sonnet-latest,llava"),
+		// Ordered fallback chain; providers without credentials are skipped.
+		LLMProviders:  getEnv("LLM_PROVIDERS", "glm,deepseek,openai,anthropic"),
+		LLMMaxRetries: parseInt(getEnv("LLM_MAX_RETRIES", "1"), 1),
+
+		// Per-provider circuit breaker: trips once the failure rate over the
+		// window crosses the threshold, then probes again after the open duration.
+		LLMBreakerWindow:       parseDuration(getEnv("LLM_BREAKER_WINDOW", "60s")),
+		LLMBreakerMinRequests:  parseInt(getEnv("LLM_BREAKER_MIN_REQUESTS", "5"), 5),
+		LLMBreakerFailureRate:  parseFloat(getEnv("LLM_BREAKER_FAILURE_RATE", "0.5"), 0.5),
+		LLMBreakerOpenDuration: parseDuration(getEnv("LLM_BREAKER_OPEN_DURATION", "30s")),
+
+		// USD per million prompt:completion tokens, per model. Models missing
+		// here are recorded at zero cost.
+		LLMPrices: getEnv("LLM_PRICES", "glm-4.7=0.6:2.2,glm-4.5v=0.6:1.8,glm-4.6v=0.3:0.9,deepseek-chat=0.28:0.42,gpt-4o=2.5:10,gpt-4o-mini=0.15:0.6,gpt-4.1=2:8,gpt-4.1-mini=0.4:1.6,claude-3-5-sonnet-latest=3:15"),
+		// Spend caps in USD; 0 disables a cap. Once one is reached AI features
+		// answer with deterministic output until the period rolls over.
+		LLMBudgetDailyUSD:       parseFloat(getEnv("LLM_BUDGET_DAILY_USD", "0"), 0),
+		LLMBudgetMonthlyUSD:     parseFloat(getEnv("LLM_BUDGET_MONTHLY_USD", "0"), 0),
+		LLMUserBudgetDailyUSD:   parseFloat(getEnv("LLM_USER_BUDGET_DAILY_USD", "0"), 0),
+		LLMUserBudgetMonthlyUSD: parseFloat(getEnv("LLM_USER_BUDGET_MONTHLY_USD", "0"), 0),
 
 		OpenAIAPIKey: getEnv("OPENAI_API_KEY", ""),
+		OpenAIAPIURL: getEnv("OPENAI_API_URL", "https://api.openai.com/v1/chat/completions"),
 		OpenAIModel:  getEnv("OPENAI_MODEL", "gpt-4o-mini"),
 
+		AnthropicAPIKey: getEnv("ANTHROPIC_API_KEY", ""),
+		AnthropicAPIURL: getEnv("ANTHROPIC_API_URL", "https://api.anthropic.com/v1/messages"),
+		AnthropicModel:  getEnv("ANTHROPIC_MODEL", "claude-3-5-sonnet-latest"),
+
+		OllamaURL:   getEnv("OLLAMA_URL", "http://localhost:11434"),
+		OllamaModel: getEnv("OLLAMA_MODEL", "llava"),
+
+		OutboxPollInterval: parseDuration(getEnv("OUTBOX_POLL_INTERVAL", "2s")),
+		OutboxBatchSize:    parseInt(getEnv("OUTBOX_BATCH_SIZE", "50"), 50),
+		OutboxMaxAttempts:  parseInt(getEnv("OUTBOX_MAX_ATTEMPTS", "8"), 8),
+
+		// Bounded worker pool for queued AI analyses. A running job whose lease
+		// lapses (the worker died mid-analysis) is picked up again.
+		AnalysisWorkers:         parseInt(getEnv("ANALYSIS_WORKERS", "4"), 4),
+		AnalysisJobPollInterval: parseDuration(getEnv("ANALYSIS_JOB_POLL_INTERVAL", "1s")),
+		AnalysisJobMaxAttempts:  parseInt(getEnv("ANALYSIS_JOB_MAX_ATTEMPTS", "3"), 3),
+		AnalysisJobLease:        parseDuration(getEnv("ANALYSIS_JOB_LEASE", "5m")),
+
+		// A photo within DuplicateMaxDistance bits (perceptual hash) of one the
+		// user had scored inside the window returns that result for free.
+		DuplicateWindow:      parseDuration(getEnv("ANALYSIS_DUPLICATE_WINDOW", "24h")),
+		DuplicateMaxDistance: parseInt(getEnv("ANALYSIS_DUPLICATE_MAX_DISTANCE", "4"), 4),
+
+		// Whether a scan answered by the deterministic fallback, because every
+		// AI provider was down, counts against the free daily limit.
+		FallbackConsumesQuota: getEnv("ANALYSIS_FALLBACK_CONSUMES_QUOTA", "false") == "true",
+
+		// Directory of extra prompt template files (*.json), loaded over the
+		// built-in templates; database rows are loaded over both.
+		PromptTemplateDir: getEnv("PROMPT_TEMPLATE_DIR", ""),
+
+		// Score calibration maps each provider's scores onto a common scale,
+		// fitted from the reference set: "linear" or "quantile". The target
+		// scale is the mean of the model providers, or CALIBRATION_TARGET's
+		// scores.
+		CalibrationMethod:         getEnv("CALIBRATION_METHOD", "linear"),
+		CalibrationTarget:         getEnv("CALIBRATION_TARGET", ""),
+		CalibrationMinSamples:     parseInt(getEnv("CALIBRATION_MIN_SAMPLES", "10"), 10),
+		CalibrationReloadInterval: parseDuration(getEnv("CALIBRATION_RELOAD_INTERVAL", "1m")),
+
+		// Uploaded photos are rejected outside these bounds, then scaled so the
+		// long side fits the canonical size. The pixel cap bounds the memory a
+		// small but highly compressed upload can take to decode.
+		ImageMinDimension:  parseInt(getEnv("IMAGE_MIN_DIMENSION", "256"), 256),
+		ImageMaxDimension:  parseInt(getEnv("IMAGE_MAX_DIMENSION", "8000"), 8000),
+		ImageMaxPixels:     parseInt(getEnv("IMAGE_MAX_PIXELS", "24000000"), 24000000),
+		ImageCanonicalSize: parseInt(getEnv("IMAGE_CANONICAL_SIZE", "1024"), 1024),
+		ImageMaxBytes:      parseInt(getEnv("IMAGE_MAX_BYTES", "4194304"), 4194304),
+
+		// Photos for AI analysis must pass resolution, blur, exposure and face
+		// checks before they are queued; rejections cost no scan. The face
+		// detector is "skin" (built-in heuristic) or "none".
+		QualityGateEnabled:   getEnv("QUALITY_GATE_ENABLED", "true") == "true",
+		QualityMinResolution: parseInt(getEnv("QUALITY_MIN_RESOLUTION", "400"), 400),
+		QualityMinSharpness:  parseFloat(getEnv("QUALITY_MIN_SHARPNESS", "40"), 40),
+		QualityFaceDetector:  getEnv("QUALITY_FACE_DETECTOR", "skin"),
+
+		// Photo storage: "local" serves files through signed /api/blobs URLs,
+		// "s3" presigns against any S3-compatible endpoint (AWS, MinIO, R2).
+		StorageDriver:        getEnv("STORAGE_DRIVER", "local"),
+		StorageLocalPath:     getEnv("STORAGE_LOCAL_PATH", "./data/blobs"),
+		StoragePublicURL:     getEnv("STORAGE_PUBLIC_URL", "http://localhost:8080"),
+		StorageSigningSecret: getEnv("STORAGE_SIGNING_SECRET", ""),
+		StorageURLTTL:        parseDuration(getEnv("STORAGE_URL_TTL", "15m")),
+		S3Endpoint:           getEnv("S3_ENDPOINT", ""),
+		S3Region:             getEnv("S3_REGION", "us-east-1"),
+		S3Bucket:             getEnv("S3_BUCKET", ""),
+		S3AccessKey:          getEnv("S3_ACCESS_KEY", ""),
+		S3SecretKey:          getEnv("S3_SECRET_KEY", ""),
+		S3PathStyle:          getEnv("S3_PATH_STYLE", "true") == "true",
+
 		Port:        getEnv("PORT", "8080"),
 		CORSOrigins: getEnv("CORS_ORIGINS", "*"),
 	}
@@ -101,3 +265,19 @@ func parseDuration(s string) time.Duration {
 	}
 	return d
 }
+
+func parseInt(s string, fallback int) int {
+	n, err := strconv.Atoi(s)
+	if err != nil || n < 0 {
+		return fallback
+	}
+	return n
+}
+
+func parseFloat(s string, fallback float64) float64 {
+	f, err := strconv.ParseFloat(s, 64)
+	if err != nil || f < 0 {
+		return fallback
+	}
+	return f
+}
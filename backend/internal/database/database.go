@@ -45,15 +45,86 @@ func InitDB(cfg *config.Config) {
 		&models.MewingGoal{},
 		&models.GlowPlan{},
 		&models.DailyUsage{},
+
+		// Gamification
+		&models.UserGamification{},
+		&models.Achievement{},
+		&models.UserAchievement{},
+		&models.DailyChallenge{},
+		&models.UserDailyChallenge{},
+		&models.WeeklyChallenge{},
+		&models.LeaderboardEntry{},
+		&models.XPTransaction{},
+		&models.Notification{},
+		&models.SocialConnection{},
+		&models.FriendActivity{},
+		&models.Referral{},
+
+		// Monetization
+		&models.UserCurrency{},
+		&models.GemTransaction{},
+		&models.StreakFreeze{},
+		&models.ChestReward{},
+		&models.UserChest{},
+		&models.ChestRewardResult{},
+		&models.UserDecayStatus{},
+		&models.ShareableCard{},
+		&models.FriendConnection{},
+		&models.NudgeHistory{},
+		&models.IntroOffer{},
+		&models.UserIntroOffer{},
+		&models.AvatarItem{},
+		&models.UserAvatarItem{},
+
+		// Premium content
+		&models.MewingExercise{},
+		&models.ExerciseInstruction{},
+		&models.DailyRoutine{},
+		&models.WeeklyPlan{},
+		&models.PersonalizedTip{},
+		&models.TechniqueCorrection{},
+		&models.PremiumFeature{},
+		&models.SubscriptionTier{},
+		&models.UserSubscription{},
+		// Outbox
+		&models.OutboxMessage{},
+		// Analysis jobs
+		&models.AnalysisJob{},
+		&models.ScanSession{},
+		&models.ScanAngleResult{},
+		// Blob storage
+		&models.Blob{},
+		// Prompt templates
+		&models.PromptTemplate{},
+		// LLM spend
+		&models.LLMUsage{},
+		// Score calibration
+		&models.CalibrationReference{},
+		&models.CalibrationSample{},
+		&models.ScoreCalibration{},
 	)
 
 	if err != nil {
 		log.Fatalf("Failed to migrate database: %v", err)
 	}
 
+	if err := backfillAnalysisSource(DB); err != nil {
+		log.Fatalf("Failed to backfill analysis sources: %v", err)
+	}
+
 	log.Println("Database connected and migrated successfully")
 }
 
+// backfillAnalysisSource derives provenance for analyses saved before it was
+// recorded, when AI scans marked themselves through image_url. The marker
+// was written for fallback scores too, so those rows cannot be trusted as
+// model-scored and are treated as estimates.
+func backfillAnalysisSource(db *gorm.DB) error {
+	return db.Model(&models.FaceAnalysis{}).
+		Where("image_url = ? AND source = ?", "ai-analyzed", models.AnalysisSourceManual).
+		Updates(map[string]interface{}{"source": models.AnalysisSourceDeterministic, "image_url": ""}).Error
+}
+
 func GetDB() *gorm.DB {
 	return DB
 }
@@ -0,0 +1,86 @@
+package dto
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// AnalyticsQuery selects the window and bucketing of GET /analyses/analytics.
+// Zero values fall back to a granularity-specific default.
+type AnalyticsQuery struct {
+	Granularity string
+	From        time.Time
+	To          time.Time
+	// Window is the number of periods in each moving average
+	Window int
+}
+
+// AnalyticsResponse is a chart-ready time series of every metric over the
+// same list of periods; each metric's Points line up index for index with
+// Periods.
+type AnalyticsResponse struct {
+	Granularity string            `json:"granularity"`
+	From        time.Time         `json:"from"`
+	To          time.Time         `json:"to"`
+	Window      int               `json:"window"`
+	Periods     []AnalyticsPeriod `json:"periods"`
+	Metrics     []MetricAnalytics `json:"metrics"`
+	Best        *AnalyticsScan    `json:"best"`
+	Worst       *AnalyticsScan    `json:"worst"`
+}
+
+type AnalyticsPeriod struct {
+	Start         time.Time `json:"start"`
+	Label         string    `json:"label"`
+	Scans         int       `json:"scans"`
+	MewingMinutes int       `json:"mewing_minutes"`
+}
+
+type MetricAnalytics struct {
+	Metric  string           `json:"metric"`
+	Average *float64         `json:"average"`
+	Points  []AnalyticsPoint `json:"points"`
+	Trend   TrendStats       `json:"trend"`
+	Best    *AnalyticsScan   `json:"best"`
+	Worst   *AnalyticsScan   `json:"worst"`
+	// MewingCorrelation is Pearson's r between the period averages and the
+	// mewing minutes logged in those periods; nil with too little data
+	MewingCorrelation *CorrelationStats `json:"mewing_correlation"`
+}
+
+// AnalyticsPoint is one period of a metric. Average is nil when the period
+// has no scans; MovingAverage covers the scanned periods in the trailing
+// window.
+type AnalyticsPoint struct {
+	Average       *float64 `json:"average"`
+	MovingAverage *float64 `json:"moving_average"`
+}
+
+// TrendStats is an ordinary least squares fit of score against time.
+type TrendStats struct {
+	// SlopePerDay and SlopePerPeriod are score points gained per unit of time
+	SlopePerDay    float64 `json:"slope_per_day"`
+	SlopePerPeriod float64 `json:"slope_per_period"`
+	StdError       float64 `json:"std_error"`
+	// CILow and CIHigh bound the 95% confidence interval of SlopePerPeriod
+	CILow     float64 `json:"ci_low"`
+	CIHigh    float64 `json:"ci_high"`
+	RSquared  float64 `json:"r_squared"`
+	Samples   int     `json:"samples"`
+	Direction string  `json:"direction"`
+	// Confidence is high, medium, low or insufficient
+	Confidence string `json:"confidence"`
+}
+
+type CorrelationStats struct {
+	R          float64 `json:"r"`
+	Periods    int     `json:"periods"`
+	Confidence string  `json:"confidence"`
+}
+
+type AnalyticsScan struct {
+	AnalysisID uuid.UUID `json:"analysis_id"`
+	Score      float64   `json:"score"`
+	AnalyzedAt time.Time `json:"analyzed_at"`
+}
@@ -0,0 +1,34 @@
+package dto
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type AnalysisJobResponse struct {
+	ID          uuid.UUID             `json:"id"`
+	Status      string                `json:"status"`
+	Attempts    int                   `json:"attempts"`
+	Cached      bool                  `json:"cached"`
+	Charged     bool                  `json:"charged"`
+	Error       string                `json:"error_message,omitempty"`
+	Result      *FaceAnalysisResponse `json:"result,omitempty"`
+	CreatedAt   time.Time             `json:"created_at"`
+	StartedAt   *time.Time            `json:"started_at,omitempty"`
+	CompletedAt *time.Time            `json:"completed_at,omitempty"`
+}
+
+// AnalysisProgressEvent is the data of one server-sent event on
+// GET /analyses/jobs/:id/events. The stage is also the SSE event name.
+type AnalysisProgressEvent struct {
+	RequestID string    `json:"request_id"`
+	JobID     uuid.UUID `json:"job_id"`
+	Stage     string    `json:"stage"`
+	Provider  string    `json:"provider,omitempty"`
+	Attempt   int       `json:"attempt,omitempty"`
+	// Scores are partial results parsed from a streaming reply
+	Scores map[string]float64 `json:"scores,omitempty"`
+	// Job is the final state, sent with done and failed
+	Job *AnalysisJobResponse `json:"job,omitempty"`
+}
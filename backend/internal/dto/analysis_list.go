@@ -0,0 +1,34 @@
+package dto
+
+import "time"
+
+// AnalysisListQuery selects a page of a user's analyses. Zero values leave a
+// filter off; Cursor continues from the page that returned it and must be
+// used with the same sort and order.
+type AnalysisListQuery struct {
+	Limit  int
+	Cursor string
+	// From and To bound analyzed_at by UTC day, both inclusive
+	From       time.Time
+	To         time.Time
+	MinOverall *float64
+	MaxOverall *float64
+	Source     string
+	Tag        string
+	// Sort is analyzed_at or a metric name; Order is asc or desc
+	Sort         string
+	Order        string
+	IncludeTotal bool
+}
+
+// AnalysisListResponse is one page of analyses. NextCursor is empty on the
+// last page; Total is only counted when asked for.
+type AnalysisListResponse struct {
+	Analyses   []FaceAnalysisResponse `json:"analyses"`
+	NextCursor string                 `json:"next_cursor,omitempty"`
+	HasMore    bool                   `json:"has_more"`
+	Limit      int                    `json:"limit"`
+	Sort       string                 `json:"sort"`
+	Order      string                 `json:"order"`
+	Total      *int64                 `json:"total,omitempty"`
+}
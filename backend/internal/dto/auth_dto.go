@@ -41,8 +41,15 @@ type AuthResponse struct {
 }
 
 type UserResponse struct {
-	ID    uuid.UUID `json:"id"`
-	Email string    `json:"email"`
+	ID     uuid.UUID `json:"id"`
+	Email  string    `json:"email"`
+	Locale string    `json:"locale"`
+}
+
+// UpdateLocaleRequest sets the user's language: en, tr or es, or "" to
+// follow Accept-Language.
+type UpdateLocaleRequest struct {
+	Locale string `json:"locale"`
 }
 
 type ErrorResponse struct {
@@ -0,0 +1,46 @@
+package dto
+
+import "time"
+
+type CreateCalibrationReferenceRequest struct {
+	Label       string `json:"label"`
+	ImageBase64 string `json:"image_base64"`
+}
+
+// CalibrationStatus describes the reference set and the mappings in force.
+type CalibrationStatus struct {
+	Version    int    `json:"version"`
+	Method     string `json:"method"`
+	Target     string `json:"target"`
+	References int64  `json:"references"`
+	// Sources lists every provider and model with reference samples, mapped
+	// or not
+	Sources []CalibrationSource `json:"sources"`
+}
+
+type CalibrationSource struct {
+	Provider string               `json:"provider"`
+	Model    string               `json:"model"`
+	Samples  int                  `json:"samples"`
+	Mappings []CalibrationMapping `json:"mappings"`
+}
+
+type CalibrationMapping struct {
+	Metric      string    `json:"metric"`
+	Method      string    `json:"method"`
+	Slope       float64   `json:"slope,omitempty"`
+	Intercept   float64   `json:"intercept,omitempty"`
+	RawKnots    []float64 `json:"raw_knots,omitempty"`
+	TargetKnots []float64 `json:"target_knots,omitempty"`
+	Samples     int       `json:"samples"`
+}
+
+// CalibrationRecomputeResult reports one recompute run.
+type CalibrationRecomputeResult struct {
+	Version         int       `json:"version"`
+	SamplesScored   int       `json:"samples_scored"`
+	SampleErrors    []string  `json:"sample_errors,omitempty"`
+	MappingsFitted  int       `json:"mappings_fitted"`
+	AnalysesUpdated int       `json:"analyses_updated"`
+	CompletedAt     time.Time `json:"completed_at"`
+}
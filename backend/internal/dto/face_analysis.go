@@ -18,9 +18,15 @@ type CreateFaceAnalysisRequest struct {
 	HarmonyScore   float64   `json:"harmony_score" validate:"required,min=1,max=10"`
 	Strengths      []string  `json:"strengths" validate:"max=3"`
 	Improvements   []string  `json:"improvements" validate:"max=3"`
+	Tags           []string  `json:"tags"`
 	AnalyzedAt     time.Time `json:"analyzed_at"`
 }
 
+// UpdateAnalysisTagsRequest replaces an analysis's tags.
+type UpdateAnalysisTagsRequest struct {
+	Tags []string `json:"tags"`
+}
+
 type FaceAnalysisResponse struct {
 	ID             uuid.UUID `json:"id"`
 	UserID         uuid.UUID `json:"user_id"`
@@ -35,15 +41,14 @@ type FaceAnalysisResponse struct {
 	HarmonyScore   float64   `json:"harmony_score"`
 	Strengths      []string  `json:"strengths"`
 	Improvements   []string  `json:"improvements"`
+	Tags           []string  `json:"tags"`
 	AnalyzedAt     time.Time `json:"analyzed_at"`
 	CreatedAt      time.Time `json:"created_at"`
-}
-
-type PaginatedAnalysesResponse struct {
-	Analyses []FaceAnalysisResponse `json:"analyses"`
-	Total    int64                  `json:"total"`
-	Page     int                    `json:"page"`
-	Limit    int                    `json:"limit"`
+	// IsEstimate is true when no model judged the photo and the scores were
+	// derived deterministically instead
+	IsEstimate bool `json:"is_estimate"`
+	// SessionID links the summary of a multi-angle scan to its angles
+	SessionID *uuid.UUID `json:"session_id,omitempty"`
 }
 
 type AnalysisStatsResponse struct {
@@ -62,4 +67,50 @@ type AnalysisStatsResponse struct {
 type MonthlyScore struct {
 	Month string  `json:"month"`
 	Score float64 `json:"score"`
-}
\ No newline at end of file
+}
+
+// AdminAnalysisFilter narrows the admin analysis list. Zero values match all.
+type AdminAnalysisFilter struct {
+	UserID       *uuid.UUID
+	Source       string
+	Provider     string
+	FallbackOnly bool
+}
+
+// DuplicateReportEntry flags one user whose analyses reuse a photo.
+type DuplicateReportEntry struct {
+	UserID uuid.UUID `json:"user_id"`
+	// Reason is shared_photo (also scored by other accounts) or edited_photo
+	// (the user's own photo scored again after small edits)
+	Reason         string      `json:"reason"`
+	AnalysisIDs    []uuid.UUID `json:"analysis_ids"`
+	MatchedUserIDs []uuid.UUID `json:"matched_user_ids,omitempty"`
+	MinDistance    int         `json:"min_distance"`
+	Count          int         `json:"count"`
+}
+
+// MetricDelta is how one score moved between two analyses.
+type MetricDelta struct {
+	Metric string  `json:"metric"`
+	From   float64 `json:"from"`
+	To     float64 `json:"to"`
+	Delta  float64 `json:"delta"`
+	// PercentChange is relative to From; nil when From is zero
+	PercentChange *float64 `json:"percent_change"`
+}
+
+// AnalysisComparisonResponse is the before/after view of two analyses.
+type AnalysisComparisonResponse struct {
+	From                FaceAnalysisResponse `json:"from"`
+	To                  FaceAnalysisResponse `json:"to"`
+	Metrics             []MetricDelta        `json:"metrics"`
+	StrengthsAdded      []string             `json:"strengths_added"`
+	StrengthsRemoved    []string             `json:"strengths_removed"`
+	ImprovementsAdded   []string             `json:"improvements_added"`
+	ImprovementsRemoved []string             `json:"improvements_removed"`
+	// DaysElapsed is negative when To was analyzed before From
+	DaysElapsed int `json:"days_elapsed"`
+	// Mewing logged from the earlier scan's date through the later one's
+	MewingMinutes    int `json:"mewing_minutes"`
+	MewingDaysLogged int `json:"mewing_days_logged"`
+}
@@ -5,4 +5,9 @@ type HealthResponse struct {
 	Status    string `json:"status"`
 	Timestamp string `json:"timestamp"`
 	DB        string `json:"db"`
+	// LLM maps each configured provider to its circuit breaker state
+	LLM map[string]string `json:"llm_providers,omitempty"`
+	// LLMVision is false when no configured provider can see photos, so
+	// face scans fall back to deterministic scores
+	LLMVision bool `json:"llm_vision"`
 }
@@ -0,0 +1,41 @@
+package dto
+
+import "time"
+
+type LLMSpendTotals struct {
+	Calls            int     `json:"calls"`
+	PromptTokens     int64   `json:"prompt_tokens"`
+	CompletionTokens int64   `json:"completion_tokens"`
+	CostUSD          float64 `json:"cost_usd" gorm:"column:cost_usd"`
+	// UnpricedCalls used models missing from the price table
+	UnpricedCalls int `json:"unpriced_calls"`
+}
+
+type LLMSpendGroup struct {
+	Key              string  `json:"key"`
+	Calls            int     `json:"calls"`
+	PromptTokens     int64   `json:"prompt_tokens"`
+	CompletionTokens int64   `json:"completion_tokens"`
+	CostUSD          float64 `json:"cost_usd" gorm:"column:cost_usd"`
+}
+
+type LLMBudgetStatus struct {
+	Period   string  `json:"period"`
+	LimitUSD float64 `json:"limit_usd"`
+	SpentUSD float64 `json:"spent_usd"`
+	Exceeded bool    `json:"exceeded"`
+}
+
+type LLMSpendSummary struct {
+	Since time.Time `json:"since"`
+	LLMSpendTotals
+	ByFeature []LLMSpendGroup `json:"by_feature"`
+	// ByModel is keyed provider/model
+	ByModel  []LLMSpendGroup `json:"by_model"`
+	ByDay    []LLMSpendGroup `json:"by_day"`
+	TopUsers []LLMSpendGroup `json:"top_users"`
+	// Budgets are the global caps; 0 means uncapped
+	Budgets             []LLMBudgetStatus `json:"budgets"`
+	UserDailyLimitUSD   float64           `json:"user_daily_limit_usd"`
+	UserMonthlyLimitUSD float64           `json:"user_monthly_limit_usd"`
+}
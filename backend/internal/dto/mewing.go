@@ -10,7 +10,8 @@ import (
 type LogMewingRequest struct {
 	MewingMinutes  int    `json:"mewingMinutes" validate:"required,min=1,max=1440"`
 	Notes          string `json:"notes"`
-	JawlinePhotoURL string `json:"jawlinePhotoUrl"`
+	// Optional progress photo; replaces any photo already logged today
+	JawlinePhotoBase64 string `json:"jawlinePhotoBase64"`
 }
 
 type UpdateGoalRequest struct {
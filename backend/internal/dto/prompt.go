@@ -0,0 +1,35 @@
+package dto
+
+type PromptTemplateResponse struct {
+	ID        string            `json:"id"`
+	Name      string            `json:"name"`
+	Version   string            `json:"version"`
+	Weight    int               `json:"weight"`
+	Variables map[string]string `json:"variables"`
+}
+
+// AnalysisPromptStats is the overall score distribution of AI-scored
+// analyses produced by one face-analysis prompt version.
+type AnalysisPromptStats struct {
+	PromptVersion string  `json:"prompt_version"`
+	Analyses      int     `json:"analyses"`
+	AvgOverall    float64 `json:"avg_overall"`
+	StddevOverall float64 `json:"stddev_overall"`
+	MinOverall    float64 `json:"min_overall"`
+	MaxOverall    float64 `json:"max_overall"`
+}
+
+// PlanPromptStats is how far users got through plans produced by one
+// glow-plan prompt version.
+type PlanPromptStats struct {
+	PromptVersion  string  `json:"prompt_version"`
+	Plans          int     `json:"plans"`
+	Users          int     `json:"users"`
+	Completed      int     `json:"completed"`
+	CompletionRate float64 `json:"completion_rate"`
+}
+
+type PromptStatsResponse struct {
+	Analyses []AnalysisPromptStats `json:"analyses"`
+	Plans    []PlanPromptStats     `json:"plans"`
+}
@@ -0,0 +1,37 @@
+package dto
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type ScanImage struct {
+	Angle       string `json:"angle"`
+	ImageBase64 string `json:"image_base64"`
+}
+
+// CreateScanSessionRequest carries a front photo and up to two profiles.
+type CreateScanSessionRequest struct {
+	Images []ScanImage `json:"images"`
+}
+
+// ScanSessionResponse is a multi-angle scan: the summary analysis every
+// list and stat uses, the profile averages and each angle's own result.
+type ScanSessionResponse struct {
+	ID                  uuid.UUID            `json:"id"`
+	Summary             FaceAnalysisResponse `json:"summary"`
+	JawAngleScore       *float64             `json:"jaw_angle_score"`
+	ChinProjectionScore *float64             `json:"chin_projection_score"`
+	NeckPostureScore    *float64             `json:"neck_posture_score"`
+	Angles              []ScanAngleResponse  `json:"angles"`
+	CreatedAt           time.Time            `json:"created_at"`
+}
+
+type ScanAngleResponse struct {
+	Angle        string             `json:"angle"`
+	ImageURL     string             `json:"image_url"`
+	Scores       map[string]float64 `json:"scores"`
+	Observations []string           `json:"observations"`
+	IsEstimate   bool               `json:"is_estimate"`
+}
@@ -0,0 +1,66 @@
+package events
+
+import (
+	"fmt"
+	"sync"
+
+	"gorm.io/gorm"
+)
+
+// Event is a domain event published by one module and consumed by others.
+type Event interface {
+	EventName() string
+}
+
+// Handler reacts to an event using the publisher's transaction, so whatever it
+// writes commits or rolls back together with the change that raised the event.
+type Handler func(tx *gorm.DB, event Event) error
+
+// Bus is a synchronous in-process event bus.
+type Bus struct {
+	mu       sync.RWMutex
+	handlers map[string][]Handler
+}
+
+func NewBus() *Bus {
+	return &Bus{handlers: make(map[string][]Handler)}
+}
+
+// Subscribe registers a handler for the named event. Handlers run in
+// registration order.
+func (b *Bus) Subscribe(name string, handler Handler) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.handlers[name] = append(b.handlers[name], handler)
+}
+
+// Publish delivers the event to every subscriber inside tx. The first handler
+// error aborts delivery and is returned so the caller can roll back.
+func (b *Bus) Publish(tx *gorm.DB, event Event) error {
+	if b == nil {
+		return nil
+	}
+
+	b.mu.RLock()
+	handlers := append([]Handler(nil), b.handlers[event.EventName()]...)
+	b.mu.RUnlock()
+
+	for _, handler := range handlers {
+		if err := handler(tx, event); err != nil {
+			return fmt.Errorf("%s handler failed: %w", event.EventName(), err)
+		}
+	}
+	return nil
+}
+
+// On subscribes a handler typed to a concrete event.
+func On[E Event](b *Bus, handler func(tx *gorm.DB, event E) error) {
+	var zero E
+	b.Subscribe(zero.EventName(), func(tx *gorm.DB, event Event) error {
+		typed, ok := event.(E)
+		if !ok {
+			return fmt.Errorf("unexpected event type %T for %s", event, zero.EventName())
+		}
+		return handler(tx, typed)
+	})
+}
@@ -0,0 +1,70 @@
+package events
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const (
+	AnalysisCreatedEvent     = "analysis.created"
+	AnalysisDeletedEvent     = "analysis.deleted"
+	MewingLoggedEvent        = "mewing.logged"
+	PlanItemCompletedEvent   = "glow_plan.item_completed"
+	SubscriptionChangedEvent = "subscription.changed"
+)
+
+// AnalysisCreated is raised when a face analysis is persisted, whether scored
+// by AI or submitted manually. Source tells which.
+type AnalysisCreated struct {
+	UserID       uuid.UUID
+	AnalysisID   uuid.UUID
+	Source       string
+	OverallScore float64
+	AnalyzedAt   time.Time
+}
+
+func (AnalysisCreated) EventName() string { return AnalysisCreatedEvent }
+
+// AnalysisDeleted is raised when a user deletes one of their analyses.
+type AnalysisDeleted struct {
+	UserID     uuid.UUID
+	AnalysisID uuid.UUID
+}
+
+func (AnalysisDeleted) EventName() string { return AnalysisDeletedEvent }
+
+// MewingLogged is raised when today's mewing entry is created or updated.
+// DeltaMinutes is the change against the previously stored value, so
+// subscribers can keep running totals exact across repeated logs.
+type MewingLogged struct {
+	UserID        uuid.UUID
+	ProgressID    uuid.UUID
+	Date          time.Time
+	TotalMinutes  int
+	DeltaMinutes  int
+	GoalCompleted bool // true only on the log that first met the daily goal
+}
+
+func (MewingLogged) EventName() string { return MewingLoggedEvent }
+
+// PlanItemCompleted is raised when a glow plan item is marked complete.
+type PlanItemCompleted struct {
+	UserID   uuid.UUID
+	PlanID   uuid.UUID
+	Category string
+}
+
+func (PlanItemCompleted) EventName() string { return PlanItemCompletedEvent }
+
+// SubscriptionChanged is raised when a RevenueCat webhook changes the status
+// of a subscription linked to a known user.
+type SubscriptionChanged struct {
+	UserID         uuid.UUID
+	SubscriptionID uuid.UUID
+	ProductID      string
+	PreviousStatus string
+	Status         string
+}
+
+func (SubscriptionChanged) EventName() string { return SubscriptionChangedEvent }
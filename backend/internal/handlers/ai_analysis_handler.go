@@ -1,22 +1,29 @@
 package handlers
 
 import (
+	"errors"
+
 	"github.com/gofiber/fiber/v2"
 	"github.com/google/uuid"
 
 	"github.com/ahmetcoskunkizilkaya/mewify/backend/internal/dto"
+	"github.com/ahmetcoskunkizilkaya/mewify/backend/internal/i18n"
+	"github.com/ahmetcoskunkizilkaya/mewify/backend/internal/imaging"
+	"github.com/ahmetcoskunkizilkaya/mewify/backend/internal/models"
 	"github.com/ahmetcoskunkizilkaya/mewify/backend/internal/services"
 )
 
 type AiAnalysisHandler struct {
-	service      *services.AiAnalysisService
+	jobService   *services.AnalysisJobService
 	usageService *services.UsageService
+	blobs        *services.BlobService
 }
 
-func NewAiAnalysisHandler(service *services.AiAnalysisService, usageService *services.UsageService) *AiAnalysisHandler {
+func NewAiAnalysisHandler(jobService *services.AnalysisJobService, usageService *services.UsageService, blobs *services.BlobService) *AiAnalysisHandler {
 	return &AiAnalysisHandler{
-		service:      service,
+		jobService:   jobService,
 		usageService: usageService,
+		blobs:        blobs,
 	}
 }
 
@@ -24,6 +31,10 @@ type aiAnalyzeRequest struct {
 	ImageBase64 string `json:"image_base64"`
 }
 
+// AnalyzeFace queues an AI analysis and returns its job right away. Clients
+// poll GET /analyses/jobs/:id, or follow GET /analyses/jobs/:id/events, for
+// the result. Sending the same Idempotency-Key header again returns the
+// original job.
 func (h *AiAnalysisHandler) AnalyzeFace(c *fiber.Ctx) error {
 	userIDStr := c.Locals("userID").(string)
 	userID, err := uuid.Parse(userIDStr)
@@ -40,50 +51,188 @@ func (h *AiAnalysisHandler) AnalyzeFace(c *fiber.Ctx) error {
 		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": true, "message": "image_base64 is required"})
 	}
 
-	// Check usage limit before calling AI service
-	allowed, _, usageErr := h.usageService.CheckAndIncrementUsage(userID)
-	if usageErr != nil {
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": true, "message": "Failed to check usage limit"})
+	// Reserves a scan from the daily limit; released if the job fails
+	job, err := h.jobService.Submit(userID, req.ImageBase64, c.Get("Idempotency-Key"), requestLocale(c))
+	if err != nil {
+		return h.submitError(c, userID, err)
 	}
-	if !allowed {
-		return c.Status(fiber.StatusTooManyRequests).JSON(fiber.Map{
-			"error":          true,
-			"message":        "Daily scan limit reached. Upgrade to Premium for unlimited scans.",
-			"remaining_uses": 0,
-			"is_premium":     false,
-		})
+
+	return h.submitted(c, userID, job)
+}
+
+// CreateSession queues a multi-angle scan of a front photo and up to two
+// profile photos. It costs one scan, like AnalyzeFace, and its job's result
+// is the session's summary analysis.
+func (h *AiAnalysisHandler) CreateSession(c *fiber.Ctx) error {
+	userIDStr := c.Locals("userID").(string)
+	userID, err := uuid.Parse(userIDStr)
+	if err != nil {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": true, "message": "Invalid user ID"})
+	}
+
+	var req dto.CreateScanSessionRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": true, "message": "Invalid request body"})
+	}
+	if len(req.Images) == 0 || len(req.Images) > len(models.ScanAngles) {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": true, "message": "Send a front photo and up to two profile photos"})
+	}
+
+	images := make(map[string]string, len(req.Images))
+	for _, img := range req.Images {
+		if _, dup := images[img.Angle]; dup {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": true, "message": "Each angle can only be sent once"})
+		}
+		images[img.Angle] = img.ImageBase64
 	}
 
-	analysis, err := h.service.AnalyzeImage(userID, req.ImageBase64)
+	job, err := h.jobService.SubmitSession(userID, images, c.Get("Idempotency-Key"), requestLocale(c))
 	if err != nil {
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": true, "message": "Failed to analyze image: " + err.Error()})
+		return h.submitError(c, userID, err)
 	}
 
-	// Get remaining uses after successful analysis
+	return h.submitted(c, userID, job)
+}
+
+func (h *AiAnalysisHandler) submitted(c *fiber.Ctx, userID uuid.UUID, job *models.AnalysisJob) error {
 	remaining, isPremium, _ := h.usageService.GetRemainingUses(userID)
 
-	response := dto.FaceAnalysisResponse{
-		ID:            analysis.ID,
-		UserID:        analysis.UserID,
-		ImageURL:      analysis.ImageURL,
-		OverallScore:  analysis.OverallScore,
-		SymmetryScore: analysis.SymmetryScore,
-		JawlineScore:  analysis.JawlineScore,
-		SkinScore:     analysis.SkinScore,
-		EyeScore:      analysis.EyeScore,
-		NoseScore:     analysis.NoseScore,
-		LipsScore:     analysis.LipsScore,
-		HarmonyScore:  analysis.HarmonyScore,
-		Strengths:     analysis.Strengths,
-		Improvements:  analysis.Improvements,
-		AnalyzedAt:    analysis.AnalyzedAt,
-		CreatedAt:     analysis.CreatedAt,
-	}
-
-	return c.Status(fiber.StatusCreated).JSON(fiber.Map{
+	// A duplicate photo comes back already answered from the earlier scan
+	status := fiber.StatusAccepted
+	if job.Status == models.AnalysisJobSucceeded {
+		status = fiber.StatusOK
+	}
+
+	return c.Status(status).JSON(fiber.Map{
 		"error":          false,
-		"data":           response,
+		"data":           h.toAnalysisJobResponse(job),
 		"remaining_uses": remaining,
 		"is_premium":     isPremium,
 	})
 }
+
+// submitError maps a rejected submission to its response. Photo rejections
+// name the angle of the photo at fault.
+func (h *AiAnalysisHandler) submitError(c *fiber.Ctx, userID uuid.UUID, err error) error {
+	angle := ""
+	var imageErr *services.ScanImageError
+	if errors.As(err, &imageErr) {
+		angle = imageErr.Angle
+	}
+
+	locale := requestLocale(c)
+	var quality *imaging.QualityError
+	if errors.As(err, &quality) {
+		remaining, isPremium, _ := h.usageService.GetRemainingUses(userID)
+		quality.Report.Localize(locale)
+		return c.Status(fiber.StatusUnprocessableEntity).JSON(fiber.Map{
+			"error":          true,
+			"message":        i18n.T(locale, "scan.low_quality"),
+			"angle":          angle,
+			"issues":         quality.Report.Issues,
+			"quality":        quality.Report,
+			"remaining_uses": remaining,
+			"is_premium":     isPremium,
+		})
+	}
+	if status, ok := imageErrorStatus(err); ok {
+		return c.Status(status).JSON(fiber.Map{"error": true, "message": err.Error(), "angle": angle})
+	}
+	if errors.Is(err, services.ErrInvalidScanAngle) || errors.Is(err, services.ErrFrontPhotoRequired) {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": true, "message": err.Error()})
+	}
+	if errors.Is(err, services.ErrUsageLimitReached) {
+		return c.Status(fiber.StatusTooManyRequests).JSON(fiber.Map{
+			"error":          true,
+			"message":        i18n.T(locale, "scan.limit_reached"),
+			"remaining_uses": 0,
+			"is_premium":     false,
+		})
+	}
+	return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": true, "message": "Failed to queue analysis"})
+}
+
+// GetJob returns the status of a queued analysis, with the result once it
+// has succeeded.
+func (h *AiAnalysisHandler) GetJob(c *fiber.Ctx) error {
+	userIDStr := c.Locals("userID").(string)
+	userID, err := uuid.Parse(userIDStr)
+	if err != nil {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": true, "message": "Invalid user ID"})
+	}
+
+	jobID, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": true, "message": "Invalid job ID"})
+	}
+
+	job, err := h.jobService.GetJob(jobID, userID)
+	if err != nil {
+		if errors.Is(err, services.ErrAnalysisJobNotFound) {
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": true, "message": "Analysis job not found"})
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": true, "message": "Failed to retrieve analysis job"})
+	}
+
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{"error": false, "data": h.toAnalysisJobResponse(job)})
+}
+
+// imageErrorStatus maps ingestion failures to the 4xx status that tells the
+// client what to fix.
+func imageErrorStatus(err error) (int, bool) {
+	switch {
+	case errors.Is(err, imaging.ErrEmptyImage),
+		errors.Is(err, imaging.ErrInvalidEncoding),
+		errors.Is(err, imaging.ErrCorruptImage):
+		return fiber.StatusBadRequest, true
+	case errors.Is(err, imaging.ErrUnsupportedFormat):
+		return fiber.StatusUnsupportedMediaType, true
+	case errors.Is(err, imaging.ErrImageFileTooLarge):
+		return fiber.StatusRequestEntityTooLarge, true
+	case errors.Is(err, imaging.ErrImageTooSmall),
+		errors.Is(err, imaging.ErrImageTooLarge):
+		return fiber.StatusUnprocessableEntity, true
+	}
+	return 0, false
+}
+
+func (h *AiAnalysisHandler) toAnalysisJobResponse(job *models.AnalysisJob) dto.AnalysisJobResponse {
+	response := dto.AnalysisJobResponse{
+		ID:          job.ID,
+		Status:      string(job.Status),
+		Attempts:    job.Attempts,
+		Cached:      job.Cached,
+		Charged:     job.UsageCharged,
+		CreatedAt:   job.CreatedAt,
+		StartedAt:   job.StartedAt,
+		CompletedAt: job.CompletedAt,
+	}
+	if job.Status == models.AnalysisJobFailed {
+		response.Error = "Analysis could not be completed. Your scan was not counted."
+	}
+
+	if analysis := job.Analysis; analysis != nil {
+		response.Result = &dto.FaceAnalysisResponse{
+			ID:            analysis.ID,
+			UserID:        analysis.UserID,
+			ImageURL:      h.blobs.SignedURL(analysis.ImageKey),
+			OverallScore:  analysis.OverallScore,
+			SymmetryScore: analysis.SymmetryScore,
+			JawlineScore:  analysis.JawlineScore,
+			SkinScore:     analysis.SkinScore,
+			EyeScore:      analysis.EyeScore,
+			NoseScore:     analysis.NoseScore,
+			LipsScore:     analysis.LipsScore,
+			HarmonyScore:  analysis.HarmonyScore,
+			Strengths:     analysis.Strengths,
+			Improvements:  analysis.Improvements,
+			Tags:          analysis.Tags,
+			AnalyzedAt:    analysis.AnalyzedAt,
+			CreatedAt:     analysis.CreatedAt,
+			IsEstimate:    analysis.IsEstimate(),
+			SessionID:     analysis.SessionID,
+		}
+	}
+
+	return response
+}
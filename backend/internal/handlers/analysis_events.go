@@ -0,0 +1,175 @@
+package handlers
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+
+	"github.com/ahmetcoskunkizilkaya/mewify/backend/internal/dto"
+	"github.com/ahmetcoskunkizilkaya/mewify/backend/internal/models"
+	"github.com/ahmetcoskunkizilkaya/mewify/backend/internal/services"
+)
+
+const (
+	// Jobs taken by another instance publish no events here, so the stream
+	// also watches the job row.
+	analysisEventsPoll      = 2 * time.Second
+	analysisEventsHeartbeat = 15 * time.Second
+	analysisEventsMaxAge    = 5 * time.Minute
+)
+
+// StreamJob streams a queued analysis as server-sent events: image
+// validated, queued, scoring, each provider attempt, partial scores when the
+// provider streams, saving, and finally done or failed with the job itself.
+// Every event carries the request ID of this stream.
+func (h *AiAnalysisHandler) StreamJob(c *fiber.Ctx) error {
+	userIDStr := c.Locals("userID").(string)
+	userID, err := uuid.Parse(userIDStr)
+	if err != nil {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": true, "message": "Invalid user ID"})
+	}
+
+	jobID, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": true, "message": "Invalid job ID"})
+	}
+
+	// Subscribe first so no stage slips by between the lookup and the stream
+	events, unsubscribe := h.jobService.Subscribe(jobID)
+	job, err := h.jobService.GetJob(jobID, userID)
+	if err != nil {
+		unsubscribe()
+		if errors.Is(err, services.ErrAnalysisJobNotFound) {
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": true, "message": "Analysis job not found"})
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": true, "message": "Failed to retrieve analysis job"})
+	}
+
+	requestID, _ := c.Locals("requestid").(string)
+
+	c.Set(fiber.HeaderContentType, "text/event-stream")
+	c.Set(fiber.HeaderCacheControl, "no-cache")
+	c.Set(fiber.HeaderConnection, "keep-alive")
+	c.Set("X-Accel-Buffering", "no")
+
+	c.Context().SetBodyStreamWriter(func(w *bufio.Writer) {
+		defer unsubscribe()
+		s := &analysisEventStream{w: w, requestID: requestID, jobID: jobID}
+
+		s.send(dto.AnalysisProgressEvent{Stage: services.AnalysisStageValidated})
+		if h.finished(s, job) {
+			return
+		}
+		status := job.Status
+		if status == models.AnalysisJobQueued {
+			s.send(dto.AnalysisProgressEvent{Stage: services.AnalysisStageQueued})
+		} else {
+			s.send(dto.AnalysisProgressEvent{Stage: services.AnalysisStageScoring, Attempt: job.Attempts})
+		}
+
+		poll := time.NewTicker(analysisEventsPoll)
+		defer poll.Stop()
+		heartbeat := time.NewTicker(analysisEventsHeartbeat)
+		defer heartbeat.Stop()
+		deadline := time.After(analysisEventsMaxAge)
+
+		for s.err == nil {
+			select {
+			case event := <-events:
+				switch event.Stage {
+				case services.AnalysisStageDone, services.AnalysisStageFailed:
+					if job, err := h.jobService.GetJob(jobID, userID); err == nil && h.finished(s, job) {
+						return
+					}
+				default:
+					s.send(dto.AnalysisProgressEvent{
+						Stage:    event.Stage,
+						Provider: event.Provider,
+						Attempt:  event.Attempt,
+						Scores:   event.Scores,
+					})
+				}
+
+			case <-poll.C:
+				job, err := h.jobService.GetJob(jobID, userID)
+				if err != nil {
+					continue
+				}
+				if h.finished(s, job) {
+					return
+				}
+				if job.Status != status {
+					status = job.Status
+					stage := services.AnalysisStageScoring
+					if status == models.AnalysisJobQueued {
+						stage = services.AnalysisStageRetrying
+					}
+					s.send(dto.AnalysisProgressEvent{Stage: stage, Attempt: job.Attempts})
+				}
+
+			case <-heartbeat.C:
+				s.comment("keep-alive")
+
+			case <-deadline:
+				// The client reconnects or falls back to polling
+				return
+			}
+		}
+	})
+	return nil
+}
+
+// finished sends the closing event once the job has succeeded or failed.
+func (h *AiAnalysisHandler) finished(s *analysisEventStream, job *models.AnalysisJob) bool {
+	var stage string
+	switch job.Status {
+	case models.AnalysisJobSucceeded:
+		stage = services.AnalysisStageDone
+	case models.AnalysisJobFailed:
+		stage = services.AnalysisStageFailed
+	default:
+		return false
+	}
+	response := h.toAnalysisJobResponse(job)
+	s.send(dto.AnalysisProgressEvent{Stage: stage, Attempt: job.Attempts, Job: &response})
+	return true
+}
+
+// analysisEventStream writes server-sent events until the client goes away.
+type analysisEventStream struct {
+	w         *bufio.Writer
+	requestID string
+	jobID     uuid.UUID
+	seq       int
+	err       error
+}
+
+func (s *analysisEventStream) send(event dto.AnalysisProgressEvent) {
+	if s.err != nil {
+		return
+	}
+	event.RequestID = s.requestID
+	event.JobID = s.jobID
+	data, err := json.Marshal(event)
+	if err != nil {
+		log.Printf("Analysis events: failed to encode %s: %v", event.Stage, err)
+		return
+	}
+	s.seq++
+	fmt.Fprintf(s.w, "id: %d\nevent: %s\ndata: %s\n\n", s.seq, event.Stage, data)
+	s.err = s.w.Flush()
+}
+
+func (s *analysisEventStream) comment(text string) {
+	if s.err != nil {
+		return
+	}
+	fmt.Fprintf(s.w, ": %s\n\n", text)
+	s.err = s.w.Flush()
+}
@@ -207,3 +207,47 @@ func (h *AuthHandler) AppleSignIn(c *fiber.Ctx) error {
 
 	return c.JSON(resp)
 }
+
+// UpdateLocale stores the language the server writes the user's plans,
+// analyses and notifications in. An empty locale follows Accept-Language.
+func (h *AuthHandler) UpdateLocale(c *fiber.Ctx) error {
+	userID, err := extractUserID(c)
+	if err != nil {
+		return c.Status(fiber.StatusUnauthorized).JSON(dto.ErrorResponse{
+			Error: true, Message: "Unauthorized",
+		})
+	}
+
+	var req dto.UpdateLocaleRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(dto.ErrorResponse{
+			Error: true, Message: "Invalid request body",
+		})
+	}
+
+	user, err := h.authService.UpdateLocale(userID, req.Locale)
+	if err != nil {
+		if errors.Is(err, services.ErrUnsupportedLocale) {
+			return c.Status(fiber.StatusBadRequest).JSON(dto.ErrorResponse{
+				Error: true, Message: err.Error(),
+			})
+		}
+		if errors.Is(err, services.ErrUserNotFound) {
+			return c.Status(fiber.StatusNotFound).JSON(dto.ErrorResponse{
+				Error: true, Message: "User not found",
+			})
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(dto.ErrorResponse{
+			Error: true, Message: "Failed to update locale",
+		})
+	}
+
+	return c.JSON(user)
+}
+
+// requestLocale returns the supported language the request's Accept-Language
+// header prefers, or "" when it names none.
+func requestLocale(c *fiber.Ctx) string {
+	locale, _ := c.Locals("locale").(string)
+	return locale
+}
@@ -0,0 +1,42 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/ahmetcoskunkizilkaya/mewify/backend/internal/storage"
+	"github.com/gofiber/fiber/v2"
+)
+
+type BlobHandler struct {
+	local *storage.LocalStore
+}
+
+// NewBlobHandler serves objects for the local store. Other stores hand out
+// URLs that point at the provider directly, so the route answers 404.
+func NewBlobHandler(store storage.BlobStore) *BlobHandler {
+	local, _ := store.(*storage.LocalStore)
+	return &BlobHandler{local: local}
+}
+
+// Serve returns a stored photo when the URL's signature is valid and unexpired.
+func (h *BlobHandler) Serve(c *fiber.Ctx) error {
+	if h.local == nil {
+		return c.Status(http.StatusNotFound).JSON(fiber.Map{"error": true, "message": "Not found"})
+	}
+
+	data, err := h.local.Open(c.Params("*"), c.Query("expires"), c.Query("signature"))
+	if err != nil {
+		switch {
+		case errors.Is(err, storage.ErrInvalidSignature):
+			return c.Status(http.StatusForbidden).JSON(fiber.Map{"error": true, "message": "Link has expired"})
+		case errors.Is(err, storage.ErrBlobNotFound):
+			return c.Status(http.StatusNotFound).JSON(fiber.Map{"error": true, "message": "Not found"})
+		}
+		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{"error": true, "message": "Failed to read image"})
+	}
+
+	c.Set(fiber.HeaderContentType, http.DetectContentType(data))
+	c.Set(fiber.HeaderCacheControl, "private, max-age=300")
+	return c.Send(data)
+}
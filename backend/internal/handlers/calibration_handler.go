@@ -0,0 +1,69 @@
+package handlers
+
+import (
+	"errors"
+
+	"github.com/ahmetcoskunkizilkaya/mewify/backend/internal/dto"
+	"github.com/ahmetcoskunkizilkaya/mewify/backend/internal/services"
+	"github.com/gofiber/fiber/v2"
+)
+
+type CalibrationHandler struct {
+	calibrationService *services.CalibrationService
+}
+
+func NewCalibrationHandler(calibrationService *services.CalibrationService) *CalibrationHandler {
+	return &CalibrationHandler{calibrationService: calibrationService}
+}
+
+// Status returns the calibration in force and the reference samples behind it.
+func (h *CalibrationHandler) Status(c *fiber.Ctx) error {
+	status, err := h.calibrationService.Status()
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(dto.ErrorResponse{
+			Error: true, Message: "Failed to fetch calibration status",
+		})
+	}
+	return c.JSON(status)
+}
+
+// AddReference adds an image to the calibration reference set.
+func (h *CalibrationHandler) AddReference(c *fiber.Ctx) error {
+	var req dto.CreateCalibrationReferenceRequest
+	if err := c.BodyParser(&req); err != nil || req.ImageBase64 == "" {
+		return c.Status(fiber.StatusBadRequest).JSON(dto.ErrorResponse{
+			Error: true, Message: "image_base64 is required",
+		})
+	}
+
+	ref, err := h.calibrationService.AddReference(req.Label, req.ImageBase64)
+	if err != nil {
+		if status, ok := imageErrorStatus(err); ok {
+			return c.Status(status).JSON(dto.ErrorResponse{Error: true, Message: err.Error()})
+		}
+		if errors.Is(err, services.ErrCalibrationReferenceExists) {
+			return c.Status(fiber.StatusConflict).JSON(dto.ErrorResponse{Error: true, Message: err.Error()})
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(dto.ErrorResponse{
+			Error: true, Message: "Failed to add reference image",
+		})
+	}
+	return c.Status(fiber.StatusCreated).JSON(ref)
+}
+
+// Recompute scores references still missing a sample (all of them with
+// ?rescore=true), fits a new calibration version and recalibrates saved
+// analyses. Scoring calls every vision provider, so it can take minutes;
+// `go run ./cmd/calibrate` does the same from a shell.
+func (h *CalibrationHandler) Recompute(c *fiber.Ctx) error {
+	result, err := h.calibrationService.Recompute(c.UserContext(), c.QueryBool("rescore", false))
+	if err != nil {
+		if errors.Is(err, services.ErrInvalidCalibrationMethod) {
+			return c.Status(fiber.StatusUnprocessableEntity).JSON(dto.ErrorResponse{Error: true, Message: err.Error()})
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(dto.ErrorResponse{
+			Error: true, Message: "Failed to recompute calibration",
+		})
+	}
+	return c.JSON(result)
+}
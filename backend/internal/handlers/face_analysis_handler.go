@@ -1,25 +1,39 @@
 package handlers
 
 import (
+	"errors"
 	"strconv"
+	"time"
 
 	"github.com/gofiber/fiber/v2"
 	"github.com/google/uuid"
 
 	"github.com/ahmetcoskunkizilkaya/mewify/backend/internal/dto"
+	"github.com/ahmetcoskunkizilkaya/mewify/backend/internal/models"
 	"github.com/ahmetcoskunkizilkaya/mewify/backend/internal/services"
 )
 
 type FaceAnalysisHandler struct {
 	service services.FaceAnalysisService
+	blobs   *services.BlobService
 }
 
-func NewFaceAnalysisHandler(service services.FaceAnalysisService) *FaceAnalysisHandler {
+func NewFaceAnalysisHandler(service services.FaceAnalysisService, blobs *services.BlobService) *FaceAnalysisHandler {
 	return &FaceAnalysisHandler{
 		service: service,
+		blobs:   blobs,
 	}
 }
 
+// imageURL returns a signed URL for a stored photo, or the URL the client
+// supplied for analyses created without one.
+func (h *FaceAnalysisHandler) imageURL(analysis *models.FaceAnalysis) string {
+	if analysis.ImageKey != "" {
+		return h.blobs.SignedURL(analysis.ImageKey)
+	}
+	return analysis.ImageURL
+}
+
 func (h *FaceAnalysisHandler) Create(c *fiber.Ctx) error {
 	userIDStr := c.Locals("userID").(string)
 	userID, err := uuid.Parse(userIDStr)
@@ -34,13 +48,16 @@ func (h *FaceAnalysisHandler) Create(c *fiber.Ctx) error {
 
 	analysis, err := h.service.CreateAnalysis(userID, req)
 	if err != nil {
+		if errors.Is(err, services.ErrInvalidTags) {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": true, "message": err.Error()})
+		}
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": true, "message": "Failed to create analysis"})
 	}
 
 	response := dto.FaceAnalysisResponse{
 		ID:             analysis.ID,
 		UserID:         analysis.UserID,
-		ImageURL:       analysis.ImageURL,
+		ImageURL:       h.imageURL(analysis),
 		OverallScore:   analysis.OverallScore,
 		SymmetryScore:  analysis.SymmetryScore,
 		JawlineScore:   analysis.JawlineScore,
@@ -51,8 +68,11 @@ func (h *FaceAnalysisHandler) Create(c *fiber.Ctx) error {
 		HarmonyScore:   analysis.HarmonyScore,
 		Strengths:      analysis.Strengths,
 		Improvements:   analysis.Improvements,
+		Tags:           analysis.Tags,
 		AnalyzedAt:     analysis.AnalyzedAt,
 		CreatedAt:      analysis.CreatedAt,
+		IsEstimate:     analysis.IsEstimate(),
+		SessionID:      analysis.SessionID,
 	}
 
 	return c.Status(fiber.StatusCreated).JSON(fiber.Map{"error": false, "data": response})
@@ -82,7 +102,7 @@ func (h *FaceAnalysisHandler) GetByID(c *fiber.Ctx) error {
 	response := dto.FaceAnalysisResponse{
 		ID:             analysis.ID,
 		UserID:         analysis.UserID,
-		ImageURL:       analysis.ImageURL,
+		ImageURL:       h.imageURL(analysis),
 		OverallScore:   analysis.OverallScore,
 		SymmetryScore:  analysis.SymmetryScore,
 		JawlineScore:   analysis.JawlineScore,
@@ -93,64 +113,134 @@ func (h *FaceAnalysisHandler) GetByID(c *fiber.Ctx) error {
 		HarmonyScore:   analysis.HarmonyScore,
 		Strengths:      analysis.Strengths,
 		Improvements:   analysis.Improvements,
+		Tags:           analysis.Tags,
 		AnalyzedAt:     analysis.AnalyzedAt,
 		CreatedAt:      analysis.CreatedAt,
+		IsEstimate:     analysis.IsEstimate(),
+		SessionID:      analysis.SessionID,
 	}
 
 	return c.Status(fiber.StatusOK).JSON(fiber.Map{"error": false, "data": response})
 }
 
-func (h *FaceAnalysisHandler) List(c *fiber.Ctx) error {
+// Compare diffs two of the user's analyses given as ?from= and ?to=.
+func (h *FaceAnalysisHandler) Compare(c *fiber.Ctx) error {
 	userIDStr := c.Locals("userID").(string)
 	userID, err := uuid.Parse(userIDStr)
 	if err != nil {
 		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": true, "message": "Invalid user ID"})
 	}
 
-	page, _ := strconv.Atoi(c.Query("page", "1"))
-	limit, _ := strconv.Atoi(c.Query("limit", "10"))
+	fromID, err := uuid.Parse(c.Query("from"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": true, "message": "Invalid from analysis ID"})
+	}
+	toID, err := uuid.Parse(c.Query("to"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": true, "message": "Invalid to analysis ID"})
+	}
 
-	if page < 1 {
-		page = 1
+	comparison, err := h.service.CompareAnalyses(userID, fromID, toID)
+	if err != nil {
+		if errors.Is(err, services.ErrSameAnalysis) {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": true, "message": err.Error()})
+		}
+		if err.Error() == "analysis not found" {
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": true, "message": "Analysis not found"})
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": true, "message": "Failed to compare analyses"})
 	}
-	if limit < 1 || limit > 100 {
-		limit = 10
+
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{"error": false, "data": comparison})
+}
+
+// List returns a page of the user's analyses. Pages continue from the
+// next_cursor of the previous one; filters, sort and order must stay the same
+// while paging.
+func (h *FaceAnalysisHandler) List(c *fiber.Ctx) error {
+	userIDStr := c.Locals("userID").(string)
+	userID, err := uuid.Parse(userIDStr)
+	if err != nil {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": true, "message": "Invalid user ID"})
 	}
 
-	analyses, total, err := h.service.ListAnalyses(userID, page, limit)
+	query := dto.AnalysisListQuery{
+		Limit:        c.QueryInt("limit", 10),
+		Cursor:       c.Query("cursor"),
+		Source:       c.Query("source"),
+		Tag:          c.Query("tag"),
+		Sort:         c.Query("sort"),
+		Order:        c.Query("order"),
+		IncludeTotal: c.QueryBool("include_total"),
+	}
+	if query.From, err = parseDateQuery(c, "from"); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": true, "message": "from must be a YYYY-MM-DD date"})
+	}
+	if query.To, err = parseDateQuery(c, "to"); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": true, "message": "to must be a YYYY-MM-DD date"})
+	}
+	if query.MinOverall, err = parseScoreQuery(c, "min_overall"); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": true, "message": "min_overall must be a number"})
+	}
+	if query.MaxOverall, err = parseScoreQuery(c, "max_overall"); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": true, "message": "max_overall must be a number"})
+	}
+
+	page, err := h.service.ListAnalyses(userID, query)
 	if err != nil {
+		if errors.Is(err, services.ErrInvalidCursor) || errors.Is(err, services.ErrInvalidSort) || errors.Is(err, services.ErrInvalidAnalysisFilter) {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": true, "message": err.Error()})
+		}
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": true, "message": "Failed to retrieve analyses"})
 	}
 
-	responseList := make([]dto.FaceAnalysisResponse, len(analyses))
-	for i, analysis := range analyses {
-		responseList[i] = dto.FaceAnalysisResponse{
-			ID:             analysis.ID,
-			UserID:         analysis.UserID,
-			ImageURL:       analysis.ImageURL,
-			OverallScore:   analysis.OverallScore,
-			SymmetryScore:  analysis.SymmetryScore,
-			JawlineScore:   analysis.JawlineScore,
-			SkinScore:      analysis.SkinScore,
-			EyeScore:       analysis.EyeScore,
-			NoseScore:      analysis.NoseScore,
-			LipsScore:      analysis.LipsScore,
-			HarmonyScore:   analysis.HarmonyScore,
-			Strengths:      analysis.Strengths,
-			Improvements:   analysis.Improvements,
-			AnalyzedAt:     analysis.AnalyzedAt,
-			CreatedAt:      analysis.CreatedAt,
-		}
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{"error": false, "data": page})
+}
+
+// parseScoreQuery reads an optional numeric query parameter; absent yields
+// nil.
+func parseScoreQuery(c *fiber.Ctx, key string) (*float64, error) {
+	value := c.Query(key)
+	if value == "" {
+		return nil, nil
+	}
+	score, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		return nil, err
 	}
+	return &score, nil
+}
 
-	response := dto.PaginatedAnalysesResponse{
-		Analyses: responseList,
-		Total:    total,
-		Page:     page,
-		Limit:    limit,
+// UpdateTags replaces the labels on one of the user's analyses.
+func (h *FaceAnalysisHandler) UpdateTags(c *fiber.Ctx) error {
+	userIDStr := c.Locals("userID").(string)
+	userID, err := uuid.Parse(userIDStr)
+	if err != nil {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": true, "message": "Invalid user ID"})
 	}
 
-	return c.Status(fiber.StatusOK).JSON(fiber.Map{"error": false, "data": response})
+	analysisID, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": true, "message": "Invalid analysis ID"})
+	}
+
+	var req dto.UpdateAnalysisTagsRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": true, "message": "Invalid request body"})
+	}
+
+	analysis, err := h.service.UpdateTags(analysisID, userID, req.Tags)
+	if err != nil {
+		if errors.Is(err, services.ErrInvalidTags) {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": true, "message": err.Error()})
+		}
+		if err.Error() == "analysis not found" {
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": true, "message": "Analysis not found"})
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": true, "message": "Failed to update tags"})
+	}
+
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{"error": false, "data": fiber.Map{"id": analysis.ID, "tags": analysis.Tags}})
 }
 
 func (h *FaceAnalysisHandler) Delete(c *fiber.Ctx) error {
@@ -196,7 +286,7 @@ func (h *FaceAnalysisHandler) GetLatest(c *fiber.Ctx) error {
 	response := dto.FaceAnalysisResponse{
 		ID:             analysis.ID,
 		UserID:         analysis.UserID,
-		ImageURL:       analysis.ImageURL,
+		ImageURL:       h.imageURL(analysis),
 		OverallScore:   analysis.OverallScore,
 		SymmetryScore:  analysis.SymmetryScore,
 		JawlineScore:   analysis.JawlineScore,
@@ -207,8 +297,11 @@ func (h *FaceAnalysisHandler) GetLatest(c *fiber.Ctx) error {
 		HarmonyScore:   analysis.HarmonyScore,
 		Strengths:      analysis.Strengths,
 		Improvements:   analysis.Improvements,
+		Tags:           analysis.Tags,
 		AnalyzedAt:     analysis.AnalyzedAt,
 		CreatedAt:      analysis.CreatedAt,
+		IsEstimate:     analysis.IsEstimate(),
+		SessionID:      analysis.SessionID,
 	}
 
 	return c.Status(fiber.StatusOK).JSON(fiber.Map{"error": false, "data": response})
@@ -227,4 +320,165 @@ func (h *FaceAnalysisHandler) GetStats(c *fiber.Ctx) error {
 	}
 
 	return c.Status(fiber.StatusOK).JSON(fiber.Map{"error": false, "data": stats})
-}
\ No newline at end of file
+}
+
+// AdminList returns analyses with their provenance for support.
+// GetAnalytics serves per-metric time series for charts. Query parameters:
+// granularity (day, week or month), from and to (YYYY-MM-DD) and window, the
+// number of periods in each moving average.
+func (h *FaceAnalysisHandler) GetAnalytics(c *fiber.Ctx) error {
+	userIDStr := c.Locals("userID").(string)
+	userID, err := uuid.Parse(userIDStr)
+	if err != nil {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": true, "message": "Invalid user ID"})
+	}
+
+	query := dto.AnalyticsQuery{
+		Granularity: c.Query("granularity"),
+		Window:      c.QueryInt("window", 0),
+	}
+	if query.From, err = parseDateQuery(c, "from"); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": true, "message": "from must be a YYYY-MM-DD date"})
+	}
+	if query.To, err = parseDateQuery(c, "to"); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": true, "message": "to must be a YYYY-MM-DD date"})
+	}
+
+	analytics, err := h.service.GetAnalytics(userID, query)
+	if err != nil {
+		if errors.Is(err, services.ErrInvalidGranularity) || errors.Is(err, services.ErrInvalidAnalyticsRange) {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": true, "message": err.Error()})
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": true, "message": "Failed to retrieve analytics"})
+	}
+
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{"error": false, "data": analytics})
+}
+
+// parseDateQuery reads an optional YYYY-MM-DD query parameter as UTC
+// midnight; absent yields the zero time.
+func parseDateQuery(c *fiber.Ctx, key string) (time.Time, error) {
+	value := c.Query(key)
+	if value == "" {
+		return time.Time{}, nil
+	}
+	return time.Parse("2006-01-02", value)
+}
+
+// GetSession returns a multi-angle scan with each angle's own result.
+func (h *FaceAnalysisHandler) GetSession(c *fiber.Ctx) error {
+	userIDStr := c.Locals("userID").(string)
+	userID, err := uuid.Parse(userIDStr)
+	if err != nil {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": true, "message": "Invalid user ID"})
+	}
+
+	sessionID, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": true, "message": "Invalid session ID"})
+	}
+
+	session, err := h.service.GetScanSession(sessionID, userID)
+	if err != nil {
+		if errors.Is(err, services.ErrScanSessionNotFound) {
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": true, "message": "Scan session not found"})
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": true, "message": "Failed to retrieve scan session"})
+	}
+
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{"error": false, "data": session})
+}
+
+func (h *FaceAnalysisHandler) AdminList(c *fiber.Ctx) error {
+	limit, _ := strconv.Atoi(c.Query("limit", "20"))
+	offset, _ := strconv.Atoi(c.Query("offset", "0"))
+
+	if limit > 100 {
+		limit = 100
+	}
+
+	filter := dto.AdminAnalysisFilter{
+		Source:       c.Query("source"),
+		Provider:     c.Query("provider"),
+		FallbackOnly: c.QueryBool("fallback_only"),
+	}
+	if userIDStr := c.Query("user_id"); userIDStr != "" {
+		userID, err := uuid.Parse(userIDStr)
+		if err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(dto.ErrorResponse{
+				Error: true, Message: "Invalid user ID",
+			})
+		}
+		filter.UserID = &userID
+	}
+
+	analyses, total, err := h.service.AdminListAnalyses(filter, limit, offset)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(dto.ErrorResponse{
+			Error: true, Message: "Failed to fetch analyses",
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"analyses": analyses,
+		"total":    total,
+		"limit":    limit,
+		"offset":   offset,
+	})
+}
+
+// AdminGet returns one analysis with its provenance and photo.
+func (h *FaceAnalysisHandler) AdminGet(c *fiber.Ctx) error {
+	analysisID, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(dto.ErrorResponse{
+			Error: true, Message: "Invalid analysis ID",
+		})
+	}
+
+	analysis, err := h.service.AdminGetAnalysis(analysisID)
+	if err != nil {
+		if err.Error() == "analysis not found" {
+			return c.Status(fiber.StatusNotFound).JSON(dto.ErrorResponse{
+				Error: true, Message: "Analysis not found",
+			})
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(dto.ErrorResponse{
+			Error: true, Message: "Failed to retrieve analysis",
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"analysis":    analysis,
+		"image_url":   h.imageURL(analysis),
+		"is_estimate": analysis.IsEstimate(),
+	})
+}
+
+// AdminDuplicates lists users scoring photos that look reused: shared with
+// other accounts, likely stock or borrowed, or edited copies of their own.
+func (h *FaceAnalysisHandler) AdminDuplicates(c *fiber.Ctx) error {
+	days, _ := strconv.Atoi(c.Query("days", "30"))
+	distance, _ := strconv.Atoi(c.Query("max_distance", "6"))
+
+	if days < 1 || days > 365 {
+		days = 30
+	}
+	if distance < 0 || distance > 16 {
+		distance = 6
+	}
+
+	report, err := h.service.DuplicateReport(time.Now().AddDate(0, 0, -days), distance)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(dto.ErrorResponse{
+			Error: true, Message: "Failed to build duplicate report",
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"flags":        report,
+		"total":        len(report),
+		"days":         days,
+		"max_distance": distance,
+	})
+}
@@ -28,7 +28,13 @@ func NewGamificationHandler(gamificationService *services.GamificationService) *
 // @Success 200 {object} models.UserGamification
 // @Router /gamification/stats [get]
 func (h *GamificationHandler) GetGamificationStats(c *fiber.Ctx) error {
-	userID := c.Locals("userID").(uuid.UUID)
+	userID, err := extractUserID(c)
+	if err != nil {
+		return c.Status(http.StatusUnauthorized).JSON(fiber.Map{
+			"error":   true,
+			"message": "Invalid user ID",
+		})
+	}
 
 	stats, err := h.gamificationService.GetGamificationStats(userID)
 	if err != nil {
@@ -52,7 +58,13 @@ func (h *GamificationHandler) GetGamificationStats(c *fiber.Ctx) error {
 // @Success 200 {array} models.Achievement
 // @Router /gamification/achievements [get]
 func (h *GamificationHandler) GetAchievements(c *fiber.Ctx) error {
-	userID := c.Locals("userID").(uuid.UUID)
+	userID, err := extractUserID(c)
+	if err != nil {
+		return c.Status(http.StatusUnauthorized).JSON(fiber.Map{
+			"error":   true,
+			"message": "Invalid user ID",
+		})
+	}
 
 	// Get all achievements
 	var achievements []models.Achievement
@@ -107,7 +119,13 @@ func (h *GamificationHandler) GetAchievements(c *fiber.Ctx) error {
 // @Success 200 {object} models.UserDailyChallenge
 // @Router /gamification/challenge/daily [get]
 func (h *GamificationHandler) GetDailyChallenge(c *fiber.Ctx) error {
-	userID := c.Locals("userID").(uuid.UUID)
+	userID, err := extractUserID(c)
+	if err != nil {
+		return c.Status(http.StatusUnauthorized).JSON(fiber.Map{
+			"error":   true,
+			"message": "Invalid user ID",
+		})
+	}
 
 	challenge, err := h.gamificationService.GetTodayChallenge(userID)
 	if err != nil {
@@ -131,7 +149,13 @@ func (h *GamificationHandler) GetDailyChallenge(c *fiber.Ctx) error {
 // @Success 200 {object} fiber.Map
 // @Router /gamification/challenge/claim [post]
 func (h *GamificationHandler) ClaimChallengeReward(c *fiber.Ctx) error {
-	userID := c.Locals("userID").(uuid.UUID)
+	userID, err := extractUserID(c)
+	if err != nil {
+		return c.Status(http.StatusUnauthorized).JSON(fiber.Map{
+			"error":   true,
+			"message": "Invalid user ID",
+		})
+	}
 
 	challenge, err := h.gamificationService.GetTodayChallenge(userID)
 	if err != nil {
@@ -170,6 +194,14 @@ func (h *GamificationHandler) ClaimChallengeReward(c *fiber.Ctx) error {
 // @Success 200 {array} models.LeaderboardEntry
 // @Router /gamification/leaderboard [get]
 func (h *GamificationHandler) GetLeaderboard(c *fiber.Ctx) error {
+	userID, err := extractUserID(c)
+	if err != nil {
+		return c.Status(http.StatusUnauthorized).JSON(fiber.Map{
+			"error":   true,
+			"message": "Invalid user ID",
+		})
+	}
+
 	period := c.Query("period", "all_time")
 	limit := c.QueryInt("limit", 100)
 
@@ -185,7 +217,6 @@ func (h *GamificationHandler) GetLeaderboard(c *fiber.Ctx) error {
 		})
 	}
 
-	userID := c.Locals("userID").(uuid.UUID)
 	userRank, _ := h.gamificationService.GetUserRank(userID, period)
 
 	return c.JSON(fiber.Map{
@@ -205,7 +236,13 @@ func (h *GamificationHandler) GetLeaderboard(c *fiber.Ctx) error {
 // @Success 200 {array} models.Notification
 // @Router /gamification/notifications [get]
 func (h *GamificationHandler) GetNotifications(c *fiber.Ctx) error {
-	userID := c.Locals("userID").(uuid.UUID)
+	userID, err := extractUserID(c)
+	if err != nil {
+		return c.Status(http.StatusUnauthorized).JSON(fiber.Map{
+			"error":   true,
+			"message": "Invalid user ID",
+		})
+	}
 
 	notifications, err := h.gamificationService.GetUnreadNotifications(userID)
 	if err != nil {
@@ -229,6 +266,14 @@ func (h *GamificationHandler) GetNotifications(c *fiber.Ctx) error {
 // @Success 200 {object} fiber.Map
 // @Router /gamification/notifications/{id}/read [post]
 func (h *GamificationHandler) MarkNotificationRead(c *fiber.Ctx) error {
+	userID, err := extractUserID(c)
+	if err != nil {
+		return c.Status(http.StatusUnauthorized).JSON(fiber.Map{
+			"error":   true,
+			"message": "Invalid user ID",
+		})
+	}
+
 	notificationID, err := uuid.Parse(c.Params("id"))
 	if err != nil {
 		return c.Status(http.StatusBadRequest).JSON(fiber.Map{
@@ -237,7 +282,7 @@ func (h *GamificationHandler) MarkNotificationRead(c *fiber.Ctx) error {
 		})
 	}
 
-	if err := h.gamificationService.MarkNotificationRead(notificationID); err != nil {
+	if err := h.gamificationService.MarkNotificationRead(userID, notificationID); err != nil {
 		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{
 			"error":   true,
 			"message": "Failed to mark as read",
@@ -259,7 +304,13 @@ func (h *GamificationHandler) MarkNotificationRead(c *fiber.Ctx) error {
 // @Success 200 {array} models.XPTransaction
 // @Router /gamification/xp/history [get]
 func (h *GamificationHandler) GetXPHistory(c *fiber.Ctx) error {
-	userID := c.Locals("userID").(uuid.UUID)
+	userID, err := extractUserID(c)
+	if err != nil {
+		return c.Status(http.StatusUnauthorized).JSON(fiber.Map{
+			"error":   true,
+			"message": "Invalid user ID",
+		})
+	}
 	limit := c.QueryInt("limit", 50)
 
 	var transactions []models.XPTransaction
@@ -67,7 +67,7 @@ func (h *GlowPlanHandler) Generate(c *fiber.Ctx) error {
 		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": true, "message": "Invalid request body"})
 	}
 
-	plans, err := h.service.GenerateGlowPlan(userID, req.AnalysisID)
+	plans, err := h.service.GenerateGlowPlan(userID, req.AnalysisID, requestLocale(c))
 	if err != nil {
 		if err.Error() == "analysis not found" {
 			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": true, "message": "Analysis not found"})
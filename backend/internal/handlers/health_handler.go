@@ -5,24 +5,72 @@ import (
 
 	"github.com/ahmetcoskunkizilkaya/mewify/backend/internal/database"
 	"github.com/ahmetcoskunkizilkaya/mewify/backend/internal/dto"
+	"github.com/ahmetcoskunkizilkaya/mewify/backend/internal/llm"
 	"github.com/gofiber/fiber/v2"
 )
 
-type HealthHandler struct{}
+type HealthHandler struct {
+	llm *llm.Client
+}
 
-func NewHealthHandler() *HealthHandler {
-	return &HealthHandler{}
+func NewHealthHandler(llmClient *llm.Client) *HealthHandler {
+	return &HealthHandler{llm: llmClient}
 }
 
 func (h *HealthHandler) Check(c *fiber.Ctx) error {
+	status := "ok"
 	dbStatus := "ok"
 	if err := database.Ping(); err != nil {
 		dbStatus = "unhealthy: " + err.Error()
 	}
 
+	providers := h.llm.Health()
+	llmStatus := make(map[string]string, len(providers))
+	for _, p := range providers {
+		llmStatus[p.Name] = string(p.State)
+	}
+	// Analyses still succeed on deterministic scoring, so an unavailable
+	// provider chain degrades the service rather than failing it.
+	if len(providers) > 0 && !h.llm.Available() {
+		status = "degraded"
+	}
+	// Without a vision model every face scan is an estimate
+	vision := h.llm.HasVisionProvider()
+	if !vision {
+		status = "degraded"
+	}
+
 	return c.JSON(dto.HealthResponse{
-		Status:    "ok",
+		Status:    status,
 		Timestamp: time.Now().UTC().Format(time.RFC3339),
 		DB:        dbStatus,
+		LLM:       llmStatus,
+		LLMVision: vision,
+	})
+}
+
+// LLMProviders godoc
+// @Summary Circuit breaker state, error rate and latency per LLM provider
+// @Tags admin
+// @Security BearerAuth
+// @Produce json
+// @Success 200 {array} llm.ProviderHealth
+// @Router /admin/llm/providers [get]
+func (h *HealthHandler) LLMProviders(c *fiber.Ctx) error {
+	return c.JSON(fiber.Map{
+		"providers": h.llm.Health(),
+	})
+}
+
+// LLMOutput godoc
+// @Summary Schema repairs and defaulted fields per structured LLM output
+// @Tags admin
+// @Security BearerAuth
+// @Produce json
+// @Success 200 {array} llm.SchemaStats
+// @Router /admin/llm/output [get]
+func (h *HealthHandler) LLMOutput(c *fiber.Ctx) error {
+	return c.JSON(fiber.Map{
+		"schemas": h.llm.OutputStats(),
 	})
 }
@@ -0,0 +1,35 @@
+package handlers
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/ahmetcoskunkizilkaya/mewify/backend/internal/dto"
+	"github.com/ahmetcoskunkizilkaya/mewify/backend/internal/services"
+	"github.com/gofiber/fiber/v2"
+)
+
+type LLMSpendHandler struct {
+	spendService *services.LLMSpendService
+}
+
+func NewLLMSpendHandler(spendService *services.LLMSpendService) *LLMSpendHandler {
+	return &LLMSpendHandler{spendService: spendService}
+}
+
+// Summary reports LLM tokens and estimated cost over the last ?days=
+// (default 30) and how close spend is to the budget caps.
+func (h *LLMSpendHandler) Summary(c *fiber.Ctx) error {
+	days, _ := strconv.Atoi(c.Query("days", "30"))
+	if days < 1 || days > 366 {
+		days = 30
+	}
+
+	summary, err := h.spendService.Summary(time.Now().AddDate(0, 0, -days))
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(dto.ErrorResponse{
+			Error: true, Message: "Failed to summarize LLM spend",
+		})
+	}
+	return c.JSON(summary)
+}
@@ -31,6 +31,9 @@ func (h *MewingHandler) Log(c *fiber.Ctx) error {
 
 	progress, err := h.service.LogProgress(parsedUserID, req)
 	if err != nil {
+		if status, ok := imageErrorStatus(err); ok {
+			return c.Status(status).JSON(fiber.Map{"error": true, "message": err.Error()})
+		}
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": true, "message": err.Error()})
 	}
 
@@ -1,6 +1,7 @@
 package handlers
 
 import (
+	"errors"
 	"time"
 
 	"github.com/ahmetcoskunkizilkaya/mewify/backend/internal/models"
@@ -21,6 +22,16 @@ func NewMonetizationHandler(monetizationService *services.MonetizationService, g
 	}
 }
 
+// currentUserID returns the caller's ID, or a 401 that the app's error
+// handler renders like the other error responses.
+func currentUserID(c *fiber.Ctx) (uuid.UUID, error) {
+	userID, err := extractUserID(c)
+	if err != nil {
+		return uuid.Nil, fiber.NewError(fiber.StatusUnauthorized, "Invalid user ID")
+	}
+	return userID, nil
+}
+
 // ==========================================
 // CURRENCY & GEMS
 // ==========================================
@@ -33,11 +44,14 @@ func NewMonetizationHandler(monetizationService *services.MonetizationService, g
 // @Success 200 {object} models.UserCurrency
 // @Router /monetization/currency [get]
 func (h *MonetizationHandler) GetCurrency(c *fiber.Ctx) error {
-	userID := c.Locals("userID").(uuid.UUID)
+	userID, err := currentUserID(c)
+	if err != nil {
+		return err
+	}
 
 	currency, err := h.monetizationService.GetUserCurrency(userID)
 	if err != nil {
-		return c.Status(500).JSON(fiber.Map{
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
 			"error":   true,
 			"message": "Failed to get currency",
 		})
@@ -63,7 +77,10 @@ func (h *MonetizationHandler) GetCurrency(c *fiber.Ctx) error {
 // @Param limit query int false "Limit" default(50)
 // @Router /monetization/gems/history [get]
 func (h *MonetizationHandler) GetGemHistory(c *fiber.Ctx) error {
-	userID := c.Locals("userID").(uuid.UUID)
+	userID, err := currentUserID(c)
+	if err != nil {
+		return err
+	}
 	limit := c.QueryInt("limit", 50)
 
 	var transactions []models.GemTransaction
@@ -72,7 +89,7 @@ func (h *MonetizationHandler) GetGemHistory(c *fiber.Ctx) error {
 		Order("created_at DESC").
 		Limit(limit).
 		Find(&transactions).Error; err != nil {
-		return c.Status(500).JSON(fiber.Map{
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
 			"error":   true,
 			"message": "Failed to get history",
 		})
@@ -95,12 +112,15 @@ func (h *MonetizationHandler) GetGemHistory(c *fiber.Ctx) error {
 // @Produce json
 // @Router /monetization/streak-freeze/check [get]
 func (h *MonetizationHandler) CanUseStreakFreeze(c *fiber.Ctx) error {
-	userID := c.Locals("userID").(uuid.UUID)
+	userID, err := currentUserID(c)
+	if err != nil {
+		return err
+	}
 	isPremium := c.Locals("isPremium") == true
 
 	canUse, method, err := h.monetizationService.CanUseStreakFreeze(userID, isPremium)
 	if err != nil {
-		return c.Status(500).JSON(fiber.Map{
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
 			"error":   true,
 			"message": "Failed to check streak freeze",
 		})
@@ -122,11 +142,14 @@ func (h *MonetizationHandler) CanUseStreakFreeze(c *fiber.Ctx) error {
 // @Produce json
 // @Router /monetization/streak-freeze/use [post]
 func (h *MonetizationHandler) UseStreakFreeze(c *fiber.Ctx) error {
-	userID := c.Locals("userID").(uuid.UUID)
+	userID, err := currentUserID(c)
+	if err != nil {
+		return err
+	}
 	isPremium := c.Locals("isPremium") == true
 
 	if err := h.monetizationService.UseStreakFreeze(userID, isPremium); err != nil {
-		return c.Status(400).JSON(fiber.Map{
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
 			"error":   true,
 			"message": err.Error(),
 		})
@@ -149,7 +172,10 @@ func (h *MonetizationHandler) UseStreakFreeze(c *fiber.Ctx) error {
 // @Produce json
 // @Router /monetization/chest/open [post]
 func (h *MonetizationHandler) OpenDailyChest(c *fiber.Ctx) error {
-	userID := c.Locals("userID").(uuid.UUID)
+	userID, err := currentUserID(c)
+	if err != nil {
+		return err
+	}
 	isPremium := c.Locals("isPremium") == true
 
 	// Get current streak
@@ -161,7 +187,13 @@ func (h *MonetizationHandler) OpenDailyChest(c *fiber.Ctx) error {
 
 	chest, err := h.monetizationService.OpenDailyChest(userID, currentStreak, isPremium)
 	if err != nil {
-		return c.Status(500).JSON(fiber.Map{
+		if errors.Is(err, services.ErrChestAlreadyOpened) {
+			return c.Status(fiber.StatusConflict).JSON(fiber.Map{
+				"error":   true,
+				"message": err.Error(),
+			})
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
 			"error":   true,
 			"message": "Failed to open chest",
 		})
@@ -184,15 +216,20 @@ func (h *MonetizationHandler) OpenDailyChest(c *fiber.Ctx) error {
 // @Produce json
 // @Router /monetization/chest/status [get]
 func (h *MonetizationHandler) CheckDailyChestStatus(c *fiber.Ctx) error {
-	userID := c.Locals("userID").(uuid.UUID)
+	userID, err := currentUserID(c)
+	if err != nil {
+		return err
+	}
 
-	today := time.Now().Truncate(24 * time.Hour)
-	var chest models.UserChest
-	err := h.monetizationService.GetDB().
-		Where("user_id = ? AND chest_type = ? AND opened_at >= ?", userID, models.ChestDaily, today).
-		First(&chest).Error
+	opened, err := h.monetizationService.HasOpenedDailyChest(userID)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error":   true,
+			"message": "Failed to check chest status",
+		})
+	}
 
-	available := err != nil // If no chest found, available = true
+	available := !opened
 
 	return c.JSON(fiber.Map{
 		"error":     false,
@@ -212,17 +249,20 @@ func (h *MonetizationHandler) CheckDailyChestStatus(c *fiber.Ctx) error {
 // @Produce json
 // @Router /monetization/decay/status [get]
 func (h *MonetizationHandler) GetDecayStatus(c *fiber.Ctx) error {
-	userID := c.Locals("userID").(uuid.UUID)
+	userID, err := currentUserID(c)
+	if err != nil {
+		return err
+	}
 
 	status, err := h.monetizationService.CheckDecayStatus(userID)
 	if err != nil {
-		return c.Status(500).JSON(fiber.Map{
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
 			"error":   true,
 			"message": "Failed to check decay status",
 		})
 	}
 
-	message := h.monetizationService.GetDecayMessage(status.DecayLevel)
+	message := h.monetizationService.GetDecayMessage(userID, status.DecayLevel, requestLocale(c))
 
 	return c.JSON(fiber.Map{
 		"error":       false,
@@ -245,13 +285,16 @@ func (h *MonetizationHandler) GetDecayStatus(c *fiber.Ctx) error {
 // @Param request body object true "Friend request"
 // @Router /monetization/friends/request [post]
 func (h *MonetizationHandler) SendFriendRequest(c *fiber.Ctx) error {
-	userID := c.Locals("userID").(uuid.UUID)
+	userID, err := currentUserID(c)
+	if err != nil {
+		return err
+	}
 
 	var req struct {
 		ReceiverID string `json:"receiver_id"`
 	}
 	if err := c.BodyParser(&req); err != nil {
-		return c.Status(400).JSON(fiber.Map{
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
 			"error":   true,
 			"message": "Invalid request",
 		})
@@ -259,14 +302,14 @@ func (h *MonetizationHandler) SendFriendRequest(c *fiber.Ctx) error {
 
 	receiverID, err := uuid.Parse(req.ReceiverID)
 	if err != nil {
-		return c.Status(400).JSON(fiber.Map{
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
 			"error":   true,
 			"message": "Invalid receiver ID",
 		})
 	}
 
 	if err := h.monetizationService.SendFriendRequest(userID, receiverID); err != nil {
-		return c.Status(400).JSON(fiber.Map{
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
 			"error":   true,
 			"message": err.Error(),
 		})
@@ -287,11 +330,16 @@ func (h *MonetizationHandler) SendFriendRequest(c *fiber.Ctx) error {
 // @Param request body object true "Accept request"
 // @Router /monetization/friends/accept [post]
 func (h *MonetizationHandler) AcceptFriendRequest(c *fiber.Ctx) error {
+	userID, err := currentUserID(c)
+	if err != nil {
+		return err
+	}
+
 	var req struct {
 		ConnectionID string `json:"connection_id"`
 	}
 	if err := c.BodyParser(&req); err != nil {
-		return c.Status(400).JSON(fiber.Map{
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
 			"error":   true,
 			"message": "Invalid request",
 		})
@@ -299,14 +347,14 @@ func (h *MonetizationHandler) AcceptFriendRequest(c *fiber.Ctx) error {
 
 	connectionID, err := uuid.Parse(req.ConnectionID)
 	if err != nil {
-		return c.Status(400).JSON(fiber.Map{
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
 			"error":   true,
 			"message": "Invalid connection ID",
 		})
 	}
 
-	if err := h.monetizationService.AcceptFriendRequest(connectionID); err != nil {
-		return c.Status(400).JSON(fiber.Map{
+	if err := h.monetizationService.AcceptFriendRequest(userID, connectionID); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
 			"error":   true,
 			"message": err.Error(),
 		})
@@ -325,11 +373,14 @@ func (h *MonetizationHandler) AcceptFriendRequest(c *fiber.Ctx) error {
 // @Produce json
 // @Router /monetization/friends [get]
 func (h *MonetizationHandler) GetFriends(c *fiber.Ctx) error {
-	userID := c.Locals("userID").(uuid.UUID)
+	userID, err := currentUserID(c)
+	if err != nil {
+		return err
+	}
 
 	friends, err := h.monetizationService.GetFriends(userID)
 	if err != nil {
-		return c.Status(500).JSON(fiber.Map{
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
 			"error":   true,
 			"message": "Failed to get friends",
 		})
@@ -350,14 +401,17 @@ func (h *MonetizationHandler) GetFriends(c *fiber.Ctx) error {
 // @Param request body object true "Nudge request"
 // @Router /monetization/friends/nudge [post]
 func (h *MonetizationHandler) SendNudge(c *fiber.Ctx) error {
-	userID := c.Locals("userID").(uuid.UUID)
+	userID, err := currentUserID(c)
+	if err != nil {
+		return err
+	}
 
 	var req struct {
 		FriendID  string `json:"friend_id"`
 		NudgeType string `json:"nudge_type"`
 	}
 	if err := c.BodyParser(&req); err != nil {
-		return c.Status(400).JSON(fiber.Map{
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
 			"error":   true,
 			"message": "Invalid request",
 		})
@@ -365,14 +419,14 @@ func (h *MonetizationHandler) SendNudge(c *fiber.Ctx) error {
 
 	friendID, err := uuid.Parse(req.FriendID)
 	if err != nil {
-		return c.Status(400).JSON(fiber.Map{
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
 			"error":   true,
 			"message": "Invalid friend ID",
 		})
 	}
 
 	if err := h.monetizationService.SendNudge(userID, friendID, req.NudgeType); err != nil {
-		return c.Status(400).JSON(fiber.Map{
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
 			"error":   true,
 			"message": err.Error(),
 		})
@@ -391,12 +445,15 @@ func (h *MonetizationHandler) SendNudge(c *fiber.Ctx) error {
 // @Produce json
 // @Router /monetization/friends/leaderboard [get]
 func (h *MonetizationHandler) GetFriendsLeaderboard(c *fiber.Ctx) error {
-	userID := c.Locals("userID").(uuid.UUID)
+	userID, err := currentUserID(c)
+	if err != nil {
+		return err
+	}
 	limit := c.QueryInt("limit", 10)
 
 	leaderboard, err := h.monetizationService.GetFriendsLeaderboard(userID, limit)
 	if err != nil {
-		return c.Status(500).JSON(fiber.Map{
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
 			"error":   true,
 			"message": "Failed to get leaderboard",
 		})
@@ -421,7 +478,7 @@ func (h *MonetizationHandler) GetFriendsLeaderboard(c *fiber.Ctx) error {
 func (h *MonetizationHandler) GetIntroOffers(c *fiber.Ctx) error {
 	offers, err := h.monetizationService.GetActiveIntroOffers()
 	if err != nil {
-		return c.Status(500).JSON(fiber.Map{
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
 			"error":   true,
 			"message": "Failed to get offers",
 		})
@@ -442,13 +499,16 @@ func (h *MonetizationHandler) GetIntroOffers(c *fiber.Ctx) error {
 // @Param request body object true "Redeem request"
 // @Router /monetization/offers/redeem [post]
 func (h *MonetizationHandler) RedeemIntroOffer(c *fiber.Ctx) error {
-	userID := c.Locals("userID").(uuid.UUID)
+	userID, err := currentUserID(c)
+	if err != nil {
+		return err
+	}
 
 	var req struct {
 		OfferID string `json:"offer_id"`
 	}
 	if err := c.BodyParser(&req); err != nil {
-		return c.Status(400).JSON(fiber.Map{
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
 			"error":   true,
 			"message": "Invalid request",
 		})
@@ -456,7 +516,7 @@ func (h *MonetizationHandler) RedeemIntroOffer(c *fiber.Ctx) error {
 
 	offerID, err := uuid.Parse(req.OfferID)
 	if err != nil {
-		return c.Status(400).JSON(fiber.Map{
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
 			"error":   true,
 			"message": "Invalid offer ID",
 		})
@@ -464,7 +524,7 @@ func (h *MonetizationHandler) RedeemIntroOffer(c *fiber.Ctx) error {
 
 	userOffer, err := h.monetizationService.RedeemIntroOffer(userID, offerID)
 	if err != nil {
-		return c.Status(400).JSON(fiber.Map{
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
 			"error":   true,
 			"message": err.Error(),
 		})
@@ -0,0 +1,72 @@
+package handlers
+
+import (
+	"errors"
+	"strconv"
+
+	"github.com/ahmetcoskunkizilkaya/mewify/backend/internal/dto"
+	"github.com/ahmetcoskunkizilkaya/mewify/backend/internal/services"
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+)
+
+type OutboxHandler struct {
+	outboxService *services.OutboxService
+}
+
+func NewOutboxHandler(outboxService *services.OutboxService) *OutboxHandler {
+	return &OutboxHandler{outboxService: outboxService}
+}
+
+// ListDeadLetters returns outbox messages that exhausted their retries.
+func (h *OutboxHandler) ListDeadLetters(c *fiber.Ctx) error {
+	limit, _ := strconv.Atoi(c.Query("limit", "20"))
+	offset, _ := strconv.Atoi(c.Query("offset", "0"))
+
+	if limit < 1 {
+		limit = 20
+	}
+	if limit > 100 {
+		limit = 100
+	}
+	if offset < 0 {
+		offset = 0
+	}
+
+	messages, total, err := h.outboxService.ListDeadLetters(limit, offset)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(dto.ErrorResponse{
+			Error: true, Message: "Failed to fetch dead letters",
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"messages": messages,
+		"total":    total,
+		"limit":    limit,
+		"offset":   offset,
+	})
+}
+
+// RetryDeadLetter requeues a dead-lettered message for delivery.
+func (h *OutboxHandler) RetryDeadLetter(c *fiber.Ctx) error {
+	messageID, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(dto.ErrorResponse{
+			Error: true, Message: "Invalid message ID",
+		})
+	}
+
+	if err := h.outboxService.RetryDeadLetter(messageID); err != nil {
+		if errors.Is(err, services.ErrOutboxMessageNotFound) {
+			return c.Status(fiber.StatusNotFound).JSON(dto.ErrorResponse{
+				Error: true, Message: err.Error(),
+			})
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(dto.ErrorResponse{
+			Error: true, Message: "Failed to requeue message",
+		})
+	}
+
+	return c.JSON(fiber.Map{"message": "Message requeued"})
+}
@@ -0,0 +1,234 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/ahmetcoskunkizilkaya/mewify/backend/internal/models"
+	"github.com/ahmetcoskunkizilkaya/mewify/backend/internal/services"
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+)
+
+type PremiumContentHandler struct {
+	premiumContentService *services.PremiumContentService
+}
+
+func NewPremiumContentHandler(premiumContentService *services.PremiumContentService) *PremiumContentHandler {
+	return &PremiumContentHandler{
+		premiumContentService: premiumContentService,
+	}
+}
+
+// GetExercises godoc
+// @Summary List mewing exercises available to the user's tier
+// @Tags premium
+// @Security BearerAuth
+// @Produce json
+// @Param type query string false "quick, focused, deep, marathon"
+// @Success 200 {array} models.MewingExercise
+// @Router /premium/exercises [get]
+func (h *PremiumContentHandler) GetExercises(c *fiber.Ctx) error {
+	userID, err := extractUserID(c)
+	if err != nil {
+		return c.Status(http.StatusUnauthorized).JSON(fiber.Map{
+			"error":   true,
+			"message": "Invalid user ID",
+		})
+	}
+
+	isPremium := h.premiumContentService.IsPremium(userID)
+	exerciseType := models.ExerciseType(c.Query("type"))
+
+	exercises, err := h.premiumContentService.GetExercises(exerciseType, isPremium)
+	if err != nil {
+		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{
+			"error":   true,
+			"message": "Failed to get exercises",
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"error":      false,
+		"data":       exercises,
+		"is_premium": isPremium,
+	})
+}
+
+// GetExercise godoc
+// @Summary Get a single exercise with step-by-step instructions
+// @Tags premium
+// @Security BearerAuth
+// @Produce json
+// @Param id path string true "Exercise ID"
+// @Success 200 {object} models.MewingExercise
+// @Router /premium/exercises/{id} [get]
+func (h *PremiumContentHandler) GetExercise(c *fiber.Ctx) error {
+	userID, err := extractUserID(c)
+	if err != nil {
+		return c.Status(http.StatusUnauthorized).JSON(fiber.Map{
+			"error":   true,
+			"message": "Invalid user ID",
+		})
+	}
+
+	exerciseID, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{
+			"error":   true,
+			"message": "Invalid exercise ID",
+		})
+	}
+
+	exercise, err := h.premiumContentService.GetExerciseByID(exerciseID)
+	if err != nil {
+		if errors.Is(err, services.ErrExerciseNotFound) {
+			return c.Status(http.StatusNotFound).JSON(fiber.Map{
+				"error":   true,
+				"message": "Exercise not found",
+			})
+		}
+		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{
+			"error":   true,
+			"message": "Failed to get exercise",
+		})
+	}
+
+	if exercise.IsPremium && !h.premiumContentService.IsPremium(userID) {
+		return c.Status(http.StatusForbidden).JSON(fiber.Map{
+			"error":   true,
+			"message": "Premium subscription required",
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"error": false,
+		"data":  exercise,
+	})
+}
+
+// GetTodayRoutine godoc
+// @Summary Get (or generate) today's mewing routine
+// @Tags premium
+// @Security BearerAuth
+// @Produce json
+// @Success 200 {object} models.DailyRoutine
+// @Router /premium/routine/today [get]
+func (h *PremiumContentHandler) GetTodayRoutine(c *fiber.Ctx) error {
+	userID, err := extractUserID(c)
+	if err != nil {
+		return c.Status(http.StatusUnauthorized).JSON(fiber.Map{
+			"error":   true,
+			"message": "Invalid user ID",
+		})
+	}
+
+	tier, err := h.premiumContentService.GetSubscriptionTier(userID)
+	if err != nil {
+		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{
+			"error":   true,
+			"message": "Failed to check subscription",
+		})
+	}
+	if !tier.HasPersonalizedPlan {
+		return c.Status(http.StatusForbidden).JSON(fiber.Map{
+			"error":   true,
+			"message": "Premium subscription required",
+			"tier":    tier.Code,
+		})
+	}
+
+	routine, err := h.premiumContentService.GetTodayRoutine(userID)
+	if err != nil {
+		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{
+			"error":   true,
+			"message": "Failed to get routine",
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"error": false,
+		"data":  routine,
+	})
+}
+
+// GetWeekPlan godoc
+// @Summary Get (or generate) the current week's personalized plan
+// @Tags premium
+// @Security BearerAuth
+// @Produce json
+// @Success 200 {object} models.WeeklyPlan
+// @Router /premium/plan/week [get]
+func (h *PremiumContentHandler) GetWeekPlan(c *fiber.Ctx) error {
+	userID, err := extractUserID(c)
+	if err != nil {
+		return c.Status(http.StatusUnauthorized).JSON(fiber.Map{
+			"error":   true,
+			"message": "Invalid user ID",
+		})
+	}
+
+	tier, err := h.premiumContentService.GetSubscriptionTier(userID)
+	if err != nil {
+		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{
+			"error":   true,
+			"message": "Failed to check subscription",
+		})
+	}
+	if !tier.HasPersonalizedPlan {
+		return c.Status(http.StatusForbidden).JSON(fiber.Map{
+			"error":   true,
+			"message": "Premium subscription required",
+			"tier":    tier.Code,
+		})
+	}
+
+	plan, err := h.premiumContentService.GetCurrentWeekPlan(userID)
+	if err != nil {
+		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{
+			"error":   true,
+			"message": "Failed to get weekly plan",
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"error": false,
+		"data":  plan,
+	})
+}
+
+// GetTips godoc
+// @Summary Get personalized tips
+// @Tags premium
+// @Security BearerAuth
+// @Produce json
+// @Param limit query int false "Limit results" default(5)
+// @Success 200 {array} models.PersonalizedTip
+// @Router /premium/tips [get]
+func (h *PremiumContentHandler) GetTips(c *fiber.Ctx) error {
+	userID, err := extractUserID(c)
+	if err != nil {
+		return c.Status(http.StatusUnauthorized).JSON(fiber.Map{
+			"error":   true,
+			"message": "Invalid user ID",
+		})
+	}
+
+	limit := c.QueryInt("limit", 5)
+	if limit < 1 || limit > 20 {
+		limit = 5
+	}
+
+	tips, err := h.premiumContentService.GetPersonalizedTips(userID, limit, h.premiumContentService.IsPremium(userID))
+	if err != nil {
+		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{
+			"error":   true,
+			"message": "Failed to get tips",
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"error": false,
+		"data":  tips,
+	})
+}
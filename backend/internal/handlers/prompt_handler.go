@@ -0,0 +1,45 @@
+package handlers
+
+import (
+	"github.com/ahmetcoskunkizilkaya/mewify/backend/internal/dto"
+	"github.com/ahmetcoskunkizilkaya/mewify/backend/internal/services"
+	"github.com/gofiber/fiber/v2"
+)
+
+type PromptHandler struct {
+	promptService *services.PromptService
+}
+
+func NewPromptHandler(promptService *services.PromptService) *PromptHandler {
+	return &PromptHandler{promptService: promptService}
+}
+
+// List returns every prompt variant and its share of users.
+func (h *PromptHandler) List(c *fiber.Ctx) error {
+	templates := h.promptService.Templates()
+	return c.JSON(fiber.Map{
+		"templates": templates,
+		"total":     len(templates),
+	})
+}
+
+// Reload picks up prompt template rows changed in the database.
+func (h *PromptHandler) Reload(c *fiber.Ctx) error {
+	if err := h.promptService.Load(); err != nil {
+		return c.Status(fiber.StatusUnprocessableEntity).JSON(dto.ErrorResponse{
+			Error: true, Message: err.Error(),
+		})
+	}
+	return h.List(c)
+}
+
+// Stats compares score distributions and plan completion per variant.
+func (h *PromptHandler) Stats(c *fiber.Ctx) error {
+	stats, err := h.promptService.Stats()
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(dto.ErrorResponse{
+			Error: true, Message: "Failed to fetch prompt stats",
+		})
+	}
+	return c.JSON(stats)
+}
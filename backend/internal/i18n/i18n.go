@@ -0,0 +1,143 @@
+// Package i18n resolves the user's language and translates the messages the
+// server writes on their behalf: analysis wording, glow plans, decay and
+// nudge messages, notifications, photo retake hints and scan errors.
+package i18n
+
+import (
+	"embed"
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Supported locales
+const (
+	English = "en"
+	Turkish = "tr"
+	Spanish = "es"
+
+	Default = English
+)
+
+// Supported lists the locales the catalog translates, default first.
+var Supported = []string{English, Turkish, Spanish}
+
+// languageNames are the English names of the locales, used to tell LLM
+// providers which language to answer in.
+var languageNames = map[string]string{
+	English: "English",
+	Turkish: "Turkish",
+	Spanish: "Spanish",
+}
+
+//go:embed locales/*.json
+var locales embed.FS
+
+// catalog maps locale to message key to format string.
+var catalog = mustLoad()
+
+func mustLoad() map[string]map[string]string {
+	files, err := fs.Glob(locales, "locales/*.json")
+	if err != nil {
+		panic(err)
+	}
+	out := make(map[string]map[string]string, len(files))
+	for _, file := range files {
+		data, err := fs.ReadFile(locales, file)
+		if err != nil {
+			panic(err)
+		}
+		var messages map[string]string
+		if err := json.Unmarshal(data, &messages); err != nil {
+			panic(fmt.Sprintf("i18n: %s: %v", file, err))
+		}
+		out[strings.TrimSuffix(path.Base(file), ".json")] = messages
+	}
+	return out
+}
+
+// Normalize maps a language tag such as "tr-TR" or "es_419" to a supported
+// locale, or "" when the language is not translated.
+func Normalize(tag string) string {
+	tag = strings.ToLower(strings.TrimSpace(tag))
+	if i := strings.IndexAny(tag, "-_"); i >= 0 {
+		tag = tag[:i]
+	}
+	if _, ok := languageNames[tag]; ok {
+		return tag
+	}
+	return ""
+}
+
+// FromAcceptLanguage returns the supported locale the Accept-Language header
+// prefers most, or "" when it names none of them.
+func FromAcceptLanguage(header string) string {
+	type choice struct {
+		locale string
+		q      float64
+	}
+	var choices []choice
+	for _, part := range strings.Split(header, ",") {
+		fields := strings.Split(part, ";")
+		locale := Normalize(fields[0])
+		if locale == "" {
+			continue
+		}
+		q := 1.0
+		for _, param := range fields[1:] {
+			param = strings.TrimSpace(param)
+			if !strings.HasPrefix(param, "q=") {
+				continue
+			}
+			if v, err := strconv.ParseFloat(strings.TrimPrefix(param, "q="), 64); err == nil {
+				q = v
+			}
+		}
+		if q > 0 {
+			choices = append(choices, choice{locale, q})
+		}
+	}
+	if len(choices) == 0 {
+		return ""
+	}
+	sort.SliceStable(choices, func(i, j int) bool { return choices[i].q > choices[j].q })
+	return choices[0].locale
+}
+
+// Resolve returns the first of the candidates that is a supported locale,
+// falling back to Default.
+func Resolve(candidates ...string) string {
+	for _, c := range candidates {
+		if locale := Normalize(c); locale != "" {
+			return locale
+		}
+	}
+	return Default
+}
+
+// LanguageName returns the English name of a locale, e.g. "Turkish".
+func LanguageName(locale string) string {
+	if name, ok := languageNames[Normalize(locale)]; ok {
+		return name
+	}
+	return languageNames[Default]
+}
+
+// T translates key into locale and formats it with args. Keys missing from
+// the locale fall back to English, and unknown keys to the key itself.
+func T(locale, key string, args ...interface{}) string {
+	format, ok := catalog[Normalize(locale)][key]
+	if !ok {
+		if format, ok = catalog[Default][key]; !ok {
+			return key
+		}
+	}
+	if len(args) == 0 {
+		return format
+	}
+	return fmt.Sprintf(format, args...)
+}
@@ -0,0 +1,134 @@
+package imaging
+
+import (
+	"bytes"
+	"encoding/binary"
+)
+
+// readOrientation returns the EXIF orientation tag, or 1 when the image has
+// none. Only the orientation is read; the metadata itself is discarded when
+// the image is re-encoded.
+func readOrientation(data []byte, format string) int {
+	var tiff []byte
+	switch format {
+	case FormatJPEG:
+		tiff = jpegExif(data)
+	case FormatPNG:
+		tiff = pngExif(data)
+	case FormatWebP:
+		tiff = webpExif(data)
+	}
+	if tiff == nil {
+		return 1
+	}
+
+	orientation, err := tiffOrientation(tiff)
+	if err != nil {
+		return 1
+	}
+	return orientation
+}
+
+// jpegExif finds the APP1 Exif segment before the image data starts.
+func jpegExif(data []byte) []byte {
+	i := 2
+	for i+4 <= len(data) {
+		if data[i] != 0xFF {
+			return nil
+		}
+		marker := data[i+1]
+		// Start of scan: no metadata past this point
+		if marker == 0xDA || marker == 0xD9 {
+			return nil
+		}
+		size := int(binary.BigEndian.Uint16(data[i+2 : i+4]))
+		if size < 2 || i+2+size > len(data) {
+			return nil
+		}
+		segment := data[i+4 : i+2+size]
+		if marker == 0xE1 && bytes.HasPrefix(segment, []byte("Exif\x00\x00")) {
+			return segment[6:]
+		}
+		i += 2 + size
+	}
+	return nil
+}
+
+// pngExif finds the eXIf chunk, which holds bare TIFF data.
+func pngExif(data []byte) []byte {
+	i := 8
+	for i+8 <= len(data) {
+		size := int(binary.BigEndian.Uint32(data[i : i+4]))
+		kind := string(data[i+4 : i+8])
+		if size < 0 || i+12+size > len(data) {
+			return nil
+		}
+		switch kind {
+		case "eXIf":
+			return data[i+8 : i+8+size]
+		case "IDAT", "IEND":
+			return nil
+		}
+		i += 12 + size
+	}
+	return nil
+}
+
+// webpExif finds the EXIF chunk of an extended WebP file.
+func webpExif(data []byte) []byte {
+	i := 12
+	for i+8 <= len(data) {
+		kind := string(data[i : i+4])
+		size := int(binary.LittleEndian.Uint32(data[i+4 : i+8]))
+		if size < 0 || i+8+size > len(data) {
+			return nil
+		}
+		if kind == "EXIF" {
+			// Some encoders keep the JPEG-style prefix
+			return bytes.TrimPrefix(data[i+8:i+8+size], []byte("Exif\x00\x00"))
+		}
+		i += 8 + size + size%2
+	}
+	return nil
+}
+
+// tiffOrientation reads tag 0x0112 from IFD0.
+func tiffOrientation(tiff []byte) (int, error) {
+	if len(tiff) < 8 {
+		return 0, errUnknownOrientation
+	}
+
+	var order binary.ByteOrder
+	switch string(tiff[0:2]) {
+	case "II":
+		order = binary.LittleEndian
+	case "MM":
+		order = binary.BigEndian
+	default:
+		return 0, errUnknownOrientation
+	}
+	if order.Uint16(tiff[2:4]) != 42 {
+		return 0, errUnknownOrientation
+	}
+
+	ifd := int(order.Uint32(tiff[4:8]))
+	if ifd < 8 || ifd+2 > len(tiff) {
+		return 0, errUnknownOrientation
+	}
+	entries := int(order.Uint16(tiff[ifd : ifd+2]))
+	for n := 0; n < entries; n++ {
+		entry := ifd + 2 + n*12
+		if entry+12 > len(tiff) {
+			break
+		}
+		if order.Uint16(tiff[entry:entry+2]) != 0x0112 {
+			continue
+		}
+		orientation := int(order.Uint16(tiff[entry+8 : entry+10]))
+		if orientation < 1 || orientation > 8 {
+			return 0, errUnknownOrientation
+		}
+		return orientation, nil
+	}
+	return 0, errUnknownOrientation
+}
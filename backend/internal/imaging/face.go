@@ -0,0 +1,120 @@
+package imaging
+
+import (
+	"image"
+
+	"golang.org/x/image/draw"
+)
+
+// FaceDetector finds the most prominent face in an image. Implementations
+// backed by a real model can replace the default skin heuristic.
+type FaceDetector interface {
+	// DetectFace returns the face's bounding box in img's coordinates.
+	DetectFace(img image.Image) (image.Rectangle, bool)
+}
+
+const skinGridSize = 96
+
+// SkinDetector treats the largest connected patch of skin-toned pixels as
+// the face. It is cheap and dependency free, and good enough to tell a
+// selfie from a wall, a pet or a screenshot; it is not a face recognizer.
+type SkinDetector struct {
+	// MinArea and MaxArea bound the patch as a fraction of the image.
+	MinArea float64
+	MaxArea float64
+	// MinFill is the smallest fraction of the patch's bounding box that
+	// must be skin; scattered skin-coloured texture fills it poorly.
+	MinFill float64
+}
+
+func NewSkinDetector() *SkinDetector {
+	return &SkinDetector{MinArea: 0.03, MaxArea: 0.9, MinFill: 0.35}
+}
+
+func (d *SkinDetector) DetectFace(img image.Image) (image.Rectangle, bool) {
+	b := img.Bounds()
+	if b.Empty() {
+		return image.Rectangle{}, false
+	}
+
+	// Classify a small grid; a face spans many cells at any upload size
+	gw, gh := skinGridSize, b.Dy()*skinGridSize/b.Dx()
+	if b.Dy() > b.Dx() {
+		gw, gh = b.Dx()*skinGridSize/b.Dy(), skinGridSize
+	}
+	gw, gh = max(gw, 1), max(gh, 1)
+	small := image.NewRGBA(image.Rect(0, 0, gw, gh))
+	draw.ApproxBiLinear.Scale(small, small.Bounds(), img, b, draw.Src, nil)
+
+	skin := make([]bool, gw*gh)
+	for y := 0; y < gh; y++ {
+		for x := 0; x < gw; x++ {
+			i := small.PixOffset(x, y)
+			skin[y*gw+x] = isSkin(small.Pix[i], small.Pix[i+1], small.Pix[i+2])
+		}
+	}
+
+	// Largest 4-connected component
+	seen := make([]bool, len(skin))
+	var best image.Rectangle
+	bestSize := 0
+	stack := make([]int, 0, len(skin))
+	for start := range skin {
+		if !skin[start] || seen[start] {
+			continue
+		}
+		seen[start] = true
+		stack = append(stack[:0], start)
+		size := 0
+		box := image.Rect(start%gw, start/gw, start%gw+1, start/gw+1)
+		for len(stack) > 0 {
+			cell := stack[len(stack)-1]
+			stack = stack[:len(stack)-1]
+			size++
+			x, y := cell%gw, cell/gw
+			box = box.Union(image.Rect(x, y, x+1, y+1))
+			for _, next := range [4][2]int{{x - 1, y}, {x + 1, y}, {x, y - 1}, {x, y + 1}} {
+				nx, ny := next[0], next[1]
+				if nx < 0 || ny < 0 || nx >= gw || ny >= gh {
+					continue
+				}
+				n := ny*gw + nx
+				if skin[n] && !seen[n] {
+					seen[n] = true
+					stack = append(stack, n)
+				}
+			}
+		}
+		if size > bestSize {
+			best, bestSize = box, size
+		}
+	}
+
+	total := float64(gw * gh)
+	area := float64(bestSize) / total
+	if bestSize == 0 || area < d.MinArea || area > d.MaxArea {
+		return image.Rectangle{}, false
+	}
+	if fill := float64(bestSize) / float64(best.Dx()*best.Dy()); fill < d.MinFill {
+		return image.Rectangle{}, false
+	}
+	// Faces are roughly as tall as wide; long strips are arms, walls or wood
+	if ratio := float64(best.Dx()) / float64(best.Dy()); ratio < 0.4 || ratio > 2.0 {
+		return image.Rectangle{}, false
+	}
+
+	return image.Rect(
+		b.Min.X+best.Min.X*b.Dx()/gw, b.Min.Y+best.Min.Y*b.Dy()/gh,
+		b.Min.X+best.Max.X*b.Dx()/gw, b.Min.Y+best.Max.Y*b.Dy()/gh,
+	), true
+}
+
+// isSkin is the common YCbCr skin-tone box, which holds up across skin
+// tones because it ignores luma.
+func isSkin(r, g, b uint8) bool {
+	fr, fg, fb := float64(r), float64(g), float64(b)
+	cb := 128 - 0.168736*fr - 0.331264*fg + 0.5*fb
+	cr := 128 + 0.5*fr - 0.418688*fg - 0.081312*fb
+	luma := 0.299*fr + 0.587*fg + 0.114*fb
+	return luma > 40 && cb >= 77 && cb <= 127 && cr >= 133 && cr <= 173
+}
@@ -0,0 +1,112 @@
+package imaging
+
+import (
+	"fmt"
+	"image"
+	"math"
+	"math/bits"
+	"sort"
+	"strconv"
+
+	"golang.org/x/image/draw"
+)
+
+const (
+	phashSampleSize = 32
+	phashBlockSize  = 8
+)
+
+// PHash is a 64-bit DCT perceptual hash. Visually similar images differ in
+// only a few bits, regardless of scaling or recompression.
+type PHash uint64
+
+// ComputePHash samples img down to 32x32 grayscale, takes the low-frequency
+// 8x8 corner of its DCT and sets one bit per coefficient above the median.
+func ComputePHash(img image.Image) PHash {
+	gray := image.NewGray(image.Rect(0, 0, phashSampleSize, phashSampleSize))
+	draw.ApproxBiLinear.Scale(gray, gray.Bounds(), img, img.Bounds(), draw.Src, nil)
+
+	pixels := make([][]float64, phashSampleSize)
+	for y := range pixels {
+		pixels[y] = make([]float64, phashSampleSize)
+		for x := range pixels[y] {
+			pixels[y][x] = float64(gray.GrayAt(x, y).Y)
+		}
+	}
+
+	coeffs := dct2D(pixels)
+	values := make([]float64, 0, phashBlockSize*phashBlockSize)
+	for y := 0; y < phashBlockSize; y++ {
+		for x := 0; x < phashBlockSize; x++ {
+			values = append(values, coeffs[y][x])
+		}
+	}
+
+	sorted := append([]float64(nil), values...)
+	sort.Float64s(sorted)
+	median := (sorted[len(sorted)/2-1] + sorted[len(sorted)/2]) / 2
+
+	var hash uint64
+	for i, v := range values {
+		if v > median {
+			hash |= 1 << uint(len(values)-1-i)
+		}
+	}
+	return PHash(hash)
+}
+
+// Distance is the number of differing bits; 0 means perceptually identical.
+func (h PHash) Distance(other PHash) int {
+	return bits.OnesCount64(uint64(h ^ other))
+}
+
+// String returns the hash as 16 hex digits.
+func (h PHash) String() string {
+	return fmt.Sprintf("%016x", uint64(h))
+}
+
+// ParsePHash reads a hash produced by String.
+func ParsePHash(s string) (PHash, error) {
+	v, err := strconv.ParseUint(s, 16, 64)
+	if err != nil {
+		return 0, err
+	}
+	return PHash(v), nil
+}
+
+// dct2D is a separable DCT-II over a square matrix.
+func dct2D(in [][]float64) [][]float64 {
+	n := len(in)
+	cos := make([][]float64, n)
+	for u := 0; u < n; u++ {
+		cos[u] = make([]float64, n)
+		for x := 0; x < n; x++ {
+			cos[u][x] = math.Cos(float64(2*x+1) * float64(u) * math.Pi / float64(2*n))
+		}
+	}
+
+	rows := make([][]float64, n)
+	for y := 0; y < n; y++ {
+		rows[y] = make([]float64, n)
+		for u := 0; u < n; u++ {
+			var sum float64
+			for x := 0; x < n; x++ {
+				sum += in[y][x] * cos[u][x]
+			}
+			rows[y][u] = sum
+		}
+	}
+
+	out := make([][]float64, n)
+	for v := 0; v < n; v++ {
+		out[v] = make([]float64, n)
+		for u := 0; u < n; u++ {
+			var sum float64
+			for y := 0; y < n; y++ {
+				sum += rows[y][u] * cos[v][y]
+			}
+			out[v][u] = sum
+		}
+	}
+	return out
+}
@@ -0,0 +1,85 @@
+package imaging
+
+import (
+	"image"
+	"image/color"
+	"testing"
+)
+
+func TestPHashDistance(t *testing.T) {
+	tests := []struct {
+		a, b PHash
+		want int
+	}{
+		{0, 0, 0},
+		{0xffffffffffffffff, 0xffffffffffffffff, 0},
+		{0, 1, 1},
+		{0, 0xffffffffffffffff, 64},
+		{0xf0f0f0f0f0f0f0f0, 0x0f0f0f0f0f0f0f0f, 64},
+		{0x8000000000000001, 0x0000000000000001, 1},
+	}
+	for _, tt := range tests {
+		if got := tt.a.Distance(tt.b); got != tt.want {
+			t.Errorf("%s.Distance(%s) = %d, want %d", tt.a, tt.b, got, tt.want)
+		}
+		if got := tt.b.Distance(tt.a); got != tt.want {
+			t.Errorf("%s.Distance(%s) = %d, want %d", tt.b, tt.a, got, tt.want)
+		}
+	}
+}
+
+func TestPHashStringRoundTrip(t *testing.T) {
+	for _, h := range []PHash{0, 1, 0xdeadbeef, 0xffffffffffffffff} {
+		s := h.String()
+		if len(s) != 16 {
+			t.Errorf("%d.String() = %q, want 16 hex digits", uint64(h), s)
+		}
+		got, err := ParsePHash(s)
+		if err != nil || got != h {
+			t.Errorf("ParsePHash(%q) = %s, %v, want %s", s, got, err, h)
+		}
+	}
+	if _, err := ParsePHash("not a hash"); err == nil {
+		t.Error("ParsePHash accepted a non-hex string")
+	}
+}
+
+// pattern draws a few soft blobs so the hash has structure to work with.
+func pattern(size int, invert bool) image.Image {
+	img := image.NewGray(image.Rect(0, 0, size, size))
+	for y := 0; y < size; y++ {
+		for x := 0; x < size; x++ {
+			fx, fy := float64(x)/float64(size), float64(y)/float64(size)
+			v := uint8(255 * (fx*0.6 + fy*fy*0.4))
+			if fx > 0.2 && fx < 0.45 && fy > 0.55 && fy < 0.8 {
+				v = 230
+			}
+			if invert {
+				v = 255 - v
+			}
+			img.SetGray(x, y, color.Gray{Y: v})
+		}
+	}
+	return img
+}
+
+func TestComputePHash(t *testing.T) {
+	base := ComputePHash(pattern(256, false))
+
+	tests := []struct {
+		name    string
+		img     image.Image
+		maxDist int
+		minDist int
+	}{
+		{"same image", pattern(256, false), 0, 0},
+		{"rescaled", pattern(640, false), 4, 0},
+		{"inverted", pattern(256, true), 64, 32},
+	}
+	for _, tt := range tests {
+		got := base.Distance(ComputePHash(tt.img))
+		if got > tt.maxDist || got < tt.minDist {
+			t.Errorf("%s: distance = %d, want %d..%d", tt.name, got, tt.minDist, tt.maxDist)
+		}
+	}
+}
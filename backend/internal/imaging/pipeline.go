@@ -0,0 +1,317 @@
+package imaging
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"image/png"
+	"strings"
+
+	"golang.org/x/image/draw"
+	"golang.org/x/image/webp"
+)
+
+var (
+	ErrEmptyImage         = errors.New("image is empty")
+	ErrInvalidEncoding    = errors.New("image is not valid base64")
+	ErrUnsupportedFormat  = errors.New("unsupported image format, use JPEG, PNG or WebP")
+	ErrCorruptImage       = errors.New("image data is corrupt or truncated")
+	ErrImageTooSmall      = errors.New("image resolution is too low")
+	ErrImageTooLarge      = errors.New("image resolution is too high")
+	ErrImageFileTooLarge  = errors.New("image file is too large")
+	errUnknownOrientation = errors.New("no orientation tag")
+)
+
+// Source formats accepted by the pipeline.
+const (
+	FormatJPEG = "jpeg"
+	FormatPNG  = "png"
+	FormatWebP = "webp"
+)
+
+// ContentType of every processed image.
+const ContentType = "image/jpeg"
+
+const jpegQuality = 90
+
+type Options struct {
+	// MinDimension is the smallest allowed short side, in pixels.
+	MinDimension int
+	// MaxDimension is the largest allowed long side of the upload, in pixels.
+	MaxDimension int
+	// MaxPixels caps width times height of the upload, which bounds the
+	// memory decoding it takes.
+	MaxPixels int
+	// CanonicalSize is the long side processed images are scaled down to.
+	CanonicalSize int
+	// MaxBytes caps the decoded upload size.
+	MaxBytes int
+}
+
+func (o Options) withDefaults() Options {
+	if o.MinDimension <= 0 {
+		o.MinDimension = 256
+	}
+	if o.MaxDimension <= 0 {
+		o.MaxDimension = 8000
+	}
+	if o.MaxPixels <= 0 {
+		o.MaxPixels = 24_000_000
+	}
+	if o.CanonicalSize <= 0 {
+		o.CanonicalSize = 1024
+	}
+	if o.MaxBytes <= 0 {
+		o.MaxBytes = 10 << 20
+	}
+	return o
+}
+
+// Image is an upload after validation and normalization. Data is a freshly
+// encoded JPEG, so none of the upload's metadata (EXIF, GPS, ICC, comments)
+// survives.
+type Image struct {
+	Data           []byte
+	Format         string
+	Width          int
+	Height         int
+	OriginalWidth  int
+	OriginalHeight int
+	// SHA256 is the hex digest of Data.
+	SHA256 string
+	PHash  PHash
+	// Pixels is the canonical image before JPEG encoding, for checks that
+	// need pixels without decoding Data again.
+	Pixels *image.RGBA
+}
+
+// Base64 returns Data as standard base64.
+func (img *Image) Base64() string {
+	return base64.StdEncoding.EncodeToString(img.Data)
+}
+
+// DataURL returns Data as a data: URL suitable for vision models.
+func (img *Image) DataURL() string {
+	return "data:" + ContentType + ";base64," + img.Base64()
+}
+
+// Pipeline decodes, validates and normalizes uploaded photos.
+type Pipeline struct {
+	opts Options
+}
+
+func NewPipeline(opts Options) *Pipeline {
+	return &Pipeline{opts: opts.withDefaults()}
+}
+
+// ProcessBase64 accepts raw base64 or a data: URL.
+func (p *Pipeline) ProcessBase64(encoded string) (*Image, error) {
+	encoded = strings.TrimSpace(encoded)
+	if strings.HasPrefix(encoded, "data:") {
+		idx := strings.Index(encoded, ",")
+		if idx < 0 {
+			return nil, ErrInvalidEncoding
+		}
+		encoded = encoded[idx+1:]
+	}
+	if encoded == "" {
+		return nil, ErrEmptyImage
+	}
+	// Reject before decoding anything that cannot fit the byte limit
+	if base64.StdEncoding.DecodedLen(len(encoded)) > p.opts.MaxBytes+2 {
+		return nil, ErrImageFileTooLarge
+	}
+
+	data, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil {
+		if data, err = base64.RawStdEncoding.DecodeString(encoded); err != nil {
+			return nil, ErrInvalidEncoding
+		}
+	}
+	return p.Process(data)
+}
+
+// Process validates an upload and returns it auto-oriented, scaled to fit the
+// canonical size and re-encoded without metadata.
+func (p *Pipeline) Process(data []byte) (*Image, error) {
+	if len(data) == 0 {
+		return nil, ErrEmptyImage
+	}
+	if len(data) > p.opts.MaxBytes {
+		return nil, ErrImageFileTooLarge
+	}
+
+	format := sniffFormat(data)
+	if format == "" {
+		return nil, ErrUnsupportedFormat
+	}
+
+	// Dimensions come from the header so oversized images are rejected
+	// before any pixels are allocated.
+	cfg, err := decodeConfig(data, format)
+	if err != nil {
+		return nil, ErrCorruptImage
+	}
+	if err := p.checkDimensions(cfg.Width, cfg.Height); err != nil {
+		return nil, err
+	}
+
+	src, err := decode(data, format)
+	if err != nil {
+		return nil, ErrCorruptImage
+	}
+
+	// Scaling first keeps orientation to one canonical-size buffer
+	canonical := orient(fit(src, p.opts.CanonicalSize), readOrientation(data, format))
+
+	var buf bytes.Buffer
+	if err := jpeg.Encode(&buf, canonical, &jpeg.Options{Quality: jpegQuality}); err != nil {
+		return nil, fmt.Errorf("failed to encode image: %w", err)
+	}
+
+	sum := sha256.Sum256(buf.Bytes())
+	bounds := canonical.Bounds()
+	return &Image{
+		Data:           buf.Bytes(),
+		Format:         format,
+		Width:          bounds.Dx(),
+		Height:         bounds.Dy(),
+		OriginalWidth:  cfg.Width,
+		OriginalHeight: cfg.Height,
+		SHA256:         hex.EncodeToString(sum[:]),
+		PHash:          ComputePHash(canonical),
+		Pixels:         canonical,
+	}, nil
+}
+
+func (p *Pipeline) checkDimensions(width, height int) error {
+	short, long := width, height
+	if short > long {
+		short, long = long, short
+	}
+	if short < p.opts.MinDimension {
+		return fmt.Errorf("%w: %dx%d, minimum is %dpx on the short side", ErrImageTooSmall, width, height, p.opts.MinDimension)
+	}
+	if long > p.opts.MaxDimension {
+		return fmt.Errorf("%w: %dx%d, maximum is %dpx on the long side", ErrImageTooLarge, width, height, p.opts.MaxDimension)
+	}
+	if int64(width)*int64(height) > int64(p.opts.MaxPixels) {
+		return fmt.Errorf("%w: %dx%d, maximum is %d pixels", ErrImageTooLarge, width, height, p.opts.MaxPixels)
+	}
+	return nil
+}
+
+// sniffFormat identifies the container from its magic bytes rather than
+// trusting a client-declared type.
+func sniffFormat(data []byte) string {
+	switch {
+	case bytes.HasPrefix(data, []byte{0xFF, 0xD8, 0xFF}):
+		return FormatJPEG
+	case bytes.HasPrefix(data, []byte("\x89PNG\r\n\x1a\n")):
+		return FormatPNG
+	case len(data) >= 12 && string(data[0:4]) == "RIFF" && string(data[8:12]) == "WEBP":
+		return FormatWebP
+	}
+	return ""
+}
+
+func decodeConfig(data []byte, format string) (image.Config, error) {
+	r := bytes.NewReader(data)
+	switch format {
+	case FormatJPEG:
+		return jpeg.DecodeConfig(r)
+	case FormatPNG:
+		return png.DecodeConfig(r)
+	default:
+		return webp.DecodeConfig(r)
+	}
+}
+
+func decode(data []byte, format string) (image.Image, error) {
+	r := bytes.NewReader(data)
+	switch format {
+	case FormatJPEG:
+		return jpeg.Decode(r)
+	case FormatPNG:
+		return png.Decode(r)
+	default:
+		return webp.Decode(r)
+	}
+}
+
+// orient applies an EXIF orientation (1-8). Upright images are returned as
+// they are.
+func orient(src *image.RGBA, orientation int) *image.RGBA {
+	if orientation <= 1 || orientation > 8 {
+		return src
+	}
+	b := src.Bounds()
+	w, h := b.Dx(), b.Dy()
+
+	dw, dh := w, h
+	if orientation >= 5 {
+		dw, dh = h, w
+	}
+	dst := image.NewRGBA(image.Rect(0, 0, dw, dh))
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			var dx, dy int
+			switch orientation {
+			case 2: // mirrored
+				dx, dy = w-1-x, y
+			case 3: // rotated 180°
+				dx, dy = w-1-x, h-1-y
+			case 4: // flipped vertically
+				dx, dy = x, h-1-y
+			case 5: // transposed
+				dx, dy = y, x
+			case 6: // rotated 90° clockwise
+				dx, dy = h-1-y, x
+			case 7: // transversed
+				dx, dy = h-1-y, w-1-x
+			case 8: // rotated 90° counter-clockwise
+				dx, dy = y, w-1-x
+			}
+			i := src.PixOffset(b.Min.X+x, b.Min.Y+y)
+			j := dst.PixOffset(dx, dy)
+			copy(dst.Pix[j:j+4], src.Pix[i:i+4])
+		}
+	}
+	return dst
+}
+
+// fit scales src down so its long side is at most size, flattening any
+// transparency onto white since the output is JPEG. Smaller images keep
+// their size; upscaling adds no detail.
+func fit(src image.Image, size int) *image.RGBA {
+	b := src.Bounds()
+	w, h := b.Dx(), b.Dy()
+	dw, dh := w, h
+	if w > size || h > size {
+		dw, dh = size, h*size/w
+		if h > w {
+			dw, dh = w*size/h, size
+		}
+		if dw < 1 {
+			dw = 1
+		}
+		if dh < 1 {
+			dh = 1
+		}
+	}
+
+	dst := image.NewRGBA(image.Rect(0, 0, dw, dh))
+	draw.Draw(dst, dst.Bounds(), image.NewUniform(color.White), image.Point{}, draw.Src)
+	if dw == w && dh == h {
+		draw.Draw(dst, dst.Bounds(), src, b.Min, draw.Over)
+	} else {
+		draw.CatmullRom.Scale(dst, dst.Bounds(), src, b, draw.Over, nil)
+	}
+	return dst
+}
@@ -0,0 +1,110 @@
+package imaging
+
+import (
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	"reflect"
+	"testing"
+)
+
+func TestOrient(t *testing.T) {
+	// A 3x2 source whose pixels carry their own coordinates, placed off the
+	// origin to catch offset mistakes. Each row of want lists the source
+	// pixel, as "xy", that lands there.
+	src := image.NewRGBA(image.Rect(5, 5, 8, 7))
+	for y := 0; y < 2; y++ {
+		for x := 0; x < 3; x++ {
+			src.SetRGBA(5+x, 5+y, color.RGBA{R: uint8(x), G: uint8(y), A: 255})
+		}
+	}
+
+	tests := []struct {
+		orientation int
+		want        [][]string
+	}{
+		{1, [][]string{{"00", "10", "20"}, {"01", "11", "21"}}},
+		{2, [][]string{{"20", "10", "00"}, {"21", "11", "01"}}},
+		{3, [][]string{{"21", "11", "01"}, {"20", "10", "00"}}},
+		{4, [][]string{{"01", "11", "21"}, {"00", "10", "20"}}},
+		{5, [][]string{{"00", "01"}, {"10", "11"}, {"20", "21"}}},
+		{6, [][]string{{"01", "00"}, {"11", "10"}, {"21", "20"}}},
+		{7, [][]string{{"21", "20"}, {"11", "10"}, {"01", "00"}}},
+		{8, [][]string{{"20", "21"}, {"10", "11"}, {"00", "01"}}},
+	}
+	for _, tt := range tests {
+		t.Run(fmt.Sprint(tt.orientation), func(t *testing.T) {
+			got := orient(src, tt.orientation)
+			b := got.Bounds()
+			labels := make([][]string, b.Dy())
+			for y := range labels {
+				labels[y] = make([]string, b.Dx())
+				for x := range labels[y] {
+					c := got.RGBAAt(b.Min.X+x, b.Min.Y+y)
+					labels[y][x] = fmt.Sprintf("%d%d", c.R, c.G)
+				}
+			}
+			if !reflect.DeepEqual(labels, tt.want) {
+				t.Errorf("orient(%d) = %v, want %v", tt.orientation, labels, tt.want)
+			}
+		})
+	}
+}
+
+func TestOrientKeepsUnknownOrientations(t *testing.T) {
+	src := image.NewRGBA(image.Rect(0, 0, 3, 2))
+	for _, orientation := range []int{0, 1, 9, -1} {
+		if got := orient(src, orientation); got != src {
+			t.Errorf("orient(%d) copied the image, want it returned as is", orientation)
+		}
+	}
+}
+
+func TestCheckDimensions(t *testing.T) {
+	p := NewPipeline(Options{MinDimension: 256, MaxDimension: 8000, MaxPixels: 24_000_000})
+
+	tests := []struct {
+		width, height int
+		want          error
+	}{
+		{1024, 768, nil},
+		{256, 8000, nil},
+		{255, 1024, ErrImageTooSmall},
+		{1024, 255, ErrImageTooSmall},
+		{8001, 1024, ErrImageTooLarge},
+		{6000, 4000, nil},
+		{6000, 4001, ErrImageTooLarge},
+	}
+	for _, tt := range tests {
+		if err := p.checkDimensions(tt.width, tt.height); !errors.Is(err, tt.want) {
+			t.Errorf("checkDimensions(%d, %d) = %v, want %v", tt.width, tt.height, err, tt.want)
+		}
+	}
+}
+
+func TestFit(t *testing.T) {
+	tests := []struct {
+		width, height int
+		size          int
+		wantW, wantH  int
+	}{
+		{2000, 1000, 1024, 1024, 512},
+		{1000, 2000, 1024, 512, 1024},
+		{800, 600, 1024, 800, 600},
+		{1024, 1024, 1024, 1024, 1024},
+	}
+	for _, tt := range tests {
+		got := fit(image.NewNRGBA(image.Rect(0, 0, tt.width, tt.height)), tt.size).Bounds()
+		if got.Dx() != tt.wantW || got.Dy() != tt.wantH {
+			t.Errorf("fit(%dx%d, %d) = %dx%d, want %dx%d", tt.width, tt.height, tt.size, got.Dx(), got.Dy(), tt.wantW, tt.wantH)
+		}
+	}
+}
+
+func TestFitFlattensTransparencyOntoWhite(t *testing.T) {
+	got := fit(image.NewNRGBA(image.Rect(0, 0, 4, 4)), 1024)
+	if c := got.RGBAAt(1, 1); c != (color.RGBA{R: 255, G: 255, B: 255, A: 255}) {
+		t.Errorf("transparent pixel = %v, want opaque white", c)
+	}
+}
@@ -0,0 +1,286 @@
+package imaging
+
+import (
+	"errors"
+	"fmt"
+	"image"
+	"math"
+	"strings"
+
+	"golang.org/x/image/draw"
+
+	"github.com/ahmetcoskunkizilkaya/mewify/backend/internal/i18n"
+)
+
+// ErrLowQuality wraps every QualityError, so callers can match rejections
+// with errors.Is.
+var ErrLowQuality = errors.New("photo did not pass the quality check")
+
+// Quality issue codes
+const (
+	IssueResolution   = "low_resolution"
+	IssueAspectRatio  = "aspect_ratio"
+	IssueBlurry       = "blurry"
+	IssueUnderexposed = "underexposed"
+	IssueOverexposed  = "overexposed"
+	IssueLowContrast  = "low_contrast"
+	IssueNoFace       = "no_face"
+	IssueFaceTooSmall = "face_too_small"
+)
+
+// QualityIssue is one failed check.
+type QualityIssue struct {
+	Code string `json:"code"`
+	Hint string `json:"hint"`
+}
+
+// QualityReport carries the measurements behind a gate decision.
+type QualityReport struct {
+	Issues []QualityIssue `json:"issues"`
+	// Sharpness is the variance of the Laplacian on a normalized grayscale
+	Sharpness float64 `json:"sharpness"`
+	// Brightness and Contrast are the luma mean and standard deviation (0-255)
+	Brightness float64 `json:"brightness"`
+	Contrast   float64 `json:"contrast"`
+	// ShadowClip and HighlightClip are the fractions of crushed pixels
+	ShadowClip    float64 `json:"shadow_clip"`
+	HighlightClip float64 `json:"highlight_clip"`
+	// FaceArea is the detected face's share of the frame; 0 when none
+	FaceArea float64 `json:"face_area"`
+}
+
+func (r *QualityReport) Passed() bool {
+	return len(r.Issues) == 0
+}
+
+func (r *QualityReport) add(code string) {
+	r.Issues = append(r.Issues, QualityIssue{Code: code, Hint: retakeHint(i18n.Default, code)})
+}
+
+// Localize rewrites the retake hints in locale.
+func (r *QualityReport) Localize(locale string) {
+	for i := range r.Issues {
+		r.Issues[i].Hint = retakeHint(locale, r.Issues[i].Code)
+	}
+}
+
+// retakeHint tells the user how to fix an issue.
+func retakeHint(locale, code string) string {
+	return i18n.T(locale, "quality.hint."+code)
+}
+
+// QualityError rejects a photo with the report explaining why.
+type QualityError struct {
+	Report *QualityReport
+}
+
+func (e *QualityError) Error() string {
+	codes := make([]string, len(e.Report.Issues))
+	for i, issue := range e.Report.Issues {
+		codes[i] = issue.Code
+	}
+	return fmt.Sprintf("%s: %s", ErrLowQuality, strings.Join(codes, ", "))
+}
+
+func (e *QualityError) Unwrap() error {
+	return ErrLowQuality
+}
+
+type QualityOptions struct {
+	// MinResolution is the smallest allowed short side of the upload.
+	MinResolution int
+	// MaxAspectRatio caps long side / short side.
+	MaxAspectRatio float64
+	// MinSharpness is the Laplacian variance below which a photo is blurry.
+	MinSharpness float64
+	// MinBrightness and MaxBrightness bound the mean luma.
+	MinBrightness float64
+	MaxBrightness float64
+	// MinContrast is the smallest allowed luma standard deviation.
+	MinContrast float64
+	// MaxClip is the largest fraction of pure black or pure white pixels.
+	MaxClip float64
+	// MinFaceArea is the smallest share of the frame the face may cover.
+	MinFaceArea float64
+	// Detector finds the face; nil skips the face checks.
+	Detector FaceDetector
+}
+
+func (o QualityOptions) withDefaults() QualityOptions {
+	if o.MinResolution <= 0 {
+		o.MinResolution = 400
+	}
+	if o.MaxAspectRatio <= 0 {
+		o.MaxAspectRatio = 2.2
+	}
+	if o.MinSharpness <= 0 {
+		o.MinSharpness = 40
+	}
+	if o.MinBrightness <= 0 {
+		o.MinBrightness = 45
+	}
+	if o.MaxBrightness <= 0 {
+		o.MaxBrightness = 215
+	}
+	if o.MinContrast <= 0 {
+		o.MinContrast = 18
+	}
+	if o.MaxClip <= 0 {
+		o.MaxClip = 0.35
+	}
+	if o.MinFaceArea <= 0 {
+		o.MinFaceArea = 0.05
+	}
+	return o
+}
+
+// sharpnessSize is the long side photos are measured at, so the blur
+// threshold means the same thing at every upload resolution.
+const sharpnessSize = 512
+
+// QualityGate rejects photos that cannot be scored meaningfully.
+type QualityGate struct {
+	opts QualityOptions
+}
+
+func NewQualityGate(opts QualityOptions) *QualityGate {
+	return &QualityGate{opts: opts.withDefaults()}
+}
+
+// Check measures a processed image. It returns the report either way, and a
+// *QualityError when any check failed.
+func (g *QualityGate) Check(img *Image) (*QualityReport, error) {
+	report := &QualityReport{Issues: []QualityIssue{}}
+
+	short, long := img.OriginalWidth, img.OriginalHeight
+	if short > long {
+		short, long = long, short
+	}
+	if short < g.opts.MinResolution {
+		report.add(IssueResolution)
+	}
+	if short > 0 && float64(long)/float64(short) > g.opts.MaxAspectRatio {
+		report.add(IssueAspectRatio)
+	}
+
+	pixels := img.Pixels
+	if pixels == nil {
+		return report, nil
+	}
+	gray := grayscale(pixels, sharpnessSize)
+
+	report.Sharpness = round(laplacianVariance(gray))
+	if report.Sharpness < g.opts.MinSharpness {
+		report.add(IssueBlurry)
+	}
+
+	exposure(gray, report)
+	switch {
+	case report.Brightness < g.opts.MinBrightness || report.ShadowClip > g.opts.MaxClip:
+		report.add(IssueUnderexposed)
+	case report.Brightness > g.opts.MaxBrightness || report.HighlightClip > g.opts.MaxClip:
+		report.add(IssueOverexposed)
+	case report.Contrast < g.opts.MinContrast:
+		report.add(IssueLowContrast)
+	}
+
+	if g.opts.Detector != nil {
+		face, ok := g.opts.Detector.DetectFace(pixels)
+		b := pixels.Bounds()
+		if ok {
+			report.FaceArea = round(float64(face.Dx()*face.Dy()) / float64(b.Dx()*b.Dy()))
+		}
+		switch {
+		case !ok:
+			report.add(IssueNoFace)
+		case report.FaceArea < g.opts.MinFaceArea:
+			report.add(IssueFaceTooSmall)
+		}
+	}
+
+	if !report.Passed() {
+		return report, &QualityError{Report: report}
+	}
+	return report, nil
+}
+
+// grayscale scales img so its long side is at most size and converts it to
+// luma.
+func grayscale(img *image.RGBA, size int) *image.Gray {
+	b := img.Bounds()
+	w, h := b.Dx(), b.Dy()
+	if w > size || h > size {
+		if w >= h {
+			w, h = size, max(h*size/w, 1)
+		} else {
+			w, h = max(w*size/h, 1), size
+		}
+	}
+	gray := image.NewGray(image.Rect(0, 0, w, h))
+	draw.ApproxBiLinear.Scale(gray, gray.Bounds(), img, b, draw.Src, nil)
+	return gray
+}
+
+// laplacianVariance convolves with the 4-neighbour Laplacian kernel; sharp
+// edges give strong responses, so a low variance means a blurry photo.
+func laplacianVariance(gray *image.Gray) float64 {
+	b := gray.Bounds()
+	w, h := b.Dx(), b.Dy()
+	if w < 3 || h < 3 {
+		return 0
+	}
+
+	var sum, sumSq float64
+	n := 0
+	for y := 1; y < h-1; y++ {
+		for x := 1; x < w-1; x++ {
+			at := func(dx, dy int) float64 {
+				return float64(gray.Pix[(y+dy)*gray.Stride+x+dx])
+			}
+			v := at(-1, 0) + at(1, 0) + at(0, -1) + at(0, 1) - 4*at(0, 0)
+			sum += v
+			sumSq += v * v
+			n++
+		}
+	}
+	mean := sum / float64(n)
+	return sumSq/float64(n) - mean*mean
+}
+
+// exposure fills the luma histogram measurements of report.
+func exposure(gray *image.Gray, report *QualityReport) {
+	var histogram [256]int
+	for _, v := range gray.Pix {
+		histogram[v]++
+	}
+	total := float64(len(gray.Pix))
+	if total == 0 {
+		return
+	}
+
+	var sum, shadows, highlights float64
+	for v, count := range histogram {
+		sum += float64(v * count)
+		if v <= 15 {
+			shadows += float64(count)
+		}
+		if v >= 245 {
+			highlights += float64(count)
+		}
+	}
+	mean := sum / total
+	var variance float64
+	for v, count := range histogram {
+		d := float64(v) - mean
+		variance += d * d * float64(count)
+	}
+
+	report.Brightness = round(mean)
+	report.Contrast = round(math.Sqrt(variance / total))
+	report.ShadowClip = round(shadows / total)
+	report.HighlightClip = round(highlights / total)
+}
+
+func round(v float64) float64 {
+	return math.Round(v*100) / 100
+}
@@ -0,0 +1,144 @@
+package llm
+
+import (
+	"context"
+	"net/http"
+	"strings"
+)
+
+const (
+	anthropicMessagesURL = "https://api.anthropic.com/v1/messages"
+	anthropicVersion     = "2023-06-01"
+	anthropicMaxTokens   = 2048
+)
+
+// AnthropicCompatible talks to a Messages API endpoint.
+type AnthropicCompatible struct {
+	url    string
+	apiKey string
+	model  string
+	vision bool
+	client *http.Client
+}
+
+// NewAnthropicCompatible returns a Messages API provider. An empty url uses
+// the public API.
+func NewAnthropicCompatible(url, apiKey, model string, vision bool) *AnthropicCompatible {
+	if strings.TrimSpace(url) == "" {
+		url = anthropicMessagesURL
+	}
+	return &AnthropicCompatible{
+		url:    url,
+		apiKey: apiKey,
+		model:  model,
+		vision: vision,
+		client: &http.Client{},
+	}
+}
+
+func (p *AnthropicCompatible) Name() string         { return "anthropic" }
+func (p *AnthropicCompatible) Model() string        { return p.model }
+func (p *AnthropicCompatible) SupportsVision() bool { return p.vision }
+
+type anthropicRequest struct {
+	Model       string             `json:"model"`
+	System      string             `json:"system,omitempty"`
+	Messages    []anthropicMessage `json:"messages"`
+	MaxTokens   int                `json:"max_tokens"`
+	Temperature float64            `json:"temperature,omitempty"`
+}
+
+type anthropicMessage struct {
+	Role    string                 `json:"role"`
+	Content []anthropicContentPart `json:"content"`
+}
+
+type anthropicContentPart struct {
+	Type   string                `json:"type"`
+	Text   string                `json:"text,omitempty"`
+	Source *anthropicImageSource `json:"source,omitempty"`
+}
+
+type anthropicImageSource struct {
+	Type      string `json:"type"`
+	MediaType string `json:"media_type"`
+	Data      string `json:"data"`
+}
+
+type anthropicResponse struct {
+	Content []struct {
+		Type string `json:"type"`
+		Text string `json:"text"`
+	} `json:"content"`
+	Usage struct {
+		InputTokens  int `json:"input_tokens"`
+		OutputTokens int `json:"output_tokens"`
+	} `json:"usage"`
+}
+
+func (p *AnthropicCompatible) Complete(ctx context.Context, req Request) (*Response, error) {
+	maxTokens := req.MaxTokens
+	if maxTokens <= 0 {
+		maxTokens = anthropicMaxTokens
+	}
+
+	// The Messages API has no JSON response mode, so it is requested in the
+	// system prompt instead.
+	system := req.System
+	if req.JSON {
+		system = strings.TrimSpace(system + "\nRespond with a single valid JSON value and nothing else.")
+	}
+
+	body := anthropicRequest{
+		Model:       p.model,
+		System:      system,
+		MaxTokens:   maxTokens,
+		Temperature: req.Temperature,
+	}
+	for _, m := range req.Messages {
+		// Images go first, as the Messages API recommends.
+		var parts []anthropicContentPart
+		for _, img := range m.Images {
+			mediaType, data, err := splitDataURL(img)
+			if err != nil {
+				return nil, err
+			}
+			parts = append(parts, anthropicContentPart{
+				Type:   "image",
+				Source: &anthropicImageSource{Type: "base64", MediaType: mediaType, Data: data},
+			})
+		}
+		parts = append(parts, anthropicContentPart{Type: "text", Text: m.Text})
+		body.Messages = append(body.Messages, anthropicMessage{Role: m.Role, Content: parts})
+	}
+
+	headers := map[string]string{
+		"x-api-key":         p.apiKey,
+		"anthropic-version": anthropicVersion,
+	}
+
+	var out anthropicResponse
+	if err := postJSON(ctx, p.client, p.url, headers, body, &out); err != nil {
+		return nil, err
+	}
+
+	var text strings.Builder
+	for _, part := range out.Content {
+		if part.Type == "text" {
+			text.WriteString(part.Text)
+		}
+	}
+	if strings.TrimSpace(text.String()) == "" {
+		return nil, ErrEmptyCompletion
+	}
+
+	return &Response{
+		Content:  text.String(),
+		Provider: p.Name(),
+		Model:    p.model,
+		Usage: Usage{
+			PromptTokens:     out.Usage.InputTokens,
+			CompletionTokens: out.Usage.OutputTokens,
+		},
+	}, nil
+}
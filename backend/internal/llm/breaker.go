@@ -0,0 +1,226 @@
+package llm
+
+import (
+	"errors"
+	"sort"
+	"sync"
+	"time"
+)
+
+var ErrCircuitOpen = errors.New("circuit breaker open")
+
+type BreakerState string
+
+const (
+	BreakerClosed   BreakerState = "closed"
+	BreakerOpen     BreakerState = "open"
+	BreakerHalfOpen BreakerState = "half_open"
+)
+
+const maxBreakerSamples = 1000
+
+type BreakerOptions struct {
+	// Window is how far back the error rate and latency stats look.
+	Window time.Duration
+	// MinRequests is the sample size needed before the error rate can trip
+	// the breaker.
+	MinRequests int
+	// FailureRate in (0, 1] trips the breaker once reached.
+	FailureRate float64
+	// OpenDuration is how long a tripped breaker rejects calls before letting
+	// a single half-open probe through.
+	OpenDuration time.Duration
+}
+
+func (o BreakerOptions) withDefaults() BreakerOptions {
+	if o.Window <= 0 {
+		o.Window = time.Minute
+	}
+	if o.MinRequests <= 0 {
+		o.MinRequests = 5
+	}
+	if o.FailureRate <= 0 || o.FailureRate > 1 {
+		o.FailureRate = 0.5
+	}
+	if o.OpenDuration <= 0 {
+		o.OpenDuration = 30 * time.Second
+	}
+	return o
+}
+
+type breakerSample struct {
+	at      time.Time
+	ok      bool
+	latency time.Duration
+}
+
+// breaker is a per-provider circuit breaker over a rolling window of calls.
+type breaker struct {
+	opts BreakerOptions
+
+	mu            sync.Mutex
+	state         BreakerState
+	openedAt      time.Time
+	probeInFlight bool
+	samples       []breakerSample
+	lastError     string
+	lastErrorAt   time.Time
+}
+
+func newBreaker(opts BreakerOptions) *breaker {
+	return &breaker{opts: opts.withDefaults(), state: BreakerClosed}
+}
+
+// allow reports whether a call may proceed. Once the open period has elapsed
+// exactly one caller is let through as the half-open probe.
+func (b *breaker) allow(now time.Time) bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	switch b.state {
+	case BreakerOpen:
+		if now.Sub(b.openedAt) < b.opts.OpenDuration {
+			return false
+		}
+		b.state = BreakerHalfOpen
+		b.probeInFlight = true
+		return true
+	case BreakerHalfOpen:
+		if b.probeInFlight {
+			return false
+		}
+		b.probeInFlight = true
+		return true
+	default:
+		return true
+	}
+}
+
+func (b *breaker) isOpen() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.state == BreakerOpen
+}
+
+func (b *breaker) record(now time.Time, err error, latency time.Duration) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.samples = append(b.samples, breakerSample{at: now, ok: err == nil, latency: latency})
+	if len(b.samples) > maxBreakerSamples {
+		b.samples = b.samples[len(b.samples)-maxBreakerSamples:]
+	}
+	b.prune(now)
+
+	if err != nil {
+		b.lastError = err.Error()
+		b.lastErrorAt = now
+	}
+
+	switch b.state {
+	case BreakerHalfOpen:
+		b.probeInFlight = false
+		if err == nil {
+			// Recovered: start over with a clean window so the failures that
+			// tripped the breaker don't immediately trip it again.
+			b.state = BreakerClosed
+			b.samples = b.samples[:0]
+		} else {
+			b.trip(now)
+		}
+	case BreakerClosed:
+		total, failures := b.counts()
+		if total >= b.opts.MinRequests && float64(failures)/float64(total) >= b.opts.FailureRate {
+			b.trip(now)
+		}
+	}
+}
+
+// abandon releases a half-open probe whose call ended for reasons unrelated to
+// the provider, such as the caller going away.
+func (b *breaker) abandon() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.probeInFlight = false
+}
+
+func (b *breaker) trip(now time.Time) {
+	b.state = BreakerOpen
+	b.openedAt = now
+	b.probeInFlight = false
+}
+
+func (b *breaker) prune(now time.Time) {
+	cutoff := now.Add(-b.opts.Window)
+	i := 0
+	for i < len(b.samples) && b.samples[i].at.Before(cutoff) {
+		i++
+	}
+	b.samples = b.samples[i:]
+}
+
+func (b *breaker) counts() (total, failures int) {
+	for _, s := range b.samples {
+		total++
+		if !s.ok {
+			failures++
+		}
+	}
+	return total, failures
+}
+
+// ProviderHealth is a point-in-time view of one provider's breaker and stats.
+type ProviderHealth struct {
+	Name         string       `json:"name"`
+	Model        string       `json:"model"`
+	Vision       bool         `json:"vision"`
+	State        BreakerState `json:"state"`
+	Requests     int          `json:"requests"`
+	Failures     int          `json:"failures"`
+	ErrorRate    float64      `json:"error_rate"`
+	AvgLatencyMs int64        `json:"avg_latency_ms"`
+	P95LatencyMs int64        `json:"p95_latency_ms"`
+	OpenedAt     *time.Time   `json:"opened_at,omitempty"`
+	RetryAt      *time.Time   `json:"retry_at,omitempty"`
+	LastError    string       `json:"last_error,omitempty"`
+	LastErrorAt  *time.Time   `json:"last_error_at,omitempty"`
+}
+
+func (b *breaker) snapshot(now time.Time) ProviderHealth {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.prune(now)
+	total, failures := b.counts()
+
+	h := ProviderHealth{
+		State:     b.state,
+		Requests:  total,
+		Failures:  failures,
+		LastError: b.lastError,
+	}
+	if total > 0 {
+		h.ErrorRate = float64(failures) / float64(total)
+
+		latencies := make([]time.Duration, 0, total)
+		var sum time.Duration
+		for _, s := range b.samples {
+			latencies = append(latencies, s.latency)
+			sum += s.latency
+		}
+		sort.Slice(latencies, func(i, j int) bool { return latencies[i] < latencies[j] })
+		h.AvgLatencyMs = (sum / time.Duration(total)).Milliseconds()
+		h.P95LatencyMs = latencies[(len(latencies)*95-1)/100].Milliseconds()
+	}
+	if b.state != BreakerClosed {
+		openedAt := b.openedAt
+		retryAt := openedAt.Add(b.opts.OpenDuration)
+		h.OpenedAt = &openedAt
+		h.RetryAt = &retryAt
+	}
+	if !b.lastErrorAt.IsZero() {
+		lastErrorAt := b.lastErrorAt
+		h.LastErrorAt = &lastErrorAt
+	}
+	return h
+}
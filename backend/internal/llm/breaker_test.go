@@ -0,0 +1,143 @@
+package llm
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+var errProvider = errors.New("provider failed")
+
+func TestBreakerTrips(t *testing.T) {
+	opts := BreakerOptions{Window: time.Minute, MinRequests: 4, FailureRate: 0.5, OpenDuration: 30 * time.Second}
+
+	tests := []struct {
+		name    string
+		results []bool // true for a successful call
+		want    BreakerState
+	}{
+		{"no calls", nil, BreakerClosed},
+		{"below min requests", []bool{false, false, false}, BreakerClosed},
+		{"at failure rate", []bool{true, false, true, false}, BreakerOpen},
+		{"below failure rate", []bool{true, true, true, false}, BreakerClosed},
+		{"all failures", []bool{false, false, false, false}, BreakerOpen},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b := newBreaker(opts)
+			now := time.Unix(1_700_000_000, 0)
+			for i, ok := range tt.results {
+				var err error
+				if !ok {
+					err = errProvider
+				}
+				b.record(now.Add(time.Duration(i)*time.Second), err, 100*time.Millisecond)
+			}
+			if b.state != tt.want {
+				t.Errorf("state = %s, want %s", b.state, tt.want)
+			}
+		})
+	}
+}
+
+func TestBreakerIgnoresSamplesOutsideWindow(t *testing.T) {
+	b := newBreaker(BreakerOptions{Window: time.Minute, MinRequests: 4, FailureRate: 0.5})
+	start := time.Unix(1_700_000_000, 0)
+	for i := 0; i < 3; i++ {
+		b.record(start, errProvider, 0)
+	}
+	// The old failures have left the window, so one more is not enough.
+	b.record(start.Add(2*time.Minute), errProvider, 0)
+	if b.state != BreakerClosed {
+		t.Fatalf("state = %s, want %s", b.state, BreakerClosed)
+	}
+}
+
+func TestBreakerHalfOpen(t *testing.T) {
+	opts := BreakerOptions{Window: time.Minute, MinRequests: 1, FailureRate: 1, OpenDuration: 30 * time.Second}
+
+	tests := []struct {
+		name  string
+		probe error
+		want  BreakerState
+	}{
+		{"probe succeeds", nil, BreakerClosed},
+		{"probe fails", errProvider, BreakerOpen},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b := newBreaker(opts)
+			trippedAt := time.Unix(1_700_000_000, 0)
+			b.record(trippedAt, errProvider, 0)
+
+			if b.allow(trippedAt.Add(10 * time.Second)) {
+				t.Fatal("allow during open period = true, want false")
+			}
+			probeAt := trippedAt.Add(opts.OpenDuration)
+			if !b.allow(probeAt) {
+				t.Fatal("allow after open period = false, want true")
+			}
+			if b.allow(probeAt) {
+				t.Fatal("allow with probe in flight = true, want false")
+			}
+
+			b.record(probeAt, tt.probe, 0)
+			if b.state != tt.want {
+				t.Fatalf("state = %s, want %s", b.state, tt.want)
+			}
+			if tt.want == BreakerClosed && len(b.samples) != 0 {
+				t.Errorf("samples after recovery = %d, want 0", len(b.samples))
+			}
+			if tt.want == BreakerOpen && !b.openedAt.Equal(probeAt) {
+				t.Errorf("openedAt = %v, want %v", b.openedAt, probeAt)
+			}
+		})
+	}
+}
+
+func TestBreakerAbandonReleasesProbe(t *testing.T) {
+	b := newBreaker(BreakerOptions{MinRequests: 1, FailureRate: 1, OpenDuration: time.Second})
+	now := time.Unix(1_700_000_000, 0)
+	b.record(now, errProvider, 0)
+
+	probeAt := now.Add(time.Second)
+	if !b.allow(probeAt) {
+		t.Fatal("allow after open period = false, want true")
+	}
+	b.abandon()
+	if !b.allow(probeAt) {
+		t.Fatal("allow after abandoned probe = false, want true")
+	}
+}
+
+func TestBreakerSnapshot(t *testing.T) {
+	b := newBreaker(BreakerOptions{Window: time.Minute, MinRequests: 100})
+	now := time.Unix(1_700_000_000, 0)
+	for i := 1; i <= 20; i++ {
+		var err error
+		if i%4 == 0 {
+			err = errProvider
+		}
+		b.record(now, err, time.Duration(i)*10*time.Millisecond)
+	}
+
+	h := b.snapshot(now)
+	if h.Requests != 20 || h.Failures != 5 {
+		t.Errorf("requests, failures = %d, %d, want 20, 5", h.Requests, h.Failures)
+	}
+	if h.ErrorRate != 0.25 {
+		t.Errorf("error rate = %v, want 0.25", h.ErrorRate)
+	}
+	if h.AvgLatencyMs != 105 {
+		t.Errorf("avg latency = %dms, want 105ms", h.AvgLatencyMs)
+	}
+	if h.P95LatencyMs != 190 {
+		t.Errorf("p95 latency = %dms, want 190ms", h.P95LatencyMs)
+	}
+	if h.OpenedAt != nil || h.RetryAt != nil {
+		t.Error("closed breaker reports opened_at or retry_at")
+	}
+	if h.LastError != errProvider.Error() {
+		t.Errorf("last error = %q, want %q", h.LastError, errProvider.Error())
+	}
+}
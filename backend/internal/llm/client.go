@@ -0,0 +1,258 @@
+package llm
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	defaultTimeout      = 20 * time.Second
+	defaultRetryBackoff = 500 * time.Millisecond
+)
+
+// Registry holds every configured provider by name.
+type Registry struct {
+	mu        sync.RWMutex
+	providers map[string]Provider
+}
+
+func NewRegistry() *Registry {
+	return &Registry{providers: make(map[string]Provider)}
+}
+
+// Register adds p, replacing any provider registered under the same name.
+func (r *Registry) Register(p Provider) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.providers[p.Name()] = p
+}
+
+func (r *Registry) Get(name string) (Provider, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	p, ok := r.providers[name]
+	return p, ok
+}
+
+type Options struct {
+	// Timeout bounds a single attempt against one provider.
+	Timeout time.Duration
+	// MaxRetries is how many extra attempts a provider gets after a
+	// retryable failure before the chain moves on.
+	MaxRetries   int
+	RetryBackoff time.Duration
+	Breaker      BreakerOptions
+}
+
+// Client sends requests through an ordered fallback chain of providers.
+type Client struct {
+	registry *Registry
+	chain    []string
+	opts     Options
+
+	breakers *breakerSet
+	output   *outputStats
+}
+
+// breakerSet holds one circuit breaker per provider name. Clients narrowed
+// with Only share their parent's set, so a provider's failures trip the same
+// breaker whichever client saw them.
+type breakerSet struct {
+	mu       sync.Mutex
+	opts     BreakerOptions
+	breakers map[string]*breaker
+}
+
+func NewClient(registry *Registry, chain []string, opts Options) *Client {
+	if opts.Timeout <= 0 {
+		opts.Timeout = defaultTimeout
+	}
+	if opts.MaxRetries < 0 {
+		opts.MaxRetries = 0
+	}
+	if opts.RetryBackoff <= 0 {
+		opts.RetryBackoff = defaultRetryBackoff
+	}
+	return &Client{
+		registry: registry,
+		chain:    chain,
+		opts:     opts,
+		breakers: &breakerSet{opts: opts.Breaker, breakers: make(map[string]*breaker)},
+		output:   newOutputStats(),
+	}
+}
+
+func (c *Client) breaker(name string) *breaker {
+	set := c.breakers
+	set.mu.Lock()
+	defer set.mu.Unlock()
+
+	b, ok := set.breakers[name]
+	if !ok {
+		b = newBreaker(set.opts)
+		set.breakers[name] = b
+	}
+	return b
+}
+
+// Providers returns the registered providers in fallback order. Chain entries
+// that were never registered (for example, missing API keys) are skipped.
+func (c *Client) Providers() []Provider {
+	providers := make([]Provider, 0, len(c.chain))
+	for _, name := range c.chain {
+		if p, ok := c.registry.Get(name); ok {
+			providers = append(providers, p)
+		}
+	}
+	return providers
+}
+
+// Only returns a client that sends requests to the named provider alone, for
+// callers that need an answer from one specific model. It shares this
+// client's circuit breakers and output stats, so a dead provider fails fast
+// here too.
+func (c *Client) Only(name string) (*Client, bool) {
+	if _, ok := c.registry.Get(name); !ok {
+		return nil, false
+	}
+	only := NewClient(c.registry, []string{name}, c.opts)
+	only.breakers = c.breakers
+	only.output = c.output
+	return only, true
+}
+
+// HasVisionProvider reports whether any provider in the chain accepts images.
+func (c *Client) HasVisionProvider() bool {
+	for _, p := range c.Providers() {
+		if p.SupportsVision() {
+			return true
+		}
+	}
+	return false
+}
+
+// Complete tries each provider in order and returns the first success.
+// Requests carrying images skip providers without vision support, and
+// providers with an open circuit are skipped without waiting on them.
+func (c *Client) Complete(ctx context.Context, req Request) (*Response, error) {
+	providers := c.Providers()
+	if len(providers) == 0 {
+		return nil, ErrNoProvider
+	}
+
+	needsVision := req.HasImages()
+	var errs []error
+	for _, p := range providers {
+		if needsVision && !p.SupportsVision() {
+			errs = append(errs, fmt.Errorf("%s: %w", p.Name(), ErrVisionUnsupported))
+			continue
+		}
+
+		b := c.breaker(p.Name())
+		if !b.allow(time.Now()) {
+			errs = append(errs, fmt.Errorf("%s: %w", p.Name(), ErrCircuitOpen))
+			continue
+		}
+
+		resp, err := c.completeWithRetry(ctx, p, b, req)
+		if err == nil {
+			return resp, nil
+		}
+		errs = append(errs, fmt.Errorf("%s: %w", p.Name(), err))
+
+		if ctx.Err() != nil {
+			break
+		}
+	}
+
+	return nil, fmt.Errorf("%w: %w", ErrAllProvidersFailed, errors.Join(errs...))
+}
+
+func (c *Client) completeWithRetry(ctx context.Context, p Provider, b *breaker, req Request) (*Response, error) {
+	progress := progressFrom(ctx)
+	var lastErr error
+	for attempt := 0; attempt <= c.opts.MaxRetries; attempt++ {
+		if attempt > 0 {
+			select {
+			case <-ctx.Done():
+				return nil, ctx.Err()
+			case <-time.After(c.opts.RetryBackoff << (attempt - 1)):
+			}
+		}
+
+		attemptCtx, cancel := context.WithTimeout(ctx, c.opts.Timeout)
+		start := time.Now()
+		resp, err := c.attempt(attemptCtx, p, req, progress, attempt+1)
+		latency := time.Since(start)
+		cancel()
+
+		// A cancelled caller says nothing about the provider's health
+		if ctx.Err() != nil {
+			b.abandon()
+			if err != nil {
+				return nil, err
+			}
+		}
+		b.record(time.Now(), err, latency)
+
+		if err == nil {
+			resp.Latency = latency
+			return resp, nil
+		}
+		lastErr = err
+
+		if !retryable(err) || b.isOpen() {
+			break
+		}
+	}
+	return nil, lastErr
+}
+
+// attempt calls p once, streaming the reply when a listener wants progress
+// and the provider can stream.
+func (c *Client) attempt(ctx context.Context, p Provider, req Request, progress func(Progress), n int) (*Response, error) {
+	if progress == nil {
+		return p.Complete(ctx, req)
+	}
+	progress(Progress{Kind: ProgressAttempt, Provider: p.Name(), Model: p.Model(), Attempt: n})
+
+	streamer, ok := p.(Streamer)
+	if !ok {
+		return p.Complete(ctx, req)
+	}
+	var content strings.Builder
+	return streamer.CompleteStream(ctx, req, func(delta string) {
+		content.WriteString(delta)
+		progress(Progress{Kind: ProgressDelta, Provider: p.Name(), Model: p.Model(), Attempt: n, Content: content.String()})
+	})
+}
+
+// Health reports breaker state and rolling stats for every provider in the
+// chain, in fallback order.
+func (c *Client) Health() []ProviderHealth {
+	now := time.Now()
+	providers := c.Providers()
+	health := make([]ProviderHealth, 0, len(providers))
+	for _, p := range providers {
+		h := c.breaker(p.Name()).snapshot(now)
+		h.Name = p.Name()
+		h.Model = p.Model()
+		h.Vision = p.SupportsVision()
+		health = append(health, h)
+	}
+	return health
+}
+
+// Available reports whether at least one provider's circuit is not open.
+func (c *Client) Available() bool {
+	for _, h := range c.Health() {
+		if h.State != BreakerOpen {
+			return true
+		}
+	}
+	return false
+}
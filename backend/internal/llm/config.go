@@ -0,0 +1,68 @@
+package llm
+
+import (
+	"strings"
+
+	"github.com/ahmetcoskunkizilkaya/mewify/backend/internal/config"
+)
+
+// NewClientFromConfig registers every provider that has credentials and
+// orders them by LLM_PROVIDERS.
+func NewClientFromConfig(cfg *config.Config) *Client {
+	registry := NewRegistry()
+	vision := visionModels(cfg.LLMVisionModels)
+
+	if key := strings.TrimSpace(cfg.GLMAPIKey); key != "" {
+		model := strings.TrimSpace(cfg.GLMModel)
+		registry.Register(NewOpenAICompatible("glm", strings.TrimSpace(cfg.GLMAPIURL), key, model, vision[strings.ToLower(model)]))
+	}
+	if key := strings.TrimSpace(cfg.DeepSeekAPIKey); key != "" {
+		model := strings.TrimSpace(cfg.DeepSeekModel)
+		registry.Register(NewOpenAICompatible("deepseek", strings.TrimSpace(cfg.DeepSeekAPIURL), key, model, vision[strings.ToLower(model)]))
+	}
+	if key := strings.TrimSpace(cfg.OpenAIAPIKey); key != "" {
+		model := strings.TrimSpace(cfg.OpenAIModel)
+		registry.Register(NewOpenAI(strings.TrimSpace(cfg.OpenAIAPIURL), key, model, vision[strings.ToLower(model)]))
+	}
+	if key := strings.TrimSpace(cfg.AnthropicAPIKey); key != "" {
+		model := strings.TrimSpace(cfg.AnthropicModel)
+		registry.Register(NewAnthropicCompatible(strings.TrimSpace(cfg.AnthropicAPIURL), key, model, vision[strings.ToLower(model)]))
+	}
+	// Ollama needs no key, so it only joins when named in the chain.
+	chain := splitList(cfg.LLMProviders)
+	for _, name := range chain {
+		if name == "ollama" {
+			model := strings.TrimSpace(cfg.OllamaModel)
+			registry.Register(NewOllama(strings.TrimSpace(cfg.OllamaURL), model, vision[strings.ToLower(model)]))
+		}
+	}
+
+	return NewClient(registry, chain, Options{
+		Timeout:    cfg.LLMTimeout,
+		MaxRetries: cfg.LLMMaxRetries,
+		Breaker: BreakerOptions{
+			Window:       cfg.LLMBreakerWindow,
+			MinRequests:  cfg.LLMBreakerMinRequests,
+			FailureRate:  cfg.LLMBreakerFailureRate,
+			OpenDuration: cfg.LLMBreakerOpenDuration,
+		},
+	})
+}
+
+func visionModels(list string) map[string]bool {
+	models := make(map[string]bool)
+	for _, m := range splitList(list) {
+		models[m] = true
+	}
+	return models
+}
+
+func splitList(list string) []string {
+	var out []string
+	for _, item := range strings.Split(list, ",") {
+		if item = strings.ToLower(strings.TrimSpace(item)); item != "" {
+			out = append(out, item)
+		}
+	}
+	return out
+}
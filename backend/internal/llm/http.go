@@ -0,0 +1,147 @@
+package llm
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+)
+
+const maxErrorBody = 512
+
+// StatusError is returned when a provider answers with a non-2xx status.
+type StatusError struct {
+	StatusCode int
+	Body       string
+}
+
+func (e *StatusError) Error() string {
+	return fmt.Sprintf("status=%d body=%s", e.StatusCode, e.Body)
+}
+
+// retryable reports whether a failed attempt is worth repeating against the
+// same provider: rate limits, server errors and transport failures. A
+// timeout is not: the attempt used the provider's whole budget, so the
+// request moves on to the next provider instead.
+func retryable(err error) bool {
+	if errors.Is(err, context.DeadlineExceeded) {
+		return false
+	}
+	var statusErr *StatusError
+	if errors.As(err, &statusErr) {
+		return statusErr.StatusCode == http.StatusTooManyRequests || statusErr.StatusCode >= 500
+	}
+	var netErr net.Error
+	if errors.As(err, &netErr) {
+		return !netErr.Timeout()
+	}
+	return errors.Is(err, io.ErrUnexpectedEOF)
+}
+
+func postJSON(ctx context.Context, client *http.Client, url string, headers map[string]string, body, out interface{}) error {
+	payload, err := json.Marshal(body)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(payload))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for k, v := range headers {
+		req.Header.Set(k, v)
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	respBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		msg := string(respBody)
+		if len(msg) > maxErrorBody {
+			msg = msg[:maxErrorBody]
+		}
+		return &StatusError{StatusCode: resp.StatusCode, Body: msg}
+	}
+
+	return json.Unmarshal(respBody, out)
+}
+
+// postStream posts body and hands the data of every server-sent event to
+// onEvent until the stream ends or sends [DONE].
+func postStream(ctx context.Context, client *http.Client, url string, headers map[string]string, body interface{}, onEvent func([]byte) error) error {
+	payload, err := json.Marshal(body)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(payload))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Accept", "text/event-stream")
+	for k, v := range headers {
+		req.Header.Set(k, v)
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, maxErrorBody))
+		return &StatusError{StatusCode: resp.StatusCode, Body: string(msg)}
+	}
+
+	scanner := bufio.NewScanner(resp.Body)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		data, ok := bytes.CutPrefix(scanner.Bytes(), []byte("data:"))
+		if !ok {
+			continue
+		}
+		data = bytes.TrimSpace(data)
+		if string(data) == "[DONE]" {
+			return nil
+		}
+		if err := onEvent(data); err != nil {
+			return err
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return err
+	}
+	// The stream closed without [DONE]; treat a cut-off reply as a transport failure
+	return io.ErrUnexpectedEOF
+}
+
+// splitDataURL breaks "data:image/png;base64,AAAA" into its media type and
+// base64 payload.
+func splitDataURL(dataURL string) (mediaType, data string, err error) {
+	rest, ok := strings.CutPrefix(dataURL, "data:")
+	if !ok {
+		return "", "", errors.New("image is not a data URL")
+	}
+	meta, data, ok := strings.Cut(rest, ",")
+	if !ok || !strings.HasSuffix(meta, ";base64") {
+		return "", "", errors.New("image data URL must be base64 encoded")
+	}
+	return strings.TrimSuffix(meta, ";base64"), data, nil
+}
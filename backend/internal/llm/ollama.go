@@ -0,0 +1,104 @@
+package llm
+
+import (
+	"context"
+	"net/http"
+	"strings"
+)
+
+const ollamaDefaultURL = "http://localhost:11434"
+
+// Ollama talks to a local Ollama server's /api/chat endpoint.
+type Ollama struct {
+	url    string
+	model  string
+	vision bool
+	client *http.Client
+}
+
+// NewOllama returns a provider for the Ollama server at baseURL. An empty
+// baseURL uses the default local address.
+func NewOllama(baseURL, model string, vision bool) *Ollama {
+	if strings.TrimSpace(baseURL) == "" {
+		baseURL = ollamaDefaultURL
+	}
+	return &Ollama{
+		url:    strings.TrimRight(baseURL, "/") + "/api/chat",
+		model:  model,
+		vision: vision,
+		client: &http.Client{},
+	}
+}
+
+func (p *Ollama) Name() string         { return "ollama" }
+func (p *Ollama) Model() string        { return p.model }
+func (p *Ollama) SupportsVision() bool { return p.vision }
+
+type ollamaRequest struct {
+	Model    string          `json:"model"`
+	Messages []ollamaMessage `json:"messages"`
+	Stream   bool            `json:"stream"`
+	Format   string          `json:"format,omitempty"`
+	Options  map[string]any  `json:"options,omitempty"`
+}
+
+type ollamaMessage struct {
+	Role    string   `json:"role"`
+	Content string   `json:"content"`
+	Images  []string `json:"images,omitempty"`
+}
+
+type ollamaResponse struct {
+	Message struct {
+		Content string `json:"content"`
+	} `json:"message"`
+	PromptEvalCount int `json:"prompt_eval_count"`
+	EvalCount       int `json:"eval_count"`
+}
+
+func (p *Ollama) Complete(ctx context.Context, req Request) (*Response, error) {
+	body := ollamaRequest{
+		Model:   p.model,
+		Stream:  false,
+		Options: map[string]any{"temperature": req.Temperature},
+	}
+	if req.MaxTokens > 0 {
+		body.Options["num_predict"] = req.MaxTokens
+	}
+	if req.JSON {
+		body.Format = "json"
+	}
+	if req.System != "" {
+		body.Messages = append(body.Messages, ollamaMessage{Role: "system", Content: req.System})
+	}
+	for _, m := range req.Messages {
+		msg := ollamaMessage{Role: m.Role, Content: m.Text}
+		// Ollama takes bare base64 rather than data URLs
+		for _, img := range m.Images {
+			_, data, err := splitDataURL(img)
+			if err != nil {
+				return nil, err
+			}
+			msg.Images = append(msg.Images, data)
+		}
+		body.Messages = append(body.Messages, msg)
+	}
+
+	var out ollamaResponse
+	if err := postJSON(ctx, p.client, p.url, nil, body, &out); err != nil {
+		return nil, err
+	}
+	if strings.TrimSpace(out.Message.Content) == "" {
+		return nil, ErrEmptyCompletion
+	}
+
+	return &Response{
+		Content:  out.Message.Content,
+		Provider: p.Name(),
+		Model:    p.model,
+		Usage: Usage{
+			PromptTokens:     out.PromptEvalCount,
+			CompletionTokens: out.EvalCount,
+		},
+	}, nil
+}
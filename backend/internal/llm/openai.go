@@ -0,0 +1,194 @@
+package llm
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strings"
+)
+
+const openAIChatURL = "https://api.openai.com/v1/chat/completions"
+
+// OpenAICompatible talks to any /chat/completions endpoint that follows the
+// OpenAI wire format (GLM, DeepSeek, OpenAI itself, most gateways).
+type OpenAICompatible struct {
+	name   string
+	url    string
+	apiKey string
+	model  string
+	vision bool
+	client *http.Client
+}
+
+func NewOpenAICompatible(name, url, apiKey, model string, vision bool) *OpenAICompatible {
+	return &OpenAICompatible{
+		name:   name,
+		url:    url,
+		apiKey: apiKey,
+		model:  model,
+		vision: vision,
+		client: &http.Client{},
+	}
+}
+
+// NewOpenAI returns the official OpenAI endpoint. An empty url uses the
+// public API.
+func NewOpenAI(url, apiKey, model string, vision bool) *OpenAICompatible {
+	if strings.TrimSpace(url) == "" {
+		url = openAIChatURL
+	}
+	return NewOpenAICompatible("openai", url, apiKey, model, vision)
+}
+
+func (p *OpenAICompatible) Name() string         { return p.name }
+func (p *OpenAICompatible) Model() string        { return p.model }
+func (p *OpenAICompatible) SupportsVision() bool { return p.vision }
+
+type openAIChatRequest struct {
+	Model          string              `json:"model"`
+	Messages       []openAIChatMessage `json:"messages"`
+	MaxTokens      int                 `json:"max_tokens,omitempty"`
+	Temperature    float64             `json:"temperature,omitempty"`
+	ResponseFormat interface{}         `json:"response_format,omitempty"`
+	Stream         bool                `json:"stream,omitempty"`
+	StreamOptions  interface{}         `json:"stream_options,omitempty"`
+}
+
+type openAIChatMessage struct {
+	Role    string      `json:"role"`
+	Content interface{} `json:"content"`
+}
+
+type openAIContentPart struct {
+	Type     string          `json:"type"`
+	Text     string          `json:"text,omitempty"`
+	ImageURL *openAIImageURL `json:"image_url,omitempty"`
+}
+
+type openAIImageURL struct {
+	URL    string `json:"url"`
+	Detail string `json:"detail,omitempty"`
+}
+
+type openAIChatResponse struct {
+	Choices []struct {
+		Message struct {
+			Content string `json:"content"`
+		} `json:"message"`
+	} `json:"choices"`
+	Usage struct {
+		PromptTokens     int `json:"prompt_tokens"`
+		CompletionTokens int `json:"completion_tokens"`
+	} `json:"usage"`
+}
+
+// openAIStreamChunk is one server-sent event of a streamed completion. The
+// final chunk carries usage when the server honours include_usage.
+type openAIStreamChunk struct {
+	Choices []struct {
+		Delta struct {
+			Content string `json:"content"`
+		} `json:"delta"`
+	} `json:"choices"`
+	Usage *struct {
+		PromptTokens     int `json:"prompt_tokens"`
+		CompletionTokens int `json:"completion_tokens"`
+	} `json:"usage"`
+}
+
+func (p *OpenAICompatible) Complete(ctx context.Context, req Request) (*Response, error) {
+	var out openAIChatResponse
+	if err := postJSON(ctx, p.client, p.url, p.headers(), p.body(req), &out); err != nil {
+		return nil, err
+	}
+	if len(out.Choices) == 0 || strings.TrimSpace(out.Choices[0].Message.Content) == "" {
+		return nil, ErrEmptyCompletion
+	}
+
+	return &Response{
+		Content:  out.Choices[0].Message.Content,
+		Provider: p.name,
+		Model:    p.model,
+		Usage: Usage{
+			PromptTokens:     out.Usage.PromptTokens,
+			CompletionTokens: out.Usage.CompletionTokens,
+		},
+	}, nil
+}
+
+// CompleteStream streams the completion, calling onDelta with each piece of
+// content as it arrives.
+func (p *OpenAICompatible) CompleteStream(ctx context.Context, req Request, onDelta func(string)) (*Response, error) {
+	body := p.body(req)
+	body.Stream = true
+	body.StreamOptions = map[string]bool{"include_usage": true}
+
+	var content strings.Builder
+	var usage Usage
+	err := postStream(ctx, p.client, p.url, p.headers(), body, func(data []byte) error {
+		var chunk openAIStreamChunk
+		if err := json.Unmarshal(data, &chunk); err != nil {
+			return err
+		}
+		if chunk.Usage != nil {
+			usage = Usage{PromptTokens: chunk.Usage.PromptTokens, CompletionTokens: chunk.Usage.CompletionTokens}
+		}
+		if len(chunk.Choices) > 0 && chunk.Choices[0].Delta.Content != "" {
+			content.WriteString(chunk.Choices[0].Delta.Content)
+			onDelta(chunk.Choices[0].Delta.Content)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	if strings.TrimSpace(content.String()) == "" {
+		return nil, ErrEmptyCompletion
+	}
+
+	return &Response{
+		Content:  content.String(),
+		Provider: p.name,
+		Model:    p.model,
+		Usage:    usage,
+	}, nil
+}
+
+func (p *OpenAICompatible) headers() map[string]string {
+	return map[string]string{"Authorization": "Bearer " + p.apiKey}
+}
+
+func (p *OpenAICompatible) body(req Request) openAIChatRequest {
+	body := openAIChatRequest{
+		Model:       p.model,
+		MaxTokens:   req.MaxTokens,
+		Temperature: req.Temperature,
+	}
+	if req.JSON {
+		body.ResponseFormat = map[string]string{"type": "json_object"}
+	}
+	if req.System != "" {
+		body.Messages = append(body.Messages, openAIChatMessage{Role: "system", Content: req.System})
+	}
+	for _, m := range req.Messages {
+		body.Messages = append(body.Messages, openAIChatMessage{Role: m.Role, Content: openAIContent(m)})
+	}
+	return body
+}
+
+// openAIContent keeps text-only messages as plain strings, which every
+// compatible server accepts, and switches to content parts for images.
+func openAIContent(m Message) interface{} {
+	if len(m.Images) == 0 {
+		return m.Text
+	}
+
+	parts := []openAIContentPart{{Type: "text", Text: m.Text}}
+	for _, img := range m.Images {
+		parts = append(parts, openAIContentPart{
+			Type:     "image_url",
+			ImageURL: &openAIImageURL{URL: img, Detail: "high"},
+		})
+	}
+	return parts
+}
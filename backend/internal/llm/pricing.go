@@ -0,0 +1,53 @@
+package llm
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Price is what a model costs in USD per million tokens.
+type Price struct {
+	Prompt     float64
+	Completion float64
+}
+
+// PriceTable maps lower-cased model names to their price.
+type PriceTable map[string]Price
+
+// ParsePrices reads "model=prompt:completion" entries separated by commas,
+// e.g. "gpt-4o-mini=0.15:0.6".
+func ParsePrices(s string) (PriceTable, error) {
+	table := make(PriceTable)
+	for _, entry := range strings.Split(s, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		model, rates, ok := strings.Cut(entry, "=")
+		prompt, completion, ok2 := strings.Cut(rates, ":")
+		if !ok || !ok2 {
+			return nil, fmt.Errorf("invalid price %q: want model=prompt:completion", entry)
+		}
+		p, err := strconv.ParseFloat(strings.TrimSpace(prompt), 64)
+		if err != nil || p < 0 {
+			return nil, fmt.Errorf("invalid prompt price in %q", entry)
+		}
+		c, err := strconv.ParseFloat(strings.TrimSpace(completion), 64)
+		if err != nil || c < 0 {
+			return nil, fmt.Errorf("invalid completion price in %q", entry)
+		}
+		table[strings.ToLower(strings.TrimSpace(model))] = Price{Prompt: p, Completion: c}
+	}
+	return table, nil
+}
+
+// Cost estimates the USD cost of usage on model, and reports whether the
+// model has a price at all.
+func (t PriceTable) Cost(model string, usage Usage) (float64, bool) {
+	price, ok := t[strings.ToLower(model)]
+	if !ok {
+		return 0, false
+	}
+	return (float64(usage.PromptTokens)*price.Prompt + float64(usage.CompletionTokens)*price.Completion) / 1e6, true
+}
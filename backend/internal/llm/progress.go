@@ -0,0 +1,44 @@
+package llm
+
+import "context"
+
+// Progress kinds reported while a request runs
+const (
+	// ProgressAttempt: a provider is about to be called
+	ProgressAttempt = "attempt"
+	// ProgressDelta: a streaming provider produced more of its reply
+	ProgressDelta = "delta"
+	// ProgressRepair: the reply failed its schema and is being repaired
+	ProgressRepair = "repair"
+)
+
+// Progress is one step of a request, reported to the function attached to
+// its context with WithProgress.
+type Progress struct {
+	Kind     string
+	Provider string
+	Model    string
+	// Attempt counts calls to Provider within this request, from 1
+	Attempt int
+	// Content is the reply so far, on delta events
+	Content string
+}
+
+// Streamer is implemented by providers that can stream a completion as it
+// is generated. The client streams only when someone listens for progress.
+type Streamer interface {
+	CompleteStream(ctx context.Context, req Request, onDelta func(string)) (*Response, error)
+}
+
+type progressKey struct{}
+
+// WithProgress returns a context whose requests report their progress to
+// fn. fn runs on the requesting goroutine and must not block.
+func WithProgress(ctx context.Context, fn func(Progress)) context.Context {
+	return context.WithValue(ctx, progressKey{}, fn)
+}
+
+func progressFrom(ctx context.Context) func(Progress) {
+	fn, _ := ctx.Value(progressKey{}).(func(Progress))
+	return fn
+}
@@ -0,0 +1,68 @@
+package llm
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+var (
+	ErrNoProvider         = errors.New("no llm provider configured")
+	ErrVisionUnsupported  = errors.New("model does not support image input")
+	ErrEmptyCompletion    = errors.New("empty llm completion")
+	ErrAllProvidersFailed = errors.New("all llm providers failed")
+)
+
+// Provider is a single chat-completion backend.
+type Provider interface {
+	// Name identifies the provider in the registry and in logs, e.g. "glm".
+	Name() string
+	Model() string
+	SupportsVision() bool
+	Complete(ctx context.Context, req Request) (*Response, error)
+}
+
+// Message is one chat turn. Images are data URLs
+// ("data:image/jpeg;base64,...") attached after the text.
+type Message struct {
+	Role   string
+	Text   string
+	Images []string
+}
+
+type Request struct {
+	System      string
+	Messages    []Message
+	MaxTokens   int
+	Temperature float64
+	// JSON asks the provider to return a single JSON value, using its native
+	// JSON mode where one exists.
+	JSON bool
+}
+
+// HasImages reports whether any message carries an image.
+func (r Request) HasImages() bool {
+	for _, m := range r.Messages {
+		if len(m.Images) > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+type Usage struct {
+	PromptTokens     int
+	CompletionTokens int
+}
+
+func (u Usage) TotalTokens() int {
+	return u.PromptTokens + u.CompletionTokens
+}
+
+type Response struct {
+	Content  string
+	Provider string
+	Model    string
+	Usage    Usage
+	Latency  time.Duration
+}
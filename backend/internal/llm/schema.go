@@ -0,0 +1,312 @@
+package llm
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// JSON Schema types understood by Schema
+const (
+	TypeObject  = "object"
+	TypeArray   = "array"
+	TypeString  = "string"
+	TypeNumber  = "number"
+	TypeInteger = "integer"
+)
+
+var ErrInvalidJSON = errors.New("reply is not a single JSON value")
+
+// Schema is the subset of JSON Schema used to pin down structured LLM
+// output. It marshals to standard JSON Schema so it can be shown to the
+// model verbatim.
+type Schema struct {
+	// Title names the schema in metrics and logs
+	Title                string             `json:"title,omitempty"`
+	Type                 string             `json:"type"`
+	Properties           map[string]*Schema `json:"properties,omitempty"`
+	Required             []string           `json:"required,omitempty"`
+	AdditionalProperties *bool              `json:"additionalProperties,omitempty"`
+	Items                *Schema            `json:"items,omitempty"`
+	MinItems             *int               `json:"minItems,omitempty"`
+	MaxItems             *int               `json:"maxItems,omitempty"`
+	UniqueItems          bool               `json:"uniqueItems,omitempty"`
+	Enum                 []string           `json:"enum,omitempty"`
+	MinLength            *int               `json:"minLength,omitempty"`
+	Minimum              *float64           `json:"minimum,omitempty"`
+	Maximum              *float64           `json:"maximum,omitempty"`
+}
+
+// SchemaError is one violation, located by a path such as
+// "recommendations[2].priority". The root is the empty path.
+type SchemaError struct {
+	Path    string `json:"path"`
+	Message string `json:"message"`
+
+	// size marks violations of an array's length or uniqueness, which
+	// callers normalize instead of discarding the array
+	size bool
+}
+
+func (e SchemaError) Error() string {
+	if e.Path == "" {
+		return "(root): " + e.Message
+	}
+	return e.Path + ": " + e.Message
+}
+
+// Float and Int build the optional bounds of a Schema.
+func Float(v float64) *float64 { return &v }
+func Int(v int) *int           { return &v }
+
+// Closed is the AdditionalProperties value that forbids unknown keys.
+var Closed = func() *bool { b := false; return &b }()
+
+// String renders the schema as compact JSON.
+func (s *Schema) String() string {
+	data, _ := json.Marshal(s)
+	return string(data)
+}
+
+// DecodeJSON parses a reply that must be exactly one JSON value. Only
+// surrounding whitespace and a markdown code fence are tolerated; prose
+// around the value is an error rather than something to cut away.
+func DecodeJSON(content string) (interface{}, error) {
+	content = strings.TrimSpace(content)
+	if strings.HasPrefix(content, "```") && strings.HasSuffix(content, "```") && len(content) >= 6 {
+		content = strings.TrimSuffix(content, "```")
+		content = strings.TrimPrefix(content, "```")
+		content = strings.TrimPrefix(content, "json")
+	}
+
+	dec := json.NewDecoder(strings.NewReader(content))
+	dec.UseNumber()
+	var value interface{}
+	if err := dec.Decode(&value); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidJSON, err)
+	}
+	if dec.More() {
+		return nil, fmt.Errorf("%w: unexpected data after the value", ErrInvalidJSON)
+	}
+	return value, nil
+}
+
+// Validate checks a value produced by DecodeJSON and returns every
+// violation in a stable order.
+func (s *Schema) Validate(value interface{}) []SchemaError {
+	var errs []SchemaError
+	s.validate(value, "", &errs)
+	return errs
+}
+
+func (s *Schema) validate(value interface{}, path string, errs *[]SchemaError) {
+	fail := func(format string, args ...interface{}) {
+		*errs = append(*errs, SchemaError{Path: path, Message: fmt.Sprintf(format, args...)})
+	}
+
+	switch s.Type {
+	case TypeObject:
+		obj, ok := value.(map[string]interface{})
+		if !ok {
+			fail("must be an object, got %s", jsonType(value))
+			return
+		}
+		for _, name := range s.Required {
+			if _, ok := obj[name]; !ok {
+				*errs = append(*errs, SchemaError{Path: join(path, name), Message: "is required"})
+			}
+		}
+		keys := make([]string, 0, len(obj))
+		for k := range obj {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			prop, ok := s.Properties[k]
+			if !ok {
+				if s.AdditionalProperties != nil && !*s.AdditionalProperties {
+					*errs = append(*errs, SchemaError{Path: join(path, k), Message: "is not allowed"})
+				}
+				continue
+			}
+			prop.validate(obj[k], join(path, k), errs)
+		}
+
+	case TypeArray:
+		arr, ok := value.([]interface{})
+		if !ok {
+			fail("must be an array, got %s", jsonType(value))
+			return
+		}
+		if s.MinItems != nil && len(arr) < *s.MinItems {
+			*errs = append(*errs, SchemaError{Path: path, Message: fmt.Sprintf("must have at least %d items, got %d", *s.MinItems, len(arr)), size: true})
+		}
+		if s.MaxItems != nil && len(arr) > *s.MaxItems {
+			*errs = append(*errs, SchemaError{Path: path, Message: fmt.Sprintf("must have at most %d items, got %d", *s.MaxItems, len(arr)), size: true})
+		}
+		if s.UniqueItems && hasDuplicates(arr) {
+			*errs = append(*errs, SchemaError{Path: path, Message: "must not repeat items", size: true})
+		}
+		if s.Items != nil {
+			for i, item := range arr {
+				s.Items.validate(item, path+"["+strconv.Itoa(i)+"]", errs)
+			}
+		}
+
+	case TypeString:
+		str, ok := value.(string)
+		if !ok {
+			fail("must be a string, got %s", jsonType(value))
+			return
+		}
+		if s.MinLength != nil && len(strings.TrimSpace(str)) < *s.MinLength {
+			fail("must have at least %d characters", *s.MinLength)
+		}
+		if len(s.Enum) > 0 && !contains(s.Enum, str) {
+			fail("must be one of %s, got %q", strings.Join(s.Enum, ", "), str)
+		}
+
+	case TypeNumber, TypeInteger:
+		num, ok := value.(json.Number)
+		if !ok {
+			fail("must be a %s, got %s", s.Type, jsonType(value))
+			return
+		}
+		f, err := num.Float64()
+		if err != nil {
+			fail("must be a %s, got %s", s.Type, num)
+			return
+		}
+		if s.Type == TypeInteger && f != math.Trunc(f) {
+			fail("must be an integer, got %s", num)
+			return
+		}
+		if s.Minimum != nil && f < *s.Minimum {
+			fail("must be at least %g, got %s", *s.Minimum, num)
+		}
+		if s.Maximum != nil && f > *s.Maximum {
+			fail("must be at most %g, got %s", *s.Maximum, num)
+		}
+	}
+}
+
+// Prune removes every value a violation points at, so the rest decodes
+// cleanly into a Go struct and the caller's defaults fill the gaps. Length
+// and uniqueness violations keep their arrays.
+func Prune(value interface{}, errs []SchemaError) interface{} {
+	for _, e := range errs {
+		if e.size {
+			continue
+		}
+		value = prune(value, splitPath(e.Path))
+	}
+	return value
+}
+
+func prune(value interface{}, path []string) interface{} {
+	if len(path) == 0 {
+		return nil
+	}
+	switch v := value.(type) {
+	case map[string]interface{}:
+		if len(path) == 1 {
+			delete(v, path[0])
+		} else if child, ok := v[path[0]]; ok {
+			v[path[0]] = prune(child, path[1:])
+		}
+	case []interface{}:
+		i, err := strconv.Atoi(path[0])
+		if err != nil || i < 0 || i >= len(v) {
+			return v
+		}
+		// Elements are nulled rather than removed so later indexes stay valid
+		if len(path) == 1 {
+			v[i] = nil
+		} else {
+			v[i] = prune(v[i], path[1:])
+		}
+	}
+	return value
+}
+
+// FieldPath collapses array indexes, "recommendations[2].priority" to
+// "recommendations[].priority", so metrics aggregate across items.
+func FieldPath(path string) string {
+	var b strings.Builder
+	skip := false
+	for _, r := range path {
+		switch {
+		case r == '[':
+			skip = true
+			b.WriteString("[]")
+		case r == ']':
+			skip = false
+		case !skip:
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
+
+func splitPath(path string) []string {
+	if path == "" {
+		return nil
+	}
+	path = strings.ReplaceAll(path, "[", ".")
+	path = strings.ReplaceAll(path, "]", "")
+	return strings.Split(path, ".")
+}
+
+func join(path, key string) string {
+	if path == "" {
+		return key
+	}
+	return path + "." + key
+}
+
+func jsonType(value interface{}) string {
+	switch value.(type) {
+	case nil:
+		return "null"
+	case map[string]interface{}:
+		return "object"
+	case []interface{}:
+		return "array"
+	case string:
+		return "string"
+	case json.Number:
+		return "number"
+	case bool:
+		return "boolean"
+	}
+	return fmt.Sprintf("%T", value)
+}
+
+func hasDuplicates(arr []interface{}) bool {
+	seen := make(map[string]struct{}, len(arr))
+	for _, item := range arr {
+		key, _ := json.Marshal(item)
+		if s, ok := item.(string); ok {
+			key = bytes.ToLower([]byte(strings.TrimSpace(s)))
+		}
+		if _, ok := seen[string(key)]; ok {
+			return true
+		}
+		seen[string(key)] = struct{}{}
+	}
+	return false
+}
+
+func contains(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,235 @@
+package llm
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// StructuredResponse is a reply decoded and checked against a schema.
+type StructuredResponse struct {
+	*Response
+	// Value is the decoded reply, after the repair round-trip if one ran
+	Value interface{}
+	// Repaired lists the fields the first reply got wrong and the repair
+	// fixed; "(root)" means the first reply was not valid JSON at all
+	Repaired []string
+	// Errors are the violations left after the repair. Decode drops the
+	// offending values, so the caller's defaults take their place.
+	Errors []SchemaError
+}
+
+// Defaulted lists the fields left invalid after the repair.
+func (r *StructuredResponse) Defaulted() []string {
+	return fieldPaths(r.Errors)
+}
+
+// Decode unmarshals the reply into dst without the values that failed
+// validation.
+func (r *StructuredResponse) Decode(dst interface{}) error {
+	data, err := json.Marshal(Prune(r.Value, r.Errors))
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, dst)
+}
+
+// CompleteStructured asks for JSON matching schema. A reply that does not
+// parse or validate is sent back once, with the violations, for the model to
+// correct. Violations still left after that are returned on the response
+// rather than failing it, unless the reply is unusable as a whole: not JSON,
+// or the wrong type at the root. Token usage covers both round-trips, and
+// the response is returned with such an error whenever a provider answered,
+// so the caller can still account for it.
+func (c *Client) CompleteStructured(ctx context.Context, req Request, schema *Schema) (*StructuredResponse, error) {
+	req.JSON = true
+	resp, err := c.Complete(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+
+	value, errs := decodeAndValidate(resp.Content, schema)
+	if len(errs) == 0 {
+		out := &StructuredResponse{Response: resp, Value: value}
+		c.output.record(schema.Title, out, false)
+		return out, nil
+	}
+
+	if progress := progressFrom(ctx); progress != nil {
+		progress(Progress{Kind: ProgressRepair, Provider: resp.Provider, Model: resp.Model})
+	}
+	repairReq := req
+	repairReq.Messages = append(append([]Message(nil), req.Messages...),
+		Message{Role: "assistant", Text: resp.Content},
+		Message{Role: "user", Text: repairPrompt(schema, errs)},
+	)
+	repaired, err := c.Complete(ctx, repairReq)
+	if err != nil {
+		c.output.failed(schema.Title)
+		return &StructuredResponse{Response: resp, Errors: errs}, fmt.Errorf("%s: repair failed: %w", resp.Provider, err)
+	}
+	repaired.Usage.PromptTokens += resp.Usage.PromptTokens
+	repaired.Usage.CompletionTokens += resp.Usage.CompletionTokens
+	repaired.Latency += resp.Latency
+
+	value, remaining := decodeAndValidate(repaired.Content, schema)
+	if fatal(remaining) {
+		c.output.failed(schema.Title)
+		return &StructuredResponse{Response: repaired, Errors: remaining},
+			fmt.Errorf("%s: invalid output after repair: %s", repaired.Provider, remaining[0].Error())
+	}
+
+	out := &StructuredResponse{
+		Response: repaired,
+		Value:    value,
+		Repaired: fixed(errs, remaining),
+		Errors:   remaining,
+	}
+	c.output.record(schema.Title, out, true)
+	log.Printf("llm structured output: schema=%s provider=%s repaired=%v defaulted=%v",
+		schema.Title, repaired.Provider, out.Repaired, out.Defaulted())
+	return out, nil
+}
+
+// OutputStats reports, per schema, how often replies needed repair and
+// which fields were repaired or defaulted.
+func (c *Client) OutputStats() []SchemaStats {
+	return c.output.snapshot()
+}
+
+func decodeAndValidate(content string, schema *Schema) (interface{}, []SchemaError) {
+	value, err := DecodeJSON(content)
+	if err != nil {
+		return nil, []SchemaError{{Message: err.Error()}}
+	}
+	return value, schema.Validate(value)
+}
+
+// fatal reports a violation at the root, which leaves nothing to keep.
+func fatal(errs []SchemaError) bool {
+	for _, e := range errs {
+		if e.Path == "" && !e.size {
+			return true
+		}
+	}
+	return false
+}
+
+func repairPrompt(schema *Schema, errs []SchemaError) string {
+	var b strings.Builder
+	b.WriteString("Your previous reply did not match the required JSON schema:\n")
+	for _, e := range errs {
+		b.WriteString("- " + e.Error() + "\n")
+	}
+	b.WriteString("Schema: " + schema.String() + "\n")
+	b.WriteString("Return ONLY the corrected JSON, no markdown formatting, no extra text.")
+	return b.String()
+}
+
+// fixed lists the fields that failed before the repair and passed after.
+func fixed(before, after []SchemaError) []string {
+	still := make(map[string]bool, len(after))
+	for _, e := range after {
+		still[FieldPath(e.Path)] = true
+	}
+	var out []string
+	for _, field := range fieldPaths(before) {
+		if !still[field] {
+			out = append(out, field)
+		}
+	}
+	return out
+}
+
+func fieldPaths(errs []SchemaError) []string {
+	seen := make(map[string]bool, len(errs))
+	var out []string
+	for _, e := range errs {
+		field := FieldPath(e.Path)
+		if field == "" {
+			field = "(root)"
+		}
+		if !seen[field] {
+			seen[field] = true
+			out = append(out, field)
+		}
+	}
+	sort.Strings(out)
+	return out
+}
+
+// SchemaStats counts structured replies for one schema since startup.
+type SchemaStats struct {
+	Schema         string         `json:"schema"`
+	Responses      int            `json:"responses"`
+	Repairs        int            `json:"repairs"`
+	RepairFailures int            `json:"repair_failures"`
+	Repaired       map[string]int `json:"repaired_fields"`
+	Defaulted      map[string]int `json:"defaulted_fields"`
+}
+
+type outputStats struct {
+	mu      sync.Mutex
+	schemas map[string]*SchemaStats
+}
+
+func newOutputStats() *outputStats {
+	return &outputStats{schemas: make(map[string]*SchemaStats)}
+}
+
+func (o *outputStats) get(schema string) *SchemaStats {
+	s, ok := o.schemas[schema]
+	if !ok {
+		s = &SchemaStats{Schema: schema, Repaired: map[string]int{}, Defaulted: map[string]int{}}
+		o.schemas[schema] = s
+	}
+	return s
+}
+
+func (o *outputStats) record(schema string, resp *StructuredResponse, repaired bool) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	s := o.get(schema)
+	s.Responses++
+	if repaired {
+		s.Repairs++
+	}
+	for _, field := range resp.Repaired {
+		s.Repaired[field]++
+	}
+	for _, field := range resp.Defaulted() {
+		s.Defaulted[field]++
+	}
+}
+
+func (o *outputStats) failed(schema string) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	s := o.get(schema)
+	s.Repairs++
+	s.RepairFailures++
+}
+
+func (o *outputStats) snapshot() []SchemaStats {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	out := make([]SchemaStats, 0, len(o.schemas))
+	for _, s := range o.schemas {
+		c := *s
+		c.Repaired = make(map[string]int, len(s.Repaired))
+		for k, v := range s.Repaired {
+			c.Repaired[k] = v
+		}
+		c.Defaulted = make(map[string]int, len(s.Defaulted))
+		for k, v := range s.Defaulted {
+			c.Defaulted[k] = v
+		}
+		out = append(out, c)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Schema < out[j].Schema })
+	return out
+}
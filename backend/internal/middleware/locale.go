@@ -0,0 +1,17 @@
+package middleware
+
+import (
+	"github.com/gofiber/fiber/v2"
+
+	"github.com/ahmetcoskunkizilkaya/mewify/backend/internal/i18n"
+)
+
+// Locale exposes the supported language the Accept-Language header prefers
+// as the `locale` local, or "" when it names none. Services still prefer the
+// language stored on the user's profile.
+func Locale() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		c.Locals("locale", i18n.FromAcceptLanguage(c.Get(fiber.HeaderAcceptLanguage)))
+		return c.Next()
+	}
+}
@@ -0,0 +1,21 @@
+package middleware
+
+import (
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+)
+
+// PremiumStatus resolves the caller's subscription state and exposes it to
+// handlers as the `isPremium` local. Must run after JWTProtected.
+func PremiumStatus(isPremium func(userID uuid.UUID) bool) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		premium := false
+		if sub, ok := c.Locals("userID").(string); ok {
+			if userID, err := uuid.Parse(sub); err == nil {
+				premium = isPremium(userID)
+			}
+		}
+		c.Locals("isPremium", premium)
+		return c.Next()
+	}
+}
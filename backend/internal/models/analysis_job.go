@@ -0,0 +1,55 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type AnalysisJobStatus string
+
+const (
+	AnalysisJobQueued    AnalysisJobStatus = "queued"
+	AnalysisJobRunning   AnalysisJobStatus = "running"
+	AnalysisJobSucceeded AnalysisJobStatus = "succeeded"
+	AnalysisJobFailed    AnalysisJobStatus = "failed"
+)
+
+// AnalysisJob is a queued AI face analysis. The photo, already normalized by
+// the ingestion pipeline, is kept only until the job finishes; the scan quota
+// it reserved is held until then as well, then committed or released.
+type AnalysisJob struct {
+	ID             uuid.UUID         `gorm:"type:uuid;default:gen_random_uuid();primaryKey" json:"id"`
+	UserID         uuid.UUID         `gorm:"type:uuid;not null;index;uniqueIndex:idx_analysis_job_idempotency,priority:1" json:"user_id"`
+	IdempotencyKey *string           `gorm:"size:255;uniqueIndex:idx_analysis_job_idempotency,priority:2" json:"-"`
+	Status         AnalysisJobStatus `gorm:"not null;default:'queued';size:20;index:idx_analysis_job_due,priority:1" json:"status"`
+	ImageBase64    string            `gorm:"type:text" json:"-"`
+	ImageSHA256    string            `gorm:"column:image_sha256;size:64" json:"-"`
+	ImagePHash     string            `gorm:"column:image_phash;size:16" json:"-"`
+	// ProfileImages are a multi-angle session's profile photos by angle; the
+	// front photo is ImageBase64
+	ProfileImages map[string]string `gorm:"type:jsonb;serializer:json" json:"-"`
+	// Locale is the language the analysis wording is written in, resolved
+	// when the job is submitted
+	Locale string `gorm:"size:10" json:"-"`
+	// Cached jobs reuse a recent analysis of a near-identical photo and cost
+	// no quota
+	Cached        bool      `gorm:"not null;default:false" json:"cached"`
+	UsageDate     time.Time `gorm:"type:date;not null" json:"-"`
+	UsageReserved bool      `gorm:"not null;default:false" json:"-"`
+	// UsageCharged is set when the finished scan counted against the quota
+	UsageCharged   bool       `gorm:"not null;default:false" json:"usage_charged"`
+	Attempts       int        `gorm:"not null;default:0" json:"attempts"`
+	NextAttemptAt  time.Time  `gorm:"not null;index:idx_analysis_job_due,priority:2" json:"-"`
+	LeaseExpiresAt *time.Time `json:"-"`
+	AnalysisID     *uuid.UUID `gorm:"type:uuid" json:"analysis_id,omitempty"`
+	LastError      string     `gorm:"type:text" json:"error,omitempty"`
+	StartedAt      *time.Time `json:"started_at,omitempty"`
+	CompletedAt    *time.Time `json:"completed_at,omitempty"`
+	CreatedAt      time.Time  `json:"created_at"`
+	UpdatedAt      time.Time  `json:"updated_at"`
+
+	// Relations
+	User     User          `gorm:"foreignKey:UserID" json:"-"`
+	Analysis *FaceAnalysis `gorm:"foreignKey:AnalysisID" json:"analysis,omitempty"`
+}
@@ -0,0 +1,26 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Blob owner types
+const (
+	BlobOwnerFaceAnalysis   = "face_analysis"
+	BlobOwnerMewingProgress = "mewing_progress"
+)
+
+// Blob records an object in the blob store. It belongs to one user and one
+// owning entry, and is removed from the store when either is deleted.
+type Blob struct {
+	ID          uuid.UUID `gorm:"type:uuid;default:gen_random_uuid();primaryKey" json:"id"`
+	UserID      uuid.UUID `gorm:"type:uuid;not null;index" json:"user_id"`
+	OwnerType   string    `gorm:"not null;size:50;index:idx_blob_owner,priority:1" json:"owner_type"`
+	OwnerID     uuid.UUID `gorm:"type:uuid;not null;index:idx_blob_owner,priority:2" json:"owner_id"`
+	Key         string    `gorm:"not null;size:500;uniqueIndex" json:"key"`
+	ContentType string    `gorm:"not null;size:100" json:"content_type"`
+	Size        int       `gorm:"not null" json:"size"`
+	CreatedAt   time.Time `json:"created_at"`
+}
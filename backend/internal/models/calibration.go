@@ -0,0 +1,57 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Calibration methods
+const (
+	CalibrationLinear   = "linear"
+	CalibrationQuantile = "quantile"
+)
+
+// CalibrationProviderDeterministic names the hash-based fallback wherever a
+// provider is expected.
+const CalibrationProviderDeterministic = "deterministic"
+
+// CalibrationReference is one image of the reference set every score source
+// is measured on. The set is small, so the normalized photo is kept inline.
+type CalibrationReference struct {
+	ID          uuid.UUID `gorm:"type:uuid;default:gen_random_uuid();primaryKey" json:"id"`
+	Label       string    `gorm:"size:100" json:"label"`
+	ImageBase64 string    `gorm:"type:text;not null" json:"-"`
+	ImageSHA256 string    `gorm:"column:image_sha256;size:64;uniqueIndex" json:"image_sha256"`
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+// CalibrationSample is one source's raw scores of a reference image.
+type CalibrationSample struct {
+	ID          uuid.UUID          `gorm:"type:uuid;default:gen_random_uuid();primaryKey" json:"id"`
+	ReferenceID uuid.UUID          `gorm:"type:uuid;not null;uniqueIndex:idx_calibration_sample,priority:1" json:"reference_id"`
+	Provider    string             `gorm:"size:50;not null;uniqueIndex:idx_calibration_sample,priority:2" json:"provider"`
+	Model       string             `gorm:"size:100;not null;uniqueIndex:idx_calibration_sample,priority:3" json:"model"`
+	Scores      map[string]float64 `gorm:"type:jsonb;serializer:json" json:"scores"`
+	CreatedAt   time.Time          `json:"created_at"`
+	UpdatedAt   time.Time          `json:"updated_at"`
+}
+
+// ScoreCalibration maps one source's raw score of one metric onto the common
+// scale. Linear mappings use Slope and Intercept; quantile mappings
+// interpolate between matching RawKnots and TargetKnots. Each recompute
+// writes a new Version and only the latest is applied.
+type ScoreCalibration struct {
+	ID          uuid.UUID `gorm:"type:uuid;default:gen_random_uuid();primaryKey" json:"id"`
+	Version     int       `gorm:"not null;index" json:"version"`
+	Provider    string    `gorm:"size:50;not null" json:"provider"`
+	Model       string    `gorm:"size:100;not null" json:"model"`
+	Metric      string    `gorm:"size:20;not null" json:"metric"`
+	Method      string    `gorm:"size:20;not null" json:"method"`
+	Slope       float64   `gorm:"not null;default:1" json:"slope"`
+	Intercept   float64   `gorm:"not null;default:0" json:"intercept"`
+	RawKnots    []float64 `gorm:"type:jsonb;serializer:json" json:"raw_knots,omitempty"`
+	TargetKnots []float64 `gorm:"type:jsonb;serializer:json" json:"target_knots,omitempty"`
+	Samples     int       `gorm:"not null" json:"samples"`
+	CreatedAt   time.Time `json:"created_at"`
+}
@@ -7,25 +7,65 @@ import (
 	"gorm.io/gorm"
 )
 
+// Analysis sources
+const (
+	AnalysisSourceLLM           = "llm"
+	AnalysisSourceDeterministic = "deterministic"
+	AnalysisSourceManual        = "manual"
+)
+
 type FaceAnalysis struct {
-	ID            uuid.UUID      `gorm:"type:uuid;default:gen_random_uuid();primaryKey" json:"id"`
-	UserID        uuid.UUID      `gorm:"type:uuid;not null;index" json:"user_id"`
-	ImageURL      string         `gorm:"type:text;not null" json:"image_url"`
-	OverallScore  float64        `gorm:"type:decimal(3,1);not null" json:"overall_score"`
-	SymmetryScore float64        `gorm:"type:decimal(3,1);not null" json:"symmetry_score"`
-	JawlineScore  float64        `gorm:"type:decimal(3,1);not null" json:"jawline_score"`
-	SkinScore     float64        `gorm:"type:decimal(3,1);not null" json:"skin_score"`
-	EyeScore      float64        `gorm:"type:decimal(3,1);not null" json:"eye_score"`
-	NoseScore     float64        `gorm:"type:decimal(3,1);not null" json:"nose_score"`
-	LipsScore     float64        `gorm:"type:decimal(3,1);not null" json:"lips_score"`
-	HarmonyScore  float64        `gorm:"type:decimal(3,1);not null" json:"harmony_score"`
-	Strengths     []string       `gorm:"type:jsonb;serializer:json" json:"strengths"`
-	Improvements  []string       `gorm:"type:jsonb;serializer:json" json:"improvements"`
-	AnalyzedAt    time.Time      `gorm:"not null" json:"analyzed_at"`
-	CreatedAt     time.Time      `gorm:"autoCreateTime" json:"created_at"`
-	UpdatedAt     time.Time      `gorm:"autoUpdateTime" json:"updated_at"`
-	DeletedAt     gorm.DeletedAt `gorm:"index" json:"deleted_at,omitempty"`
+	ID            uuid.UUID `gorm:"type:uuid;default:gen_random_uuid();primaryKey" json:"id"`
+	UserID        uuid.UUID `gorm:"type:uuid;not null;index;index:idx_face_analysis_user_analyzed,priority:1" json:"user_id"`
+	ImageURL      string    `gorm:"type:text;not null" json:"image_url"`
+	ImageKey      string    `gorm:"size:500" json:"-"`
+	ImageSHA256   string    `gorm:"column:image_sha256;size:64;index" json:"image_sha256,omitempty"`
+	ImagePHash    string    `gorm:"column:image_phash;size:16" json:"image_phash,omitempty"`
+	OverallScore  float64   `gorm:"type:decimal(3,1);not null" json:"overall_score"`
+	SymmetryScore float64   `gorm:"type:decimal(3,1);not null" json:"symmetry_score"`
+	JawlineScore  float64   `gorm:"type:decimal(3,1);not null" json:"jawline_score"`
+	SkinScore     float64   `gorm:"type:decimal(3,1);not null" json:"skin_score"`
+	EyeScore      float64   `gorm:"type:decimal(3,1);not null" json:"eye_score"`
+	NoseScore     float64   `gorm:"type:decimal(3,1);not null" json:"nose_score"`
+	LipsScore     float64   `gorm:"type:decimal(3,1);not null" json:"lips_score"`
+	HarmonyScore  float64   `gorm:"type:decimal(3,1);not null" json:"harmony_score"`
+	Strengths     []string  `gorm:"type:jsonb;serializer:json" json:"strengths"`
+	Improvements  []string  `gorm:"type:jsonb;serializer:json" json:"improvements"`
+	AnalyzedAt    time.Time `gorm:"not null;index:idx_face_analysis_user_analyzed,priority:2" json:"analyzed_at"`
+	// Tags are the user's own labels, lowercased, for filtering the history
+	Tags []string `gorm:"type:jsonb;serializer:json;index:idx_face_analysis_tags,type:gin" json:"tags"`
+	// RawScores are the scores as the source produced them, by metric; the
+	// score columns hold them after calibration. CalibrationVersion is the
+	// ScoreCalibration version applied, 0 when none was.
+	RawScores          map[string]float64 `gorm:"type:jsonb;serializer:json" json:"raw_scores,omitempty"`
+	CalibrationVersion int                `gorm:"not null;default:0" json:"calibration_version"`
+	// SessionID is set on the summary analysis of a multi-angle scan
+	SessionID *uuid.UUID `gorm:"type:uuid;index" json:"session_id,omitempty"`
+
+	// Provenance: who produced the scores and how
+	Source           string `gorm:"size:20;not null;default:'manual';index" json:"source"`
+	Provider         string `gorm:"size:50" json:"provider,omitempty"`
+	Model            string `gorm:"size:100" json:"model,omitempty"`
+	PromptVersion    string `gorm:"size:50" json:"prompt_version,omitempty"`
+	LatencyMs        int64  `gorm:"not null;default:0" json:"latency_ms"`
+	PromptTokens     int    `gorm:"not null;default:0" json:"prompt_tokens"`
+	CompletionTokens int    `gorm:"not null;default:0" json:"completion_tokens"`
+	FallbackError    string `gorm:"type:text" json:"fallback_error,omitempty"`
+	// Fields the model got wrong: fixed by the repair round-trip, or still
+	// invalid after it and filled with fallback values
+	RepairedFields  []string `gorm:"type:jsonb;serializer:json" json:"repaired_fields,omitempty"`
+	DefaultedFields []string `gorm:"type:jsonb;serializer:json" json:"defaulted_fields,omitempty"`
+
+	CreatedAt time.Time      `gorm:"autoCreateTime" json:"created_at"`
+	UpdatedAt time.Time      `gorm:"autoUpdateTime" json:"updated_at"`
+	DeletedAt gorm.DeletedAt `gorm:"index" json:"deleted_at,omitempty"`
 
 	// Relations
 	User User `gorm:"foreignKey:UserID" json:"-"`
 }
+
+// IsEstimate reports whether the scores were derived from the image bytes
+// rather than judged by a model or entered by hand.
+func (a *FaceAnalysis) IsEstimate() bool {
+	return a.Source == AnalysisSourceDeterministic
+}
@@ -18,6 +18,9 @@ type GlowPlan struct {
 	Difficulty     string         `gorm:"type:varchar(10);check:difficulty IN ('easy','medium','hard')"`
 	TimeframeWeeks int            `gorm:"type:integer;default:4"`
 	IsCompleted    bool           `gorm:"type:boolean;default:false"`
+	// PromptVersion is the prompt variant that wrote an AI plan; empty for
+	// the built-in fallback plan
+	PromptVersion  string         `gorm:"type:varchar(50);index"`
 	CompletedAt    *time.Time     `gorm:"type:timestamp"`
 	CreatedAt      time.Time      `gorm:"type:timestamp;default:now()"`
 	UpdatedAt      time.Time      `gorm:"type:timestamp;default:now()"`
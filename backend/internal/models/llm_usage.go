@@ -0,0 +1,23 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// LLMUsage is the token count and estimated cost of one AI feature call.
+// UserID is nil for calls made on nobody's behalf.
+type LLMUsage struct {
+	ID               uuid.UUID  `gorm:"type:uuid;default:gen_random_uuid();primaryKey" json:"id"`
+	UserID           *uuid.UUID `gorm:"type:uuid;index:idx_llm_usage_user_time,priority:1" json:"user_id,omitempty"`
+	Feature          string     `gorm:"size:50;not null" json:"feature"`
+	Provider         string     `gorm:"size:50;not null" json:"provider"`
+	Model            string     `gorm:"size:100" json:"model"`
+	PromptTokens     int        `gorm:"not null;default:0" json:"prompt_tokens"`
+	CompletionTokens int        `gorm:"not null;default:0" json:"completion_tokens"`
+	CostUSD          float64    `gorm:"column:cost_usd;type:decimal(12,6);not null;default:0" json:"cost_usd"`
+	// Priced is false when the model had no entry in the price table
+	Priced    bool      `gorm:"not null;default:true" json:"priced"`
+	CreatedAt time.Time `gorm:"index;index:idx_llm_usage_user_time,priority:2" json:"created_at"`
+}
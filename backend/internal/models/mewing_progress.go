@@ -15,6 +15,7 @@ type MewingProgress struct {
 	Completed       bool           `gorm:"not null;default:false"`
 	Notes           string         `gorm:"type:text"`
 	JawlinePhotoURL string         `gorm:"type:varchar(500)"`
+	JawlinePhotoKey string         `gorm:"type:varchar(500)"`
 	CreatedAt       time.Time
 	UpdatedAt       time.Time
 	DeletedAt       gorm.DeletedAt `gorm:"index"`
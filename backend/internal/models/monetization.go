@@ -104,10 +104,13 @@ type ChestReward struct {
 // UserChest - Kullanıcının açtığı sandıklar
 type UserChest struct {
 	ID        uuid.UUID `gorm:"type:uuid;default:gen_random_uuid();primaryKey" json:"id"`
-	UserID    uuid.UUID `gorm:"type:uuid;not null;index" json:"user_id"`
+	UserID    uuid.UUID `gorm:"type:uuid;not null;index;uniqueIndex:idx_user_chest_date,priority:1" json:"user_id"`
 
 	// Chest info
-	ChestType ChestType `gorm:"not null" json:"chest_type"`
+	ChestType ChestType `gorm:"not null;uniqueIndex:idx_user_chest_date,priority:2" json:"chest_type"`
+	// ChestDate is the UTC day of a daily chest, unique per user so it opens
+	// once a day; other chests leave it empty
+	ChestDate *time.Time `gorm:"type:date;uniqueIndex:idx_user_chest_date,priority:3" json:"chest_date,omitempty"`
 
 	// Rewards earned
 	Rewards []ChestRewardResult `gorm:"foreignKey:UserChestID" json:"rewards"`
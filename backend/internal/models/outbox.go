@@ -0,0 +1,32 @@
+package models
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type OutboxStatus string
+
+const (
+	OutboxPending   OutboxStatus = "pending"
+	OutboxDelivered OutboxStatus = "delivered"
+	OutboxDead      OutboxStatus = "dead"
+)
+
+// OutboxMessage is a side effect recorded in the same transaction as the
+// business change that caused it and delivered later by the outbox dispatcher.
+type OutboxMessage struct {
+	ID             uuid.UUID       `gorm:"type:uuid;default:gen_random_uuid();primaryKey" json:"id"`
+	Topic          string          `gorm:"not null;size:100;index" json:"topic"`
+	IdempotencyKey string          `gorm:"not null;size:255;uniqueIndex" json:"idempotency_key"`
+	Payload        json.RawMessage `gorm:"type:jsonb;not null" json:"payload"`
+	Status         OutboxStatus    `gorm:"not null;default:'pending';size:20;index:idx_outbox_due,priority:1" json:"status"`
+	Attempts       int             `gorm:"not null;default:0" json:"attempts"`
+	NextAttemptAt  time.Time       `gorm:"not null;index:idx_outbox_due,priority:2" json:"next_attempt_at"`
+	LastError      string          `gorm:"type:text" json:"last_error,omitempty"`
+	DeliveredAt    *time.Time      `json:"delivered_at,omitempty"`
+	CreatedAt      time.Time       `json:"created_at"`
+	UpdatedAt      time.Time       `json:"updated_at"`
+}
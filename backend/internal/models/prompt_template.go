@@ -0,0 +1,23 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// PromptTemplate is a prompt version managed from the database. Active rows
+// are loaded over the templates shipped with the binary, so a variant can be
+// added or re-weighted without a deploy.
+type PromptTemplate struct {
+	ID        uuid.UUID         `gorm:"type:uuid;default:gen_random_uuid();primaryKey" json:"id"`
+	Name      string            `gorm:"size:100;not null;uniqueIndex:idx_prompt_template_version,priority:1" json:"name"`
+	Version   string            `gorm:"size:50;not null;uniqueIndex:idx_prompt_template_version,priority:2" json:"version"`
+	Weight    int               `gorm:"not null;default:0" json:"weight"`
+	System    string            `gorm:"type:text" json:"system"`
+	User      string            `gorm:"type:text;not null" json:"user"`
+	Variables map[string]string `gorm:"type:jsonb;serializer:json" json:"variables"`
+	Active    bool              `gorm:"not null;default:true" json:"active"`
+	CreatedAt time.Time         `json:"created_at"`
+	UpdatedAt time.Time         `json:"updated_at"`
+}
@@ -0,0 +1,57 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// Scan angles
+const (
+	ScanAngleFront        = "front"
+	ScanAngleLeftProfile  = "left_profile"
+	ScanAngleRightProfile = "right_profile"
+)
+
+// ScanAngles lists every angle a session accepts, front first.
+var ScanAngles = []string{ScanAngleFront, ScanAngleLeftProfile, ScanAngleRightProfile}
+
+// ScanSession is a multi-angle scan. Its summary scores live on the
+// FaceAnalysis it points at, so analysis lists, stats and glow plans treat a
+// session like any single scan; the per-angle results hang off the session.
+type ScanSession struct {
+	ID         uuid.UUID `gorm:"type:uuid;default:gen_random_uuid();primaryKey" json:"id"`
+	UserID     uuid.UUID `gorm:"type:uuid;not null;index" json:"user_id"`
+	AnalysisID uuid.UUID `gorm:"type:uuid;not null;uniqueIndex" json:"analysis_id"`
+	// Profile metrics averaged over the profile shots; nil without any
+	JawAngleScore       *float64 `gorm:"type:decimal(3,1)" json:"jaw_angle_score"`
+	ChinProjectionScore *float64 `gorm:"type:decimal(3,1)" json:"chin_projection_score"`
+	NeckPostureScore    *float64 `gorm:"type:decimal(3,1)" json:"neck_posture_score"`
+
+	CreatedAt time.Time      `gorm:"autoCreateTime" json:"created_at"`
+	DeletedAt gorm.DeletedAt `gorm:"index" json:"-"`
+
+	// Relations
+	Analysis FaceAnalysis      `gorm:"foreignKey:AnalysisID" json:"-"`
+	Angles   []ScanAngleResult `gorm:"foreignKey:SessionID" json:"angles"`
+}
+
+// ScanAngleResult is the scoring of one photo of a session. Scores holds the
+// metrics of its angle: the eight face scores for the front, jaw angle, chin
+// projection and neck posture for a profile.
+type ScanAngleResult struct {
+	ID            uuid.UUID          `gorm:"type:uuid;default:gen_random_uuid();primaryKey" json:"id"`
+	SessionID     uuid.UUID          `gorm:"type:uuid;not null;index" json:"session_id"`
+	Angle         string             `gorm:"size:20;not null" json:"angle"`
+	ImageKey      string             `gorm:"size:500" json:"-"`
+	ImageSHA256   string             `gorm:"column:image_sha256;size:64" json:"-"`
+	Scores        map[string]float64 `gorm:"type:jsonb;serializer:json" json:"scores"`
+	Observations  []string           `gorm:"type:jsonb;serializer:json" json:"observations,omitempty"`
+	Source        string             `gorm:"size:20;not null" json:"source"`
+	Provider      string             `gorm:"size:50" json:"provider,omitempty"`
+	Model         string             `gorm:"size:100" json:"model,omitempty"`
+	PromptVersion string             `gorm:"size:50" json:"prompt_version,omitempty"`
+	FallbackError string             `gorm:"type:text" json:"fallback_error,omitempty"`
+	CreatedAt     time.Time          `gorm:"autoCreateTime" json:"created_at"`
+}
@@ -11,6 +11,9 @@ type DailyUsage struct {
 	UserID        uuid.UUID `gorm:"type:uuid;not null;uniqueIndex:idx_user_date" json:"user_id"`
 	Date          time.Time `gorm:"type:date;not null;uniqueIndex:idx_user_date" json:"date"`
 	AnalysisCount int       `gorm:"not null;default:0" json:"analysis_count"`
+	// ReservedCount holds scans taken by analyses still in flight; they move
+	// to AnalysisCount when delivered and are released otherwise.
+	ReservedCount int       `gorm:"not null;default:0" json:"reserved_count"`
 	CreatedAt     time.Time `json:"created_at"`
 	UpdatedAt     time.Time `json:"updated_at"`
 	User          User      `gorm:"foreignKey:UserID" json:"-"`
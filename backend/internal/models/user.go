@@ -12,6 +12,7 @@ type User struct {
 	Email     string         `gorm:"uniqueIndex;not null;size:255" json:"email"`
 	AppleSub  *string        `gorm:"uniqueIndex;size:255" json:"-"`
 	Password  string         `gorm:"not null" json:"-"`
+	Locale    string         `gorm:"size:10" json:"locale"`
 	CreatedAt time.Time      `json:"created_at"`
 	UpdatedAt time.Time      `json:"updated_at"`
 	DeletedAt gorm.DeletedAt `gorm:"index" json:"-"`
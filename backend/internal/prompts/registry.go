@@ -0,0 +1,213 @@
+package prompts
+
+import (
+	"embed"
+	"encoding/json"
+	"fmt"
+	"hash/fnv"
+	"io/fs"
+	"os"
+	"path"
+	"sort"
+	"sync"
+
+	"github.com/google/uuid"
+)
+
+// Names of the templates the services render
+const (
+	FaceAnalysis = "face-analysis"
+	FaceProfile  = "face-profile"
+	GlowPlan     = "glow-plan"
+)
+
+//go:embed builtin/*.json
+var builtin embed.FS
+
+// assignBuckets is the fixed space subjects are hashed into before it is
+// split between variants by weight.
+const assignBuckets = 10000
+
+// Registry holds every known version of each template. It is safe for
+// concurrent use, so templates can be reloaded while requests render them.
+// Templates from files are kept apart from overrides, so replacing the
+// overrides restores the file templates they shadowed.
+type Registry struct {
+	mu        sync.RWMutex
+	files     map[string]map[string]*Template
+	overrides map[string]map[string]*Template
+	templates map[string]map[string]*Template
+}
+
+func NewRegistry() *Registry {
+	return &Registry{
+		files:     make(map[string]map[string]*Template),
+		overrides: make(map[string]map[string]*Template),
+		templates: make(map[string]map[string]*Template),
+	}
+}
+
+// Default returns a registry holding the templates built into the binary.
+func Default() (*Registry, error) {
+	r := NewRegistry()
+	if err := r.LoadFS(builtin, "builtin"); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// Register compiles t and adds it, replacing any template with the same name
+// and version. An override of that name and version still takes precedence.
+func (r *Registry) Register(t Template) error {
+	if err := t.compile(); err != nil {
+		return err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	put(r.files, &t)
+	r.rebuild()
+	return nil
+}
+
+// Override replaces the whole set of overrides with templates, which take
+// precedence over file templates of the same name and version. Templates
+// that fail to compile are left out and reported; the rest still apply.
+func (r *Registry) Override(templates []Template) error {
+	overrides := make(map[string]map[string]*Template)
+	var failed []string
+	for i := range templates {
+		t := templates[i]
+		if err := t.compile(); err != nil {
+			failed = append(failed, err.Error())
+			continue
+		}
+		put(overrides, &t)
+	}
+
+	r.mu.Lock()
+	r.overrides = overrides
+	r.rebuild()
+	r.mu.Unlock()
+
+	if len(failed) > 0 {
+		return fmt.Errorf("%d prompt templates failed to load: %v", len(failed), failed)
+	}
+	return nil
+}
+
+// rebuild merges the overrides over the file templates. The caller holds
+// the write lock.
+func (r *Registry) rebuild() {
+	templates := make(map[string]map[string]*Template, len(r.files))
+	for _, layer := range []map[string]map[string]*Template{r.files, r.overrides} {
+		for _, versions := range layer {
+			for _, t := range versions {
+				put(templates, t)
+			}
+		}
+	}
+	r.templates = templates
+}
+
+func put(m map[string]map[string]*Template, t *Template) {
+	if m[t.Name] == nil {
+		m[t.Name] = make(map[string]*Template)
+	}
+	m[t.Name][t.Version] = t
+}
+
+// LoadDir registers every *.json template file in dir.
+func (r *Registry) LoadDir(dir string) error {
+	return r.LoadFS(os.DirFS(dir), ".")
+}
+
+// LoadFS registers every *.json template file in dir of fsys.
+func (r *Registry) LoadFS(fsys fs.FS, dir string) error {
+	files, err := fs.Glob(fsys, path.Join(dir, "*.json"))
+	if err != nil {
+		return err
+	}
+	for _, file := range files {
+		data, err := fs.ReadFile(fsys, file)
+		if err != nil {
+			return err
+		}
+		var t Template
+		if err := json.Unmarshal(data, &t); err != nil {
+			return fmt.Errorf("%w: %s: %v", ErrInvalidTemplate, file, err)
+		}
+		if err := r.Register(t); err != nil {
+			return fmt.Errorf("%s: %w", file, err)
+		}
+	}
+	return nil
+}
+
+// Get returns one version of a template.
+func (r *Registry) Get(name, version string) (*Template, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	t, ok := r.templates[name][version]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s/%s", ErrTemplateNotFound, name, version)
+	}
+	return t, nil
+}
+
+// Assign picks the variant of name that subject sees. The subject is hashed
+// with the template name into a fixed bucket space that the variants split
+// by weight, in version order. A user keeps the same variant across requests
+// and restarts, and assignments for different templates are independent.
+// When weights change, buckets keep their place and only the range edges
+// move, so just the users between the old and new edges switch variant.
+func (r *Registry) Assign(name string, subject uuid.UUID) (*Template, error) {
+	variants := r.Variants(name)
+
+	total := 0
+	for _, t := range variants {
+		total += t.Weight
+	}
+	if total == 0 {
+		return nil, fmt.Errorf("%w: no weighted variant of %s", ErrTemplateNotFound, name)
+	}
+
+	h := fnv.New64a()
+	h.Write([]byte(name))
+	h.Write([]byte{0})
+	h.Write(subject[:])
+	bucket := int(h.Sum64() % assignBuckets)
+
+	cumulative := 0
+	for _, t := range variants {
+		cumulative += t.Weight
+		if t.Weight > 0 && bucket < cumulative*assignBuckets/total {
+			return t, nil
+		}
+	}
+	return variants[len(variants)-1], nil
+}
+
+// Variants returns every version of name, ordered by version.
+func (r *Registry) Variants(name string) []*Template {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	variants := make([]*Template, 0, len(r.templates[name]))
+	for _, t := range r.templates[name] {
+		variants = append(variants, t)
+	}
+	sort.Slice(variants, func(i, j int) bool { return variants[i].Version < variants[j].Version })
+	return variants
+}
+
+// Names returns the registered template names in order.
+func (r *Registry) Names() []string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	names := make([]string, 0, len(r.templates))
+	for name := range r.templates {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
@@ -0,0 +1,159 @@
+// Package prompts holds the named, versioned prompt templates sent to LLM
+// providers and assigns users to template variants for A/B comparisons.
+package prompts
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"text/template"
+)
+
+// Variable types a template can declare
+const (
+	TypeString  = "string"
+	TypeInt     = "int"
+	TypeFloat   = "float"
+	TypeStrings = "strings"
+)
+
+var (
+	ErrTemplateNotFound = errors.New("prompt template not found")
+	ErrInvalidTemplate  = errors.New("invalid prompt template")
+	ErrInvalidVariable  = errors.New("invalid prompt variable")
+)
+
+// Template is one version of a named prompt. Variables declares the name and
+// type of every value the system and user texts may reference; rendering
+// rejects missing, undeclared and mistyped values.
+type Template struct {
+	Name    string `json:"name"`
+	Version string `json:"version"`
+	// Weight is the variant's share of users among versions of the same
+	// name. Zero keeps the version renderable by ID but assigns nobody.
+	Weight    int               `json:"weight"`
+	System    string            `json:"system"`
+	User      string            `json:"user"`
+	Variables map[string]string `json:"variables"`
+
+	system *template.Template
+	user   *template.Template
+}
+
+// Vars are the values a template is rendered with.
+type Vars map[string]interface{}
+
+// Rendered is a template filled in for one request.
+type Rendered struct {
+	// ID is name/version, recorded on whatever the prompt produced
+	ID     string
+	System string
+	User   string
+}
+
+// ID identifies the version, e.g. "face-analysis/v1".
+func (t *Template) ID() string {
+	return t.Name + "/" + t.Version
+}
+
+// Declares reports whether the template takes the named variable.
+func (t *Template) Declares(name string) bool {
+	_, ok := t.Variables[name]
+	return ok
+}
+
+var funcs = template.FuncMap{
+	"join": strings.Join,
+}
+
+func (t *Template) compile() error {
+	if t.Name == "" || t.Version == "" || strings.Contains(t.Name, "/") {
+		return fmt.Errorf("%w: name and version are required", ErrInvalidTemplate)
+	}
+	if strings.TrimSpace(t.User) == "" {
+		return fmt.Errorf("%w: %s has no user text", ErrInvalidTemplate, t.ID())
+	}
+	if t.Weight < 0 {
+		return fmt.Errorf("%w: %s has a negative weight", ErrInvalidTemplate, t.ID())
+	}
+	for name, kind := range t.Variables {
+		switch kind {
+		case TypeString, TypeInt, TypeFloat, TypeStrings:
+		default:
+			return fmt.Errorf("%w: %s declares %s with unknown type %q", ErrInvalidTemplate, t.ID(), name, kind)
+		}
+	}
+
+	var err error
+	if t.system, err = template.New(t.ID() + ":system").Funcs(funcs).Option("missingkey=error").Parse(t.System); err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidTemplate, err)
+	}
+	if t.user, err = template.New(t.ID() + ":user").Funcs(funcs).Option("missingkey=error").Parse(t.User); err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidTemplate, err)
+	}
+	return nil
+}
+
+// Render checks vars against the declared variables and fills in both texts.
+func (t *Template) Render(vars Vars) (Rendered, error) {
+	if err := t.check(vars); err != nil {
+		return Rendered{}, err
+	}
+
+	var system, user bytes.Buffer
+	if err := t.system.Execute(&system, vars); err != nil {
+		return Rendered{}, fmt.Errorf("%s: %w", t.ID(), err)
+	}
+	if err := t.user.Execute(&user, vars); err != nil {
+		return Rendered{}, fmt.Errorf("%s: %w", t.ID(), err)
+	}
+	return Rendered{
+		ID:     t.ID(),
+		System: strings.TrimSpace(system.String()),
+		User:   strings.TrimSpace(user.String()),
+	}, nil
+}
+
+func (t *Template) check(vars Vars) error {
+	var problems []string
+	for name, kind := range t.Variables {
+		value, ok := vars[name]
+		if !ok {
+			problems = append(problems, name+" is missing")
+			continue
+		}
+		if !hasType(value, kind) {
+			problems = append(problems, fmt.Sprintf("%s must be %s, got %T", name, kind, value))
+		}
+	}
+	for name := range vars {
+		if _, ok := t.Variables[name]; !ok {
+			problems = append(problems, name+" is not declared")
+		}
+	}
+	if len(problems) == 0 {
+		return nil
+	}
+	sort.Strings(problems)
+	return fmt.Errorf("%w: %s: %s", ErrInvalidVariable, t.ID(), strings.Join(problems, "; "))
+}
+
+func hasType(value interface{}, kind string) bool {
+	switch kind {
+	case TypeString:
+		_, ok := value.(string)
+		return ok
+	case TypeInt:
+		_, ok := value.(int)
+		return ok
+	case TypeFloat:
+		_, ok := value.(float64)
+		return ok
+	case TypeStrings:
+		_, ok := value.([]string)
+		return ok
+	}
+	return false
+}
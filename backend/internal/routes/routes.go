@@ -4,12 +4,14 @@ import (
 	"github.com/ahmetcoskunkizilkaya/mewify/backend/internal/config"
 	"github.com/ahmetcoskunkizilkaya/mewify/backend/internal/handlers"
 	"github.com/ahmetcoskunkizilkaya/mewify/backend/internal/middleware"
+	"github.com/ahmetcoskunkizilkaya/mewify/backend/internal/services"
 	"github.com/gofiber/fiber/v2"
 )
 
 func Setup(
 	app *fiber.App,
 	cfg *config.Config,
+	subscriptionService *services.SubscriptionService,
 	authHandler *handlers.AuthHandler,
 	healthHandler *handlers.HealthHandler,
 	webhookHandler *handlers.WebhookHandler,
@@ -20,12 +22,23 @@ func Setup(
 	aiAnalysisHandler *handlers.AiAnalysisHandler,
 	usageHandler *handlers.UsageHandler,
 	legalHandler *handlers.LegalHandler,
+	gamificationHandler *handlers.GamificationHandler,
+	monetizationHandler *handlers.MonetizationHandler,
+	premiumContentHandler *handlers.PremiumContentHandler,
+	outboxHandler *handlers.OutboxHandler,
+	blobHandler *handlers.BlobHandler,
+	promptHandler *handlers.PromptHandler,
+	llmSpendHandler *handlers.LLMSpendHandler,
+	calibrationHandler *handlers.CalibrationHandler,
 ) {
 	api := app.Group("/api")
 
 	// Health
 	api.Get("/health", healthHandler.Check)
 
+	// Stored photos (public, authorized by the signed URL itself)
+	api.Get("/blobs/*", blobHandler.Serve)
+
 	// Legal pages (public, required for App Store)
 	api.Get("/privacy-policy", legalHandler.PrivacyPolicy)
 	api.Get("/terms", legalHandler.TermsOfService)
@@ -42,6 +55,7 @@ func Setup(
 	protected.Post("/auth/logout", authHandler.Logout)
 	protected.Post("/auth/claim", authHandler.ClaimGuest)
 	protected.Delete("/auth/account", authHandler.DeleteAccount) // Account deletion (Guideline 5.1.1)
+	protected.Put("/auth/locale", authHandler.UpdateLocale)
 
 	// Moderation - User endpoints (protected)
 	protected.Post("/reports", moderationHandler.CreateReport)     // Report content (Guideline 1.2)
@@ -53,11 +67,18 @@ func Setup(
 	protected.Get("/analyses", faceAnalysisHandler.List)
 	protected.Get("/analyses/latest", faceAnalysisHandler.GetLatest)
 	protected.Get("/analyses/stats", faceAnalysisHandler.GetStats)
+	protected.Get("/analyses/compare", faceAnalysisHandler.Compare)
+	protected.Get("/analyses/analytics", faceAnalysisHandler.GetAnalytics)
+	protected.Get("/analyses/jobs/:id", aiAnalysisHandler.GetJob)
+	protected.Get("/analyses/jobs/:id/events", aiAnalysisHandler.StreamJob)
+	protected.Get("/analyses/sessions/:id", faceAnalysisHandler.GetSession)
 	protected.Get("/analyses/:id", faceAnalysisHandler.GetByID)
+	protected.Put("/analyses/:id/tags", faceAnalysisHandler.UpdateTags)
 	protected.Delete("/analyses/:id", faceAnalysisHandler.Delete)
 
-	// AI Face Analysis (protected)
+	// AI Face Analysis (protected, queued; poll /analyses/jobs/:id or stream /analyses/jobs/:id/events)
 	protected.Post("/analyses/ai", aiAnalysisHandler.AnalyzeFace)
+	protected.Post("/analyses/sessions", aiAnalysisHandler.CreateSession)
 
 	// Usage tracking (protected)
 	protected.Get("/usage/remaining", usageHandler.GetRemaining)
@@ -78,10 +99,60 @@ func Setup(
 	glowPlan.Get("/progress", glowPlanHandler.GetProgress)
 	glowPlan.Put("/:id/complete", glowPlanHandler.MarkComplete)
 
+	// Gamification (protected)
+	gamification := protected.Group("/gamification")
+	gamification.Get("/stats", gamificationHandler.GetGamificationStats)
+	gamification.Get("/achievements", gamificationHandler.GetAchievements)
+	gamification.Get("/challenge/daily", gamificationHandler.GetDailyChallenge)
+	gamification.Post("/challenge/claim", gamificationHandler.ClaimChallengeReward)
+	gamification.Get("/leaderboard", gamificationHandler.GetLeaderboard)
+	gamification.Get("/notifications", gamificationHandler.GetNotifications)
+	gamification.Post("/notifications/:id/read", gamificationHandler.MarkNotificationRead)
+	gamification.Get("/xp/history", gamificationHandler.GetXPHistory)
+
+	// Monetization: gems, chests, streak freezes, decay, friends, offers (protected)
+	monetization := protected.Group("/monetization", middleware.PremiumStatus(subscriptionService.IsPremium))
+	monetization.Get("/currency", monetizationHandler.GetCurrency)
+	monetization.Get("/gems/history", monetizationHandler.GetGemHistory)
+	monetization.Get("/streak-freeze/check", monetizationHandler.CanUseStreakFreeze)
+	monetization.Post("/streak-freeze/use", monetizationHandler.UseStreakFreeze)
+	monetization.Get("/chest/status", monetizationHandler.CheckDailyChestStatus)
+	monetization.Post("/chest/open", monetizationHandler.OpenDailyChest)
+	monetization.Get("/decay/status", monetizationHandler.GetDecayStatus)
+	monetization.Get("/friends", monetizationHandler.GetFriends)
+	monetization.Post("/friends/request", monetizationHandler.SendFriendRequest)
+	monetization.Post("/friends/accept", monetizationHandler.AcceptFriendRequest)
+	monetization.Post("/friends/nudge", monetizationHandler.SendNudge)
+	monetization.Get("/friends/leaderboard", monetizationHandler.GetFriendsLeaderboard)
+	monetization.Get("/offers", monetizationHandler.GetIntroOffers)
+	monetization.Post("/offers/redeem", monetizationHandler.RedeemIntroOffer)
+
+	// Premium content: exercises, routines, weekly plans, tips (protected)
+	premium := protected.Group("/premium")
+	premium.Get("/exercises", premiumContentHandler.GetExercises)
+	premium.Get("/exercises/:id", premiumContentHandler.GetExercise)
+	premium.Get("/routine/today", premiumContentHandler.GetTodayRoutine)
+	premium.Get("/plan/week", premiumContentHandler.GetWeekPlan)
+	premium.Get("/tips", premiumContentHandler.GetTips)
+
 	// Admin moderation panel (protected + admin check)
 	admin := api.Group("/admin", middleware.JWTProtected(cfg), middleware.AdminOnly(cfg))
 	admin.Get("/moderation/reports", moderationHandler.ListReports)
 	admin.Put("/moderation/reports/:id", moderationHandler.ActionReport)
+	admin.Get("/outbox/dead-letters", outboxHandler.ListDeadLetters)
+	admin.Post("/outbox/dead-letters/:id/retry", outboxHandler.RetryDeadLetter)
+	admin.Get("/llm/providers", healthHandler.LLMProviders)
+	admin.Get("/llm/output", healthHandler.LLMOutput)
+	admin.Get("/llm/spend", llmSpendHandler.Summary)
+	admin.Get("/analyses", faceAnalysisHandler.AdminList)
+	admin.Get("/analyses/duplicates", faceAnalysisHandler.AdminDuplicates)
+	admin.Get("/analyses/:id", faceAnalysisHandler.AdminGet)
+	admin.Get("/prompts", promptHandler.List)
+	admin.Get("/prompts/stats", promptHandler.Stats)
+	admin.Post("/prompts/reload", promptHandler.Reload)
+	admin.Get("/calibration", calibrationHandler.Status)
+	admin.Post("/calibration/references", calibrationHandler.AddReference)
+	admin.Post("/calibration/recompute", calibrationHandler.Recompute)
 
 	// Webhooks (verified by auth header, not JWT)
 	webhooks := api.Group("/webhooks")
@@ -1,12 +1,10 @@
 package services
 
 import (
-	"bytes"
+	"context"
 	"crypto/sha256"
-	"encoding/json"
-	"errors"
+	"encoding/base64"
 	"fmt"
-	"io"
 	"net/http"
 	"strings"
 	"time"
@@ -14,43 +12,57 @@ import (
 	"github.com/google/uuid"
 	"gorm.io/gorm"
 
-	"github.com/ahmetcoskunkizilkaya/mewify/backend/internal/config"
+	"github.com/ahmetcoskunkizilkaya/mewify/backend/internal/events"
+	"github.com/ahmetcoskunkizilkaya/mewify/backend/internal/i18n"
+	"github.com/ahmetcoskunkizilkaya/mewify/backend/internal/llm"
 	"github.com/ahmetcoskunkizilkaya/mewify/backend/internal/models"
+	"github.com/ahmetcoskunkizilkaya/mewify/backend/internal/prompts"
 )
 
 type AiAnalysisService struct {
-	db  *gorm.DB
-	cfg *config.Config
+	db      *gorm.DB
+	llm     *llm.Client
+	bus     *events.Bus
+	prompts *prompts.Registry
+	spend   *LLMSpendService
 }
 
-type llmProvider struct {
-	name   string
-	apiURL string
-	apiKey string
-	model  string
-}
-
-// Shared request/response structs used by multiple services in this package.
-type openAIRequest struct {
-	Model          string          `json:"model"`
-	Messages       []openAIMessage `json:"messages"`
-	MaxTokens      int             `json:"max_tokens,omitempty"`
-	Temperature    float64         `json:"temperature,omitempty"`
-	ResponseFormat interface{}     `json:"response_format,omitempty"`
-}
-
-type openAIMessage struct {
-	Role    string      `json:"role"`
-	Content interface{} `json:"content"`
-}
-
-type openAIResponse struct {
-	Choices []struct {
-		Message struct {
-			Content string `json:"content"`
-		} `json:"message"`
-	} `json:"choices"`
-}
+// maxFallbackErrorLen bounds the provider errors kept on an analysis; a failed
+// chain joins one error per provider.
+const maxFallbackErrorLen = 1000
+
+// analysisSchema is the reply contract of the face-analysis prompt.
+var analysisSchema = func() *llm.Schema {
+	score := &llm.Schema{Type: llm.TypeNumber, Minimum: llm.Float(1), Maximum: llm.Float(10)}
+	list := &llm.Schema{
+		Type:        llm.TypeArray,
+		Items:       &llm.Schema{Type: llm.TypeString, MinLength: llm.Int(1)},
+		MinItems:    llm.Int(3),
+		MaxItems:    llm.Int(3),
+		UniqueItems: true,
+	}
+	return &llm.Schema{
+		Title: "face-analysis",
+		Type:  llm.TypeObject,
+		Properties: map[string]*llm.Schema{
+			"overall_score":  score,
+			"symmetry_score": score,
+			"jawline_score":  score,
+			"skin_score":     score,
+			"eye_score":      score,
+			"nose_score":     score,
+			"lips_score":     score,
+			"harmony_score":  score,
+			"strengths":      list,
+			"improvements":   list,
+		},
+		Required: []string{
+			"overall_score", "symmetry_score", "jawline_score", "skin_score", "eye_score",
+			"nose_score", "lips_score", "harmony_score", "strengths", "improvements",
+		},
+		AdditionalProperties: llm.Closed,
+	}
+}()
 
 type aiAnalysisResult struct {
 	OverallScore  float64  `json:"overall_score"`
@@ -65,182 +77,179 @@ type aiAnalysisResult struct {
 	Improvements  []string `json:"improvements"`
 }
 
-func NewAiAnalysisService(db *gorm.DB, cfg *config.Config) *AiAnalysisService {
-	return &AiAnalysisService{db: db, cfg: cfg}
+func NewAiAnalysisService(db *gorm.DB, llmClient *llm.Client, bus *events.Bus, registry *prompts.Registry, spend *LLMSpendService) *AiAnalysisService {
+	return &AiAnalysisService{db: db, llm: llmClient, bus: bus, prompts: registry, spend: spend}
 }
 
-func (s *AiAnalysisService) AnalyzeImage(userID uuid.UUID, imageBase64 string) (*models.FaceAnalysis, error) {
-	fallback := deterministicAIResult(imageBase64)
+// scoreImage scores a photo, falling back to deterministic scores when no
+// vision model can, and records which of the two produced the result. Only a
+// vision-capable model that received the photo yields an llm-sourced
+// analysis. The analysis records the prompt variant the user is assigned to;
+// strengths and improvements are written in locale. The returned analysis is
+// not yet saved.
+func (s *AiAnalysisService) scoreImage(ctx context.Context, userID uuid.UUID, imageBase64, locale string) *models.FaceAnalysis {
+	fallback := deterministicAIResult(imageBase64, locale)
 	result := fallback
 
-	if llmResult, err := s.analyzeWithLLM(imageBase64, fallback); err == nil {
-		result = llmResult
-	}
-
+	start := time.Now()
+	promptID, llmResult, resp, err := s.analyzeWithLLM(ctx, s.llm, userID, imageBase64, locale, fallback)
 	analysis := &models.FaceAnalysis{
 		UserID:        userID,
-		ImageURL:      "ai-analyzed",
-		OverallScore:  result.OverallScore,
-		SymmetryScore: result.SymmetryScore,
-		JawlineScore:  result.JawlineScore,
-		SkinScore:     result.SkinScore,
-		EyeScore:      result.EyeScore,
-		NoseScore:     result.NoseScore,
-		LipsScore:     result.LipsScore,
-		HarmonyScore:  result.HarmonyScore,
-		Strengths:     result.Strengths,
-		Improvements:  result.Improvements,
-		AnalyzedAt:    time.Now(),
+		Source:        models.AnalysisSourceDeterministic,
+		PromptVersion: promptID,
+		LatencyMs:     time.Since(start).Milliseconds(),
 	}
-
-	if err := s.db.Create(analysis).Error; err != nil {
-		return nil, fmt.Errorf("failed to save analysis: %w", err)
+	if resp != nil {
+		s.spend.Record(userID, LLMFeatureFaceAnalysis, resp.Response)
+		analysis.Provider = resp.Provider
+		analysis.Model = resp.Model
+		analysis.PromptTokens = resp.Usage.PromptTokens
+		analysis.CompletionTokens = resp.Usage.CompletionTokens
+		analysis.RepairedFields = resp.Repaired
+		analysis.DefaultedFields = resp.Defaulted()
+	}
+	if err == nil {
+		result = llmResult
+		analysis.Source = models.AnalysisSourceLLM
+	} else {
+		analysis.FallbackError = truncateError(err, maxFallbackErrorLen)
 	}
 
-	return analysis, nil
+	analysis.OverallScore = result.OverallScore
+	analysis.SymmetryScore = result.SymmetryScore
+	analysis.JawlineScore = result.JawlineScore
+	analysis.SkinScore = result.SkinScore
+	analysis.EyeScore = result.EyeScore
+	analysis.NoseScore = result.NoseScore
+	analysis.LipsScore = result.LipsScore
+	analysis.HarmonyScore = result.HarmonyScore
+	analysis.Strengths = result.Strengths
+	analysis.Improvements = result.Improvements
+	analysis.AnalyzedAt = time.Now()
+	return analysis
 }
 
-func (s *AiAnalysisService) providers() []llmProvider {
-	providers := make([]llmProvider, 0, 2)
-
-	if strings.TrimSpace(s.cfg.GLMAPIKey) != "" {
-		providers = append(providers, llmProvider{
-			name:   "glm",
-			apiURL: strings.TrimSpace(s.cfg.GLMAPIURL),
-			apiKey: strings.TrimSpace(s.cfg.GLMAPIKey),
-			model:  strings.TrimSpace(s.cfg.GLMModel),
-		})
+// scoreReference scores a calibration reference image with one provider
+// alone, so each provider's scale can be measured. Replies that needed
+// fallback values are rejected rather than mixing scales.
+func (s *AiAnalysisService) scoreReference(ctx context.Context, provider, imageBase64 string) (string, map[string]float64, error) {
+	client, ok := s.llm.Only(provider)
+	if !ok {
+		return "", nil, fmt.Errorf("provider %q is not configured", provider)
 	}
-
-	if strings.TrimSpace(s.cfg.DeepSeekAPIKey) != "" {
-		providers = append(providers, llmProvider{
-			name:   "deepseek",
-			apiURL: strings.TrimSpace(s.cfg.DeepSeekAPIURL),
-			apiKey: strings.TrimSpace(s.cfg.DeepSeekAPIKey),
-			model:  strings.TrimSpace(s.cfg.DeepSeekModel),
-		})
+	_, result, resp, err := s.analyzeWithLLM(ctx, client, uuid.Nil, imageBase64, i18n.Default, deterministicAIResult(imageBase64, i18n.Default))
+	if resp != nil {
+		s.spend.Record(uuid.Nil, LLMFeatureCalibration, resp.Response)
 	}
-
-	return providers
-}
-
-func (s *AiAnalysisService) analyzeWithLLM(imageBase64 string, fallback aiAnalysisResult) (aiAnalysisResult, error) {
-	providers := s.providers()
-	if len(providers) == 0 {
-		return fallback, errors.New("no llm provider configured")
+	if err != nil {
+		return "", nil, err
 	}
-
-	var lastErr error
-	for _, provider := range providers {
-		result, err := s.analyzeWithProvider(provider, imageBase64, fallback)
-		if err == nil {
-			return result, nil
-		}
-		lastErr = fmt.Errorf("%s provider failed: %w", provider.name, err)
+	if defaulted := resp.Defaulted(); len(defaulted) > 0 {
+		return "", nil, fmt.Errorf("%s: reply missing %s", provider, strings.Join(defaulted, ", "))
 	}
+	return resp.Model, result.scores(), nil
+}
 
-	if lastErr != nil {
-		return fallback, lastErr
+// scores returns the eight scores by metric name.
+func (r aiAnalysisResult) scores() map[string]float64 {
+	return map[string]float64{
+		"overall":  r.OverallScore,
+		"symmetry": r.SymmetryScore,
+		"jawline":  r.JawlineScore,
+		"skin":     r.SkinScore,
+		"eye":      r.EyeScore,
+		"nose":     r.NoseScore,
+		"lips":     r.LipsScore,
+		"harmony":  r.HarmonyScore,
 	}
-	return fallback, errors.New("llm analysis failed")
 }
 
-func (s *AiAnalysisService) analyzeWithProvider(provider llmProvider, imageBase64 string, fallback aiAnalysisResult) (aiAnalysisResult, error) {
-	timeout := s.cfg.LLMTimeout
-	if timeout <= 0 {
-		timeout = 20 * time.Second
+// defaultedScores lists the score fields left invalid after the repair. The
+// fallback value in their place would be an estimate recorded as the
+// model's.
+func defaultedScores(resp *llm.StructuredResponse) []string {
+	var scores []string
+	for _, field := range resp.Defaulted() {
+		if strings.HasSuffix(field, "_score") {
+			scores = append(scores, field)
+		}
 	}
+	return scores
+}
 
-	snippet := firstNChars(strings.TrimSpace(imageBase64), 1800)
-	if snippet == "" {
-		snippet = "missing_image_data"
+func truncateError(err error, max int) string {
+	msg := err.Error()
+	if len(msg) > max {
+		return msg[:max] + "…"
 	}
+	return msg
+}
 
-	prompt := fmt.Sprintf(
-		"Analyze this facial image represented by base64 prefix and return ONLY valid JSON. "+
-			"base64_prefix=%q fallback=%+v. Output keys: overall_score, symmetry_score, jawline_score, skin_score, eye_score, nose_score, lips_score, harmony_score, strengths (3 strings), improvements (3 strings). "+
-			"Scores must be floats in range 1.0-10.0.",
-		snippet,
-		fallback,
-	)
-
-	reqBody := openAIRequest{
-		Model: provider.model,
-		Messages: []openAIMessage{
-			{Role: "system", Content: "You are a facial aesthetics scoring engine. Return valid JSON only."},
-			{Role: "user", Content: prompt},
-		},
-		Temperature:    0.2,
-		ResponseFormat: map[string]string{"type": "json_object"},
+// saveAnalysis stores a scored analysis and publishes AnalysisCreated in tx.
+func (s *AiAnalysisService) saveAnalysis(tx *gorm.DB, analysis *models.FaceAnalysis) error {
+	if err := tx.Create(analysis).Error; err != nil {
+		return fmt.Errorf("failed to save analysis: %w", err)
 	}
+	return s.bus.Publish(tx, events.AnalysisCreated{
+		UserID:       analysis.UserID,
+		AnalysisID:   analysis.ID,
+		Source:       analysis.Source,
+		OverallScore: analysis.OverallScore,
+		AnalyzedAt:   analysis.AnalyzedAt,
+	})
+}
 
-	payload, err := json.Marshal(reqBody)
+// analyzeWithLLM returns the prompt variant and the validated response so
+// the caller can record which model answered and what had to be repaired.
+// A score still invalid after the repair round-trip rejects the reply, so
+// the caller falls back to deterministic scores; other fields take fallback
+// values.
+func (s *AiAnalysisService) analyzeWithLLM(ctx context.Context, client *llm.Client, userID uuid.UUID, imageBase64, locale string, fallback aiAnalysisResult) (string, aiAnalysisResult, *llm.StructuredResponse, error) {
+	tmpl, err := s.prompts.Assign(prompts.FaceAnalysis, userID)
 	if err != nil {
-		return fallback, err
+		return "", fallback, nil, err
 	}
-
-	httpReq, err := http.NewRequest(http.MethodPost, provider.apiURL, bytes.NewReader(payload))
+	prompt, err := tmpl.Render(withLanguage(tmpl, prompts.Vars{}, locale))
 	if err != nil {
-		return fallback, err
+		return tmpl.ID(), fallback, nil, err
 	}
-	httpReq.Header.Set("Content-Type", "application/json")
-	httpReq.Header.Set("Authorization", "Bearer "+provider.apiKey)
-
-	client := &http.Client{Timeout: timeout}
-	resp, err := client.Do(httpReq)
-	if err != nil {
-		return fallback, err
+	if err := s.spend.CheckBudget(userID); err != nil {
+		return prompt.ID, fallback, nil, err
 	}
-	defer resp.Body.Close()
 
-	body, err := io.ReadAll(resp.Body)
+	imageURL, err := imageDataURL(imageBase64)
 	if err != nil {
-		return fallback, err
+		return prompt.ID, fallback, nil, err
 	}
 
-	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
-		return fallback, fmt.Errorf("status=%d", resp.StatusCode)
-	}
-
-	var completion openAIResponse
-	if err := json.Unmarshal(body, &completion); err != nil {
-		return fallback, err
-	}
-	if len(completion.Choices) == 0 {
-		return fallback, errors.New("empty choices")
-	}
-
-	parsed, err := parseAIAnalysis(completion.Choices[0].Message.Content)
+	// Text-only models never see the face, so the client skips them for
+	// requests that carry an image.
+	resp, err := client.CompleteStructured(ctx, llm.Request{
+		System: prompt.System,
+		Messages: []llm.Message{{
+			Role:   "user",
+			Text:   prompt.User,
+			Images: []string{imageURL},
+		}},
+		Temperature: 0.2,
+	}, analysisSchema)
 	if err != nil {
-		return fallback, err
+		return prompt.ID, fallback, resp, err
 	}
 
-	return normalizeAIResult(parsed, fallback), nil
-}
-
-func parseAIAnalysis(content string) (aiAnalysisResult, error) {
-	content = strings.TrimSpace(content)
-	if content == "" {
-		return aiAnalysisResult{}, errors.New("empty ai response")
+	if missing := defaultedScores(resp); len(missing) > 0 {
+		return prompt.ID, fallback, resp, fmt.Errorf("%s: reply missing %s", resp.Provider, strings.Join(missing, ", "))
 	}
 
 	var parsed aiAnalysisResult
-	if err := json.Unmarshal([]byte(content), &parsed); err == nil {
-		return parsed, nil
+	if err := resp.Decode(&parsed); err != nil {
+		return prompt.ID, fallback, resp, fmt.Errorf("%s: %w", resp.Provider, err)
 	}
 
-	start := strings.Index(content, "{")
-	end := strings.LastIndex(content, "}")
-	if start >= 0 && end > start {
-		if err := json.Unmarshal([]byte(content[start:end+1]), &parsed); err == nil {
-			return parsed, nil
-		}
-	}
-
-	return aiAnalysisResult{}, errors.New("unable to parse ai json")
+	return prompt.ID, normalizeAIResult(parsed, fallback, locale), resp, nil
 }
 
-func normalizeAIResult(raw aiAnalysisResult, fallback aiAnalysisResult) aiAnalysisResult {
+func normalizeAIResult(raw aiAnalysisResult, fallback aiAnalysisResult, locale string) aiAnalysisResult {
 	out := raw
 
 	out.OverallScore = clampFloat(out.OverallScore, 1, 10, fallback.OverallScore)
@@ -252,13 +261,13 @@ func normalizeAIResult(raw aiAnalysisResult, fallback aiAnalysisResult) aiAnalys
 	out.LipsScore = clampFloat(out.LipsScore, 1, 10, fallback.LipsScore)
 	out.HarmonyScore = clampFloat(out.HarmonyScore, 1, 10, fallback.HarmonyScore)
 
-	out.Strengths = normalizeList(out.Strengths, fallback.Strengths)
-	out.Improvements = normalizeList(out.Improvements, fallback.Improvements)
+	out.Strengths = normalizeList(out.Strengths, fallback.Strengths, locale)
+	out.Improvements = normalizeList(out.Improvements, fallback.Improvements, locale)
 
 	return out
 }
 
-func normalizeList(raw []string, fallback []string) []string {
+func normalizeList(raw []string, fallback []string, locale string) []string {
 	seen := make(map[string]struct{})
 	clean := make([]string, 0, 3)
 
@@ -292,8 +301,8 @@ func normalizeList(raw []string, fallback []string) []string {
 		}
 	}
 
-	defaults := []string{"facial symmetry", "jawline structure", "skin tone"}
-	for _, v := range defaults {
+	for _, key := range defaultListKeys {
+		v := i18n.T(locale, key)
 		if _, ok := seen[v]; ok {
 			continue
 		}
@@ -306,7 +315,20 @@ func normalizeList(raw []string, fallback []string) []string {
 	return clean
 }
 
-func deterministicAIResult(imageBase64 string) aiAnalysisResult {
+// Catalog keys of the wording deterministic results draw from
+var (
+	strengthKeys = []string{
+		"analysis.strength.balanced_proportions", "analysis.strength.jawline_definition", "analysis.strength.clear_skin",
+		"analysis.strength.eye_symmetry", "analysis.strength.facial_harmony", "analysis.strength.confident_expression",
+	}
+	improvementKeys = []string{
+		"analysis.improvement.hydration_routine", "analysis.improvement.sleep_consistency", "analysis.improvement.posture_training",
+		"analysis.improvement.skincare_consistency", "analysis.improvement.facial_relaxation", "analysis.improvement.photo_lighting",
+	}
+	defaultListKeys = []string{"analysis.default.facial_symmetry", "analysis.default.jawline_structure", "analysis.default.skin_tone"}
+)
+
+func deterministicAIResult(imageBase64, locale string) aiAnalysisResult {
 	h := sha256.Sum256([]byte(strings.TrimSpace(imageBase64)))
 
 	base := 5.5 + float64(h[0]%35)/10.0
@@ -318,9 +340,6 @@ func deterministicAIResult(imageBase64 string) aiAnalysisResult {
 	lips := base + float64(int(h[6]%7)-3)/10.0
 	harmony := (sym + jaw + skin + eye + nose + lips) / 6
 
-	strengthPool := []string{"balanced proportions", "jawline definition", "clear skin", "eye symmetry", "facial harmony", "confident expression"}
-	improvementPool := []string{"hydration routine", "sleep consistency", "posture and neck training", "skincare consistency", "facial relaxation", "photo lighting awareness"}
-
 	return aiAnalysisResult{
 		OverallScore:  clampFloat(base, 1, 10, 7.0),
 		SymmetryScore: clampFloat(sym, 1, 10, 7.0),
@@ -331,14 +350,14 @@ func deterministicAIResult(imageBase64 string) aiAnalysisResult {
 		LipsScore:     clampFloat(lips, 1, 10, 7.0),
 		HarmonyScore:  clampFloat(harmony, 1, 10, 7.0),
 		Strengths: []string{
-			strengthPool[int(h[7])%len(strengthPool)],
-			strengthPool[int(h[8])%len(strengthPool)],
-			strengthPool[int(h[9])%len(strengthPool)],
+			i18n.T(locale, strengthKeys[int(h[7])%len(strengthKeys)]),
+			i18n.T(locale, strengthKeys[int(h[8])%len(strengthKeys)]),
+			i18n.T(locale, strengthKeys[int(h[9])%len(strengthKeys)]),
 		},
 		Improvements: []string{
-			improvementPool[int(h[10])%len(improvementPool)],
-			improvementPool[int(h[11])%len(improvementPool)],
-			improvementPool[int(h[12])%len(improvementPool)],
+			i18n.T(locale, improvementKeys[int(h[10])%len(improvementKeys)]),
+			i18n.T(locale, improvementKeys[int(h[11])%len(improvementKeys)]),
+			i18n.T(locale, improvementKeys[int(h[12])%len(improvementKeys)]),
 		},
 	}
 }
@@ -356,9 +375,23 @@ func clampFloat(v, min, max, fallback float64) float64 {
 	return v
 }
 
-func firstNChars(s string, n int) string {
-	if len(s) <= n {
-		return s
+// imageDataURL turns the uploaded image into a data URL for an image_url
+// content part. Input that already is a data URL is passed through.
+func imageDataURL(imageBase64 string) (string, error) {
+	imageBase64 = strings.TrimSpace(imageBase64)
+	if strings.HasPrefix(imageBase64, "data:image/") {
+		return imageBase64, nil
+	}
+
+	raw, err := base64.StdEncoding.DecodeString(imageBase64)
+	if err != nil {
+		return "", fmt.Errorf("invalid base64 image: %w", err)
 	}
-	return s[:n]
+
+	mimeType := http.DetectContentType(raw)
+	if !strings.HasPrefix(mimeType, "image/") {
+		return "", fmt.Errorf("unsupported image type %q", mimeType)
+	}
+
+	return "data:" + mimeType + ";base64," + imageBase64, nil
 }
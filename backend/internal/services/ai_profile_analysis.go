@@ -0,0 +1,207 @@
+package services
+
+import (
+	"context"
+	"crypto/sha256"
+	"fmt"
+	"strings"
+
+	"github.com/google/uuid"
+
+	"github.com/ahmetcoskunkizilkaya/mewify/backend/internal/i18n"
+	"github.com/ahmetcoskunkizilkaya/mewify/backend/internal/llm"
+	"github.com/ahmetcoskunkizilkaya/mewify/backend/internal/models"
+	"github.com/ahmetcoskunkizilkaya/mewify/backend/internal/prompts"
+)
+
+// Profile metrics
+const (
+	MetricJawAngle       = "jaw_angle"
+	MetricChinProjection = "chin_projection"
+	MetricNeckPosture    = "neck_posture"
+)
+
+// profileSchema is the reply contract of the face-profile prompt.
+var profileSchema = func() *llm.Schema {
+	score := &llm.Schema{Type: llm.TypeNumber, Minimum: llm.Float(1), Maximum: llm.Float(10)}
+	return &llm.Schema{
+		Title: "face-profile",
+		Type:  llm.TypeObject,
+		Properties: map[string]*llm.Schema{
+			"jaw_angle_score":       score,
+			"chin_projection_score": score,
+			"neck_posture_score":    score,
+			"observations": {
+				Type:     llm.TypeArray,
+				Items:    &llm.Schema{Type: llm.TypeString, MinLength: llm.Int(1)},
+				MinItems: llm.Int(1),
+				MaxItems: llm.Int(3),
+			},
+		},
+		Required:             []string{"jaw_angle_score", "chin_projection_score", "neck_posture_score", "observations"},
+		AdditionalProperties: llm.Closed,
+	}
+}()
+
+type aiProfileResult struct {
+	JawAngleScore       float64  `json:"jaw_angle_score"`
+	ChinProjectionScore float64  `json:"chin_projection_score"`
+	NeckPostureScore    float64  `json:"neck_posture_score"`
+	Observations        []string `json:"observations"`
+}
+
+// profileSides names each profile angle in the prompt.
+var profileSides = map[string]string{
+	models.ScanAngleLeftProfile:  "left",
+	models.ScanAngleRightProfile: "right",
+}
+
+// scoreProfile scores a profile photo the way scoreImage scores a front one,
+// falling back to deterministic scores when no vision model answers. The
+// result is not yet saved.
+func (s *AiAnalysisService) scoreProfile(ctx context.Context, userID uuid.UUID, angle, imageBase64, locale string) *models.ScanAngleResult {
+	result := deterministicProfileResult(imageBase64, locale)
+	angleResult := &models.ScanAngleResult{
+		ID:     uuid.New(),
+		Angle:  angle,
+		Source: models.AnalysisSourceDeterministic,
+	}
+
+	promptID, llmResult, resp, err := s.analyzeProfileWithLLM(ctx, userID, angle, imageBase64, locale, result)
+	angleResult.PromptVersion = promptID
+	if resp != nil {
+		s.spend.Record(userID, LLMFeatureFaceAnalysis, resp.Response)
+		angleResult.Provider = resp.Provider
+		angleResult.Model = resp.Model
+	}
+	if err == nil {
+		result = llmResult
+		angleResult.Source = models.AnalysisSourceLLM
+	} else {
+		angleResult.FallbackError = truncateError(err, maxFallbackErrorLen)
+	}
+
+	angleResult.Scores = map[string]float64{
+		MetricJawAngle:       result.JawAngleScore,
+		MetricChinProjection: result.ChinProjectionScore,
+		MetricNeckPosture:    result.NeckPostureScore,
+	}
+	angleResult.Observations = result.Observations
+	return angleResult
+}
+
+func (s *AiAnalysisService) analyzeProfileWithLLM(ctx context.Context, userID uuid.UUID, angle, imageBase64, locale string, fallback aiProfileResult) (string, aiProfileResult, *llm.StructuredResponse, error) {
+	tmpl, err := s.prompts.Assign(prompts.FaceProfile, userID)
+	if err != nil {
+		return "", fallback, nil, err
+	}
+	prompt, err := tmpl.Render(withLanguage(tmpl, prompts.Vars{"side": profileSides[angle]}, locale))
+	if err != nil {
+		return tmpl.ID(), fallback, nil, err
+	}
+	if err := s.spend.CheckBudget(userID); err != nil {
+		return prompt.ID, fallback, nil, err
+	}
+
+	imageURL, err := imageDataURL(imageBase64)
+	if err != nil {
+		return prompt.ID, fallback, nil, err
+	}
+
+	resp, err := s.llm.CompleteStructured(ctx, llm.Request{
+		System: prompt.System,
+		Messages: []llm.Message{{
+			Role:   "user",
+			Text:   prompt.User,
+			Images: []string{imageURL},
+		}},
+		Temperature: 0.2,
+	}, profileSchema)
+	if err != nil {
+		return prompt.ID, fallback, resp, err
+	}
+
+	if missing := defaultedScores(resp); len(missing) > 0 {
+		return prompt.ID, fallback, resp, fmt.Errorf("%s: reply missing %s", resp.Provider, strings.Join(missing, ", "))
+	}
+
+	var parsed aiProfileResult
+	if err := resp.Decode(&parsed); err != nil {
+		return prompt.ID, fallback, resp, fmt.Errorf("%s: %w", resp.Provider, err)
+	}
+
+	parsed.JawAngleScore = clampFloat(parsed.JawAngleScore, 1, 10, fallback.JawAngleScore)
+	parsed.ChinProjectionScore = clampFloat(parsed.ChinProjectionScore, 1, 10, fallback.ChinProjectionScore)
+	parsed.NeckPostureScore = clampFloat(parsed.NeckPostureScore, 1, 10, fallback.NeckPostureScore)
+	if len(parsed.Observations) == 0 {
+		parsed.Observations = fallback.Observations
+	}
+	return prompt.ID, parsed, resp, nil
+}
+
+// observationKeys are the catalog keys of the deterministic observations.
+var observationKeys = []string{
+	"profile.observation.tongue_posture",
+	"profile.observation.chin_tucks",
+	"profile.observation.head_tilt",
+	"profile.observation.neck_stack",
+}
+
+func deterministicProfileResult(imageBase64, locale string) aiProfileResult {
+	h := sha256.Sum256([]byte(strings.TrimSpace(imageBase64)))
+
+	base := 5.5 + float64(h[0]%35)/10.0
+
+	return aiProfileResult{
+		JawAngleScore:       clampFloat(base+float64(int(h[1]%7)-3)/10.0, 1, 10, 7.0),
+		ChinProjectionScore: clampFloat(base+float64(int(h[2]%7)-3)/10.0, 1, 10, 7.0),
+		NeckPostureScore:    clampFloat(base+float64(int(h[3]%7)-3)/10.0, 1, 10, 7.0),
+		Observations: []string{
+			i18n.T(locale, observationKeys[int(h[4])%len(observationKeys)]),
+			i18n.T(locale, observationKeys[(int(h[4])+1)%len(observationKeys)]),
+		},
+	}
+}
+
+// summarizeSession folds the profile results into the front analysis before
+// it is calibrated, so its raw scores are the blended ones. The session keeps
+// the profile averages; the summary jawline becomes the mean of the front
+// jawline and the profile jaw angle and chin projection, since the profile
+// shows the jaw far better, and the overall score moves by the same change
+// spread over its eight metrics. Only model-scored profiles count: blending
+// in fallback estimates would pass them off as the model's scores.
+func summarizeSession(session *models.ScanSession, analysis *models.FaceAnalysis, profiles []*models.ScanAngleResult) {
+	var scored []*models.ScanAngleResult
+	for _, p := range profiles {
+		if p.Source == models.AnalysisSourceLLM {
+			scored = append(scored, p)
+		}
+	}
+	if len(scored) == 0 {
+		return
+	}
+
+	average := func(metric string) *float64 {
+		var sum float64
+		for _, p := range scored {
+			sum += p.Scores[metric]
+		}
+		avg := round1(sum / float64(len(scored)))
+		return &avg
+	}
+	session.JawAngleScore = average(MetricJawAngle)
+	session.ChinProjectionScore = average(MetricChinProjection)
+	session.NeckPostureScore = average(MetricNeckPosture)
+
+	blendProfiles(analysis, session)
+}
+
+// blendProfiles moves the front scores of analysis to the session summary.
+func blendProfiles(analysis *models.FaceAnalysis, session *models.ScanSession) {
+	if session.JawAngleScore == nil || session.ChinProjectionScore == nil {
+		return
+	}
+	jawline := round1((analysis.JawlineScore + *session.JawAngleScore + *session.ChinProjectionScore) / 3)
+	analysis.OverallScore = clampFloat(round1(analysis.OverallScore+(jawline-analysis.JawlineScore)/8), 1, 10, analysis.OverallScore)
+	analysis.JawlineScore = jawline
+}
@@ -0,0 +1,363 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/ahmetcoskunkizilkaya/mewify/backend/internal/dto"
+	"github.com/ahmetcoskunkizilkaya/mewify/backend/internal/models"
+)
+
+// Analytics granularities
+const (
+	GranularityDay   = "day"
+	GranularityWeek  = "week"
+	GranularityMonth = "month"
+)
+
+const (
+	defaultAnalyticsWindow = 3
+	maxAnalyticsWindow     = 12
+	maxAnalyticsPeriods    = 400
+)
+
+var (
+	ErrInvalidGranularity    = errors.New("granularity must be day, week or month")
+	ErrInvalidAnalyticsRange = errors.New("invalid analytics date range")
+)
+
+// defaultAnalyticsPeriods is how far back each granularity looks when no
+// start date is given.
+var defaultAnalyticsPeriods = map[string]int{
+	GranularityDay:   30,
+	GranularityWeek:  26,
+	GranularityMonth: 12,
+}
+
+// GetAnalytics buckets a user's scans into day, week or month periods and
+// derives, per metric, the period averages, a trailing moving average, a
+// least squares trend over the individual scans, the best and worst scan and
+// the correlation with mewing minutes logged in the same periods.
+func (s *faceAnalysisService) GetAnalytics(userID uuid.UUID, query dto.AnalyticsQuery) (*dto.AnalyticsResponse, error) {
+	if query.Granularity == "" {
+		query.Granularity = GranularityWeek
+	}
+	defaultPeriods, ok := defaultAnalyticsPeriods[query.Granularity]
+	if !ok {
+		return nil, ErrInvalidGranularity
+	}
+	if query.Window <= 0 {
+		query.Window = defaultAnalyticsWindow
+	}
+	if query.Window > maxAnalyticsWindow {
+		query.Window = maxAnalyticsWindow
+	}
+	if query.To.IsZero() {
+		query.To = time.Now()
+	}
+	last := periodStart(query.To, query.Granularity)
+	first := addPeriods(last, query.Granularity, -(defaultPeriods - 1))
+	if !query.From.IsZero() {
+		first = periodStart(query.From, query.Granularity)
+	}
+	if first.After(last) {
+		return nil, ErrInvalidAnalyticsRange
+	}
+
+	var starts []time.Time
+	for p := first; !p.After(last); p = addPeriods(p, query.Granularity, 1) {
+		if len(starts) == maxAnalyticsPeriods {
+			return nil, fmt.Errorf("%w: more than %d periods", ErrInvalidAnalyticsRange, maxAnalyticsPeriods)
+		}
+		starts = append(starts, p)
+	}
+	end := addPeriods(last, query.Granularity, 1)
+
+	var analyses []models.FaceAnalysis
+	if err := s.db.Where("user_id = ? AND analyzed_at >= ? AND analyzed_at < ?", userID, first, end).
+		Order("analyzed_at ASC").
+		Find(&analyses).Error; err != nil {
+		return nil, err
+	}
+
+	var mewing []struct {
+		Date          time.Time
+		MewingMinutes int
+	}
+	if err := s.db.Model(&models.MewingProgress{}).
+		Select("date, mewing_minutes").
+		Where("user_id = ? AND date >= ? AND date < ?", userID, first.Format("2006-01-02"), end.Format("2006-01-02")).
+		Scan(&mewing).Error; err != nil {
+		return nil, err
+	}
+
+	index := make(map[time.Time]int, len(starts))
+	response := &dto.AnalyticsResponse{
+		Granularity: query.Granularity,
+		From:        first,
+		To:          end,
+		Window:      query.Window,
+		Periods:     make([]dto.AnalyticsPeriod, len(starts)),
+		Metrics:     make([]dto.MetricAnalytics, 0, len(AnalysisMetrics)),
+	}
+	for i, start := range starts {
+		index[start] = i
+		response.Periods[i] = dto.AnalyticsPeriod{Start: start, Label: periodLabel(start, query.Granularity)}
+	}
+	for _, m := range mewing {
+		if i, ok := index[periodStart(m.Date, query.Granularity)]; ok {
+			response.Periods[i].MewingMinutes += m.MewingMinutes
+		}
+	}
+	scanPeriod := make([]int, len(analyses))
+	for j := range analyses {
+		scanPeriod[j] = index[periodStart(analyses[j].AnalyzedAt, query.Granularity)]
+		response.Periods[scanPeriod[j]].Scans++
+	}
+
+	periodDays := addPeriods(first, query.Granularity, 1).Sub(first).Hours() / 24
+	if query.Granularity == GranularityMonth {
+		periodDays = 365.25 / 12
+	}
+
+	for _, metric := range AnalysisMetrics {
+		ma := dto.MetricAnalytics{Metric: metric, Points: make([]dto.AnalyticsPoint, len(starts))}
+
+		sums := make([]float64, len(starts))
+		xs := make([]float64, len(analyses))
+		ys := make([]float64, len(analyses))
+		var total float64
+		for j := range analyses {
+			score := metricScore(&analyses[j], metric)
+			sums[scanPeriod[j]] += score
+			total += score
+			xs[j] = analyses[j].AnalyzedAt.Sub(first).Hours() / 24
+			ys[j] = score
+			if ma.Best == nil || score > ma.Best.Score {
+				ma.Best = analyticsScan(&analyses[j], score)
+			}
+			if ma.Worst == nil || score < ma.Worst.Score {
+				ma.Worst = analyticsScan(&analyses[j], score)
+			}
+		}
+		if len(analyses) > 0 {
+			avg := round2(total / float64(len(analyses)))
+			ma.Average = &avg
+		}
+
+		var corrScores, corrMinutes []float64
+		for i, period := range response.Periods {
+			if period.Scans > 0 {
+				avg := round2(sums[i] / float64(period.Scans))
+				ma.Points[i].Average = &avg
+				corrScores = append(corrScores, avg)
+				corrMinutes = append(corrMinutes, float64(period.MewingMinutes))
+			}
+			ma.Points[i].MovingAverage = movingAverage(ma.Points, i, query.Window)
+		}
+
+		ma.Trend = linearTrend(xs, ys, periodDays)
+		ma.MewingCorrelation = pearson(corrScores, corrMinutes)
+		response.Metrics = append(response.Metrics, ma)
+	}
+
+	if len(response.Metrics) > 0 {
+		response.Best = response.Metrics[0].Best
+		response.Worst = response.Metrics[0].Worst
+	}
+
+	return response, nil
+}
+
+func analyticsScan(a *models.FaceAnalysis, score float64) *dto.AnalyticsScan {
+	return &dto.AnalyticsScan{AnalysisID: a.ID, Score: score, AnalyzedAt: a.AnalyzedAt}
+}
+
+// movingAverage averages the scanned periods among the window ending at i.
+func movingAverage(points []dto.AnalyticsPoint, i, window int) *float64 {
+	var sum float64
+	var n int
+	for k := i; k >= 0 && k > i-window; k-- {
+		if points[k].Average != nil {
+			sum += *points[k].Average
+			n++
+		}
+	}
+	if n == 0 {
+		return nil
+	}
+	avg := round2(sum / float64(n))
+	return &avg
+}
+
+// linearTrend fits y = a + b·x by least squares, x in days, and reports the
+// slope with its standard error and 95% confidence interval scaled to one
+// period.
+func linearTrend(xs, ys []float64, periodDays float64) dto.TrendStats {
+	n := len(xs)
+	trend := dto.TrendStats{Samples: n, Direction: "flat", Confidence: "insufficient"}
+	if n < 3 {
+		return trend
+	}
+
+	var meanX, meanY float64
+	for i := range xs {
+		meanX += xs[i]
+		meanY += ys[i]
+	}
+	meanX /= float64(n)
+	meanY /= float64(n)
+
+	var sxx, sxy, syy float64
+	for i := range xs {
+		dx, dy := xs[i]-meanX, ys[i]-meanY
+		sxx += dx * dx
+		sxy += dx * dy
+		syy += dy * dy
+	}
+	// All scans at the same moment say nothing about a trend
+	if sxx == 0 {
+		return trend
+	}
+
+	slope := sxy / sxx
+	sse := math.Max(syy-slope*sxy, 0)
+	se := math.Sqrt(sse/float64(n-2)) / math.Sqrt(sxx)
+	margin := tCritical95(n-2) * se
+
+	trend.SlopePerDay = round4(slope)
+	trend.SlopePerPeriod = round4(slope * periodDays)
+	trend.StdError = round4(se * periodDays)
+	trend.CILow = round4((slope - margin) * periodDays)
+	trend.CIHigh = round4((slope + margin) * periodDays)
+	if syy > 0 {
+		trend.RSquared = round4(slope * sxy / syy)
+	}
+
+	switch {
+	case slope > 0:
+		trend.Direction = "up"
+	case slope < 0:
+		trend.Direction = "down"
+	}
+	switch {
+	case se == 0 || slope-margin > 0 || slope+margin < 0:
+		trend.Confidence = "high"
+	case math.Abs(slope) >= se:
+		trend.Confidence = "medium"
+	default:
+		trend.Confidence = "low"
+	}
+	if slope == 0 {
+		trend.Direction = "flat"
+	}
+	return trend
+}
+
+// pearson correlates period score averages with mewing minutes. Significance
+// uses the t-test on r with n-2 degrees of freedom.
+func pearson(xs, ys []float64) *dto.CorrelationStats {
+	n := len(xs)
+	if n < 3 {
+		return nil
+	}
+	var meanX, meanY float64
+	for i := range xs {
+		meanX += xs[i]
+		meanY += ys[i]
+	}
+	meanX /= float64(n)
+	meanY /= float64(n)
+
+	var sxx, syy, sxy float64
+	for i := range xs {
+		dx, dy := xs[i]-meanX, ys[i]-meanY
+		sxx += dx * dx
+		syy += dy * dy
+		sxy += dx * dy
+	}
+	if sxx == 0 || syy == 0 {
+		return nil
+	}
+
+	r := sxy / math.Sqrt(sxx*syy)
+	corr := &dto.CorrelationStats{R: round4(r), Periods: n, Confidence: "low"}
+	if math.Abs(r) >= 1 {
+		corr.Confidence = "high"
+		return corr
+	}
+	t := math.Abs(r) * math.Sqrt(float64(n-2)/(1-r*r))
+	switch {
+	case t >= tCritical95(n-2):
+		corr.Confidence = "high"
+	case math.Abs(r) >= 0.3:
+		corr.Confidence = "medium"
+	}
+	return corr
+}
+
+// tCritical95 is the two-sided 95% critical value of Student's t.
+func tCritical95(df int) float64 {
+	table := []float64{12.706, 4.303, 3.182, 2.776, 2.571, 2.447, 2.365, 2.306, 2.262, 2.228,
+		2.201, 2.179, 2.160, 2.145, 2.131, 2.120, 2.110, 2.101, 2.093, 2.086,
+		2.080, 2.074, 2.069, 2.064, 2.060, 2.056, 2.052, 2.048, 2.045, 2.042}
+	switch {
+	case df < 1:
+		return math.Inf(1)
+	case df <= len(table):
+		return table[df-1]
+	case df <= 60:
+		return 2.000
+	case df <= 120:
+		return 1.980
+	}
+	return 1.960
+}
+
+// periodStart truncates t (in UTC) to the start of its day, ISO week (Monday)
+// or month.
+func periodStart(t time.Time, granularity string) time.Time {
+	t = t.UTC()
+	day := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+	switch granularity {
+	case GranularityWeek:
+		offset := (int(day.Weekday()) + 6) % 7
+		return day.AddDate(0, 0, -offset)
+	case GranularityMonth:
+		return time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, time.UTC)
+	}
+	return day
+}
+
+func addPeriods(t time.Time, granularity string, n int) time.Time {
+	switch granularity {
+	case GranularityWeek:
+		return t.AddDate(0, 0, 7*n)
+	case GranularityMonth:
+		return t.AddDate(0, n, 0)
+	}
+	return t.AddDate(0, 0, n)
+}
+
+func periodLabel(start time.Time, granularity string) string {
+	switch granularity {
+	case GranularityWeek:
+		year, week := start.ISOWeek()
+		return fmt.Sprintf("%d-W%02d", year, week)
+	case GranularityMonth:
+		return start.Format("2006-01")
+	}
+	return start.Format("2006-01-02")
+}
+
+func round2(v float64) float64 {
+	return math.Round(v*100) / 100
+}
+
+func round4(v float64) float64 {
+	return math.Round(v*10000) / 10000
+}
@@ -0,0 +1,182 @@
+package services
+
+import (
+	"errors"
+	"math"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/ahmetcoskunkizilkaya/mewify/backend/internal/dto"
+	"github.com/ahmetcoskunkizilkaya/mewify/backend/internal/models"
+)
+
+// AnalysisMetrics are the eight scored metrics, in display order.
+var AnalysisMetrics = []string{"overall", "symmetry", "jawline", "skin", "eye", "nose", "lips", "harmony"}
+
+// setMetricScore sets one of AnalysisMetrics on a.
+func setMetricScore(a *models.FaceAnalysis, metric string, v float64) {
+	switch metric {
+	case "overall":
+		a.OverallScore = v
+	case "symmetry":
+		a.SymmetryScore = v
+	case "jawline":
+		a.JawlineScore = v
+	case "skin":
+		a.SkinScore = v
+	case "eye":
+		a.EyeScore = v
+	case "nose":
+		a.NoseScore = v
+	case "lips":
+		a.LipsScore = v
+	case "harmony":
+		a.HarmonyScore = v
+	}
+}
+
+var ErrSameAnalysis = errors.New("cannot compare an analysis with itself")
+
+// metricScore returns one of AnalysisMetrics from a.
+func metricScore(a *models.FaceAnalysis, metric string) float64 {
+	switch metric {
+	case "overall":
+		return a.OverallScore
+	case "symmetry":
+		return a.SymmetryScore
+	case "jawline":
+		return a.JawlineScore
+	case "skin":
+		return a.SkinScore
+	case "eye":
+		return a.EyeScore
+	case "nose":
+		return a.NoseScore
+	case "lips":
+		return a.LipsScore
+	case "harmony":
+		return a.HarmonyScore
+	}
+	return 0
+}
+
+// CompareAnalyses diffs two of a user's analyses: per-metric deltas, the
+// strengths and improvements that came and went, and the mewing logged
+// between the two scans.
+func (s *faceAnalysisService) CompareAnalyses(userID, fromID, toID uuid.UUID) (*dto.AnalysisComparisonResponse, error) {
+	if fromID == toID {
+		return nil, ErrSameAnalysis
+	}
+	from, err := s.GetAnalysisByID(fromID, userID)
+	if err != nil {
+		return nil, err
+	}
+	to, err := s.GetAnalysisByID(toID, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	cmp := &dto.AnalysisComparisonResponse{
+		From:        s.toResponse(from),
+		To:          s.toResponse(to),
+		Metrics:     make([]dto.MetricDelta, 0, len(AnalysisMetrics)),
+		DaysElapsed: daysBetween(from.AnalyzedAt, to.AnalyzedAt),
+	}
+	for _, metric := range AnalysisMetrics {
+		a, b := metricScore(from, metric), metricScore(to, metric)
+		delta := dto.MetricDelta{Metric: metric, From: a, To: b, Delta: round1(b - a)}
+		if a != 0 {
+			pct := round1((b - a) / a * 100)
+			delta.PercentChange = &pct
+		}
+		cmp.Metrics = append(cmp.Metrics, delta)
+	}
+	cmp.StrengthsAdded, cmp.StrengthsRemoved = diffLists(from.Strengths, to.Strengths)
+	cmp.ImprovementsAdded, cmp.ImprovementsRemoved = diffLists(from.Improvements, to.Improvements)
+
+	start, end := from.AnalyzedAt, to.AnalyzedAt
+	if end.Before(start) {
+		start, end = end, start
+	}
+	var mewing struct {
+		Minutes int
+		Days    int
+	}
+	if err := s.db.Model(&models.MewingProgress{}).
+		Select("COALESCE(SUM(mewing_minutes), 0) AS minutes, COUNT(DISTINCT date) AS days").
+		Where("user_id = ? AND date BETWEEN ? AND ?", userID, start.UTC().Format("2006-01-02"), end.UTC().Format("2006-01-02")).
+		Scan(&mewing).Error; err != nil {
+		return nil, err
+	}
+	cmp.MewingMinutes = mewing.Minutes
+	cmp.MewingDaysLogged = mewing.Days
+
+	return cmp, nil
+}
+
+func (s *faceAnalysisService) toResponse(a *models.FaceAnalysis) dto.FaceAnalysisResponse {
+	imageURL := a.ImageURL
+	if a.ImageKey != "" {
+		imageURL = s.blobs.SignedURL(a.ImageKey)
+	}
+	return dto.FaceAnalysisResponse{
+		ID:            a.ID,
+		UserID:        a.UserID,
+		ImageURL:      imageURL,
+		OverallScore:  a.OverallScore,
+		SymmetryScore: a.SymmetryScore,
+		JawlineScore:  a.JawlineScore,
+		SkinScore:     a.SkinScore,
+		EyeScore:      a.EyeScore,
+		NoseScore:     a.NoseScore,
+		LipsScore:     a.LipsScore,
+		HarmonyScore:  a.HarmonyScore,
+		Strengths:     a.Strengths,
+		Improvements:  a.Improvements,
+		Tags:          a.Tags,
+		AnalyzedAt:    a.AnalyzedAt,
+		CreatedAt:     a.CreatedAt,
+		IsEstimate:    a.IsEstimate(),
+		SessionID:     a.SessionID,
+	}
+}
+
+// diffLists returns the entries of after missing from before, and those of
+// before missing from after, ignoring case and surrounding space.
+func diffLists(before, after []string) (added, removed []string) {
+	key := func(s string) string { return strings.ToLower(strings.TrimSpace(s)) }
+	in := func(list []string, s string) bool {
+		for _, v := range list {
+			if key(v) == key(s) {
+				return true
+			}
+		}
+		return false
+	}
+
+	added, removed = []string{}, []string{}
+	for _, s := range after {
+		if key(s) != "" && !in(before, s) {
+			added = append(added, s)
+		}
+	}
+	for _, s := range before {
+		if key(s) != "" && !in(after, s) {
+			removed = append(removed, s)
+		}
+	}
+	return added, removed
+}
+
+// daysBetween counts calendar days (UTC) from a to b.
+func daysBetween(a, b time.Time) int {
+	da := time.Date(a.UTC().Year(), a.UTC().Month(), a.UTC().Day(), 0, 0, 0, 0, time.UTC)
+	db := time.Date(b.UTC().Year(), b.UTC().Month(), b.UTC().Day(), 0, 0, 0, 0, time.UTC)
+	return int(math.Round(db.Sub(da).Hours() / 24))
+}
+
+func round1(v float64) float64 {
+	return math.Round(v*10) / 10
+}
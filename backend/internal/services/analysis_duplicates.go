@@ -0,0 +1,193 @@
+package services
+
+import (
+	"sort"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/ahmetcoskunkizilkaya/mewify/backend/internal/dto"
+	"github.com/ahmetcoskunkizilkaya/mewify/backend/internal/imaging"
+	"github.com/ahmetcoskunkizilkaya/mewify/backend/internal/models"
+)
+
+// Duplicate report reasons
+const (
+	DuplicateReasonSharedPhoto = "shared_photo"
+	DuplicateReasonEditedPhoto = "edited_photo"
+)
+
+const (
+	duplicateCandidateLimit = 50
+	duplicateReportLimit    = 5000
+)
+
+// findDuplicate returns a job for a photo the user submitted inside the
+// duplicate window: the pending job scoring it, or a new job already
+// completed with the earlier analysis. Neither consumes quota. It returns nil
+// when the photo is new.
+func (s *AnalysisJobService) findDuplicate(userID uuid.UUID, key *string, img *imaging.Image, now time.Time) (*models.AnalysisJob, error) {
+	if s.duplicateWindow <= 0 {
+		return nil, nil
+	}
+	since := now.Add(-s.duplicateWindow)
+
+	// A double-tap while the first upload is still being scored
+	var pending []models.AnalysisJob
+	if err := s.db.Where("user_id = ? AND status IN ? AND created_at >= ?",
+		userID, []models.AnalysisJobStatus{models.AnalysisJobQueued, models.AnalysisJobRunning}, since).
+		Order("created_at DESC").
+		Limit(duplicateCandidateLimit).
+		Find(&pending).Error; err != nil {
+		return nil, err
+	}
+	for i := range pending {
+		if s.isDuplicate(img, pending[i].ImageSHA256, pending[i].ImagePHash) {
+			return &pending[i], nil
+		}
+	}
+
+	var analyses []models.FaceAnalysis
+	if err := s.db.Where("user_id = ? AND created_at >= ? AND image_phash <> ''", userID, since).
+		Order("created_at DESC").
+		Limit(duplicateCandidateLimit).
+		Find(&analyses).Error; err != nil {
+		return nil, err
+	}
+
+	var match *models.FaceAnalysis
+	best := s.duplicateMaxDistance + 1
+	for i := range analyses {
+		if analyses[i].ImageSHA256 == img.SHA256 {
+			match = &analyses[i]
+			break
+		}
+		hash, err := imaging.ParsePHash(analyses[i].ImagePHash)
+		if err != nil {
+			continue
+		}
+		if d := img.PHash.Distance(hash); d < best {
+			best = d
+			match = &analyses[i]
+		}
+	}
+	if match == nil {
+		return nil, nil
+	}
+
+	job := &models.AnalysisJob{
+		UserID:         userID,
+		IdempotencyKey: key,
+		Status:         models.AnalysisJobSucceeded,
+		ImageSHA256:    img.SHA256,
+		ImagePHash:     img.PHash.String(),
+		UsageDate:      now.UTC().Truncate(24 * time.Hour),
+		NextAttemptAt:  now,
+		AnalysisID:     &match.ID,
+		Cached:         true,
+		CompletedAt:    &now,
+	}
+	if err := s.db.Create(job).Error; err != nil {
+		return nil, err
+	}
+	job.Analysis = match
+	return job, nil
+}
+
+func (s *AnalysisJobService) isDuplicate(img *imaging.Image, sha, phash string) bool {
+	if sha != "" && sha == img.SHA256 {
+		return true
+	}
+	hash, err := imaging.ParsePHash(phash)
+	if err != nil {
+		return false
+	}
+	return img.PHash.Distance(hash) <= s.duplicateMaxDistance
+}
+
+// DuplicateReport flags photos that look reused: the same picture scored by
+// several accounts (stock or borrowed photos), and one user's photo scored
+// again after small edits. maxDistance is the perceptual-hash distance that
+// still counts as the same picture.
+func (s *faceAnalysisService) DuplicateReport(since time.Time, maxDistance int) ([]dto.DuplicateReportEntry, error) {
+	var analyses []models.FaceAnalysis
+	if err := s.db.Select("id, user_id, image_sha256, image_phash, created_at").
+		Where("created_at >= ? AND image_phash <> ''", since).
+		Order("created_at DESC").
+		Limit(duplicateReportLimit).
+		Find(&analyses).Error; err != nil {
+		return nil, err
+	}
+
+	hashes := make([]imaging.PHash, len(analyses))
+	valid := make([]bool, len(analyses))
+	for i, a := range analyses {
+		if h, err := imaging.ParsePHash(a.ImagePHash); err == nil {
+			hashes[i], valid[i] = h, true
+		}
+	}
+
+	entries := make(map[string]*dto.DuplicateReportEntry)
+	flag := func(reason string, a, b models.FaceAnalysis, distance int) {
+		key := reason + ":" + a.UserID.String()
+		entry, ok := entries[key]
+		if !ok {
+			entry = &dto.DuplicateReportEntry{UserID: a.UserID, Reason: reason, MinDistance: distance}
+			entries[key] = entry
+		}
+		entry.AnalysisIDs = appendUniqueID(entry.AnalysisIDs, a.ID)
+		entry.AnalysisIDs = appendUniqueID(entry.AnalysisIDs, b.ID)
+		if b.UserID != a.UserID {
+			entry.MatchedUserIDs = appendUniqueID(entry.MatchedUserIDs, b.UserID)
+		}
+		if distance < entry.MinDistance {
+			entry.MinDistance = distance
+		}
+	}
+
+	for i := range analyses {
+		if !valid[i] {
+			continue
+		}
+		for j := i + 1; j < len(analyses); j++ {
+			if !valid[j] {
+				continue
+			}
+			d := hashes[i].Distance(hashes[j])
+			if d > maxDistance {
+				continue
+			}
+			a, b := analyses[i], analyses[j]
+			switch {
+			case a.UserID != b.UserID:
+				flag(DuplicateReasonSharedPhoto, a, b, d)
+				flag(DuplicateReasonSharedPhoto, b, a, d)
+			case a.ImageSHA256 != b.ImageSHA256 && d > 0:
+				// Same user, same picture, different pixels
+				flag(DuplicateReasonEditedPhoto, a, b, d)
+			}
+		}
+	}
+
+	report := make([]dto.DuplicateReportEntry, 0, len(entries))
+	for _, entry := range entries {
+		entry.Count = len(entry.AnalysisIDs)
+		report = append(report, *entry)
+	}
+	sort.Slice(report, func(i, j int) bool {
+		if report[i].Count != report[j].Count {
+			return report[i].Count > report[j].Count
+		}
+		return report[i].UserID.String() < report[j].UserID.String()
+	})
+	return report, nil
+}
+
+func appendUniqueID(ids []uuid.UUID, id uuid.UUID) []uuid.UUID {
+	for _, existing := range ids {
+		if existing == id {
+			return ids
+		}
+	}
+	return append(ids, id)
+}
@@ -0,0 +1,516 @@
+package services
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"github.com/ahmetcoskunkizilkaya/mewify/backend/internal/config"
+	"github.com/ahmetcoskunkizilkaya/mewify/backend/internal/imaging"
+	"github.com/ahmetcoskunkizilkaya/mewify/backend/internal/models"
+)
+
+const (
+	analysisJobBaseBackoff = 5 * time.Second
+	analysisJobMaxBackoff  = 2 * time.Minute
+)
+
+var (
+	ErrAnalysisJobNotFound = errors.New("analysis job not found")
+	ErrInvalidScanAngle    = errors.New("angle must be front, left_profile or right_profile")
+	ErrFrontPhotoRequired  = errors.New("a front photo is required")
+
+	// errAnalysisJobLost means another worker took over the job after this
+	// one's lease lapsed, so this worker's result is discarded.
+	errAnalysisJobLost = errors.New("analysis job lease lost")
+)
+
+// AnalysisJobService queues AI analyses in the database and runs them on a
+// bounded pool of workers. Scan quota is reserved at submission and settled
+// when the job succeeds (see UsageService.Settle) or released when it fails
+// for good.
+type AnalysisJobService struct {
+	db          *gorm.DB
+	ai          *AiAnalysisService
+	calibration *CalibrationService
+	usage       *UsageService
+	images      *imaging.Pipeline
+	quality     *imaging.QualityGate // nil disables the pre-scoring check
+	blobs       *BlobService
+	workers     int
+	maxAttempts int
+	lease       time.Duration
+
+	duplicateWindow      time.Duration
+	duplicateMaxDistance int
+
+	wake     chan struct{}
+	progress *progressHub
+}
+
+func NewAnalysisJobService(db *gorm.DB, ai *AiAnalysisService, calibration *CalibrationService, usage *UsageService, images *imaging.Pipeline, quality *imaging.QualityGate, blobs *BlobService, cfg *config.Config) *AnalysisJobService {
+	s := &AnalysisJobService{
+		db:          db,
+		ai:          ai,
+		calibration: calibration,
+		usage:       usage,
+		images:      images,
+		quality:     quality,
+		blobs:       blobs,
+		workers:     cfg.AnalysisWorkers,
+		maxAttempts: cfg.AnalysisJobMaxAttempts,
+		lease:       cfg.AnalysisJobLease,
+		wake:        make(chan struct{}, 1),
+		progress:    newProgressHub(),
+
+		duplicateWindow:      cfg.DuplicateWindow,
+		duplicateMaxDistance: cfg.DuplicateMaxDistance,
+	}
+	if s.workers <= 0 {
+		s.workers = 1
+	}
+	if s.maxAttempts <= 0 {
+		s.maxAttempts = 1
+	}
+	if s.lease <= 0 {
+		s.lease = 5 * time.Minute
+	}
+	return s
+}
+
+// Submit validates and normalizes the photo, queues an analysis and reserves
+// one scan of the user's quota. Photos the ingestion pipeline rejects return
+// its imaging error and cost nothing. A repeated idempotency key returns the
+// job created the first time without charging again, so clients can safely
+// retry a submission, and a near-identical photo the user submitted recently
+// returns that job or its analysis instead of scoring again. The wording is
+// written in the user's stored language, else locale.
+func (s *AnalysisJobService) Submit(userID uuid.UUID, imageBase64, idempotencyKey, locale string) (*models.AnalysisJob, error) {
+	return s.SubmitSession(userID, map[string]string{models.ScanAngleFront: imageBase64}, idempotencyKey, locale)
+}
+
+// SubmitSession queues a multi-angle scan: a front photo and optionally one
+// or both profiles, keyed by angle. The whole session costs one scan and
+// yields one summary analysis.
+func (s *AnalysisJobService) SubmitSession(userID uuid.UUID, images map[string]string, idempotencyKey, locale string) (*models.AnalysisJob, error) {
+	for angle := range images {
+		if !isScanAngle(angle) {
+			return nil, ErrInvalidScanAngle
+		}
+	}
+	if images[models.ScanAngleFront] == "" {
+		return nil, ErrFrontPhotoRequired
+	}
+
+	var key *string
+	if k := strings.TrimSpace(idempotencyKey); k != "" {
+		key = &k
+		if job, err := s.findByIdempotencyKey(userID, k); err == nil {
+			return job, nil
+		} else if !errors.Is(err, ErrAnalysisJobNotFound) {
+			return nil, err
+		}
+	}
+
+	processed := make(map[string]*imaging.Image, len(images))
+	for _, angle := range models.ScanAngles {
+		encoded, ok := images[angle]
+		if !ok {
+			continue
+		}
+		img, err := s.images.ProcessBase64(encoded)
+		// Rejected before anything is reserved, so a retake costs nothing
+		if err == nil && s.quality != nil {
+			_, err = s.quality.Check(img)
+		}
+		if err != nil {
+			if len(images) > 1 {
+				err = &ScanImageError{Angle: angle, Err: err}
+			}
+			return nil, err
+		}
+		processed[angle] = img
+	}
+	front := processed[models.ScanAngleFront]
+
+	now := time.Now()
+	// Only single photos are answered from an earlier scan; a session's
+	// profile shots are always scored
+	if len(processed) == 1 {
+		if duplicate, err := s.findDuplicate(userID, key, front, now); err != nil || duplicate != nil {
+			return duplicate, err
+		}
+	}
+
+	job := &models.AnalysisJob{
+		UserID:         userID,
+		IdempotencyKey: key,
+		Status:         models.AnalysisJobQueued,
+		ImageBase64:    front.Base64(),
+		ImageSHA256:    front.SHA256,
+		ImagePHash:     front.PHash.String(),
+		Locale:         userLocale(s.db, userID, locale),
+		UsageReserved:  true,
+		NextAttemptAt:  now,
+	}
+	for angle, img := range processed {
+		if angle == models.ScanAngleFront {
+			continue
+		}
+		if job.ProfileImages == nil {
+			job.ProfileImages = make(map[string]string)
+		}
+		job.ProfileImages[angle] = img.Base64()
+	}
+
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		date, err := s.usage.withDB(tx).Reserve(userID)
+		if err != nil {
+			return err
+		}
+		job.UsageDate = date
+		return tx.Create(job).Error
+	})
+	if err != nil {
+		// A concurrent retry with the same key won the insert
+		if key != nil && !errors.Is(err, ErrUsageLimitReached) {
+			if existing, findErr := s.findByIdempotencyKey(userID, *key); findErr == nil {
+				return existing, nil
+			}
+		}
+		return nil, err
+	}
+
+	select {
+	case s.wake <- struct{}{}:
+	default:
+	}
+	return job, nil
+}
+
+// GetJob returns a user's job, with its analysis once it has succeeded.
+func (s *AnalysisJobService) GetJob(jobID, userID uuid.UUID) (*models.AnalysisJob, error) {
+	var job models.AnalysisJob
+	err := s.db.Preload("Analysis").
+		Where("id = ? AND user_id = ?", jobID, userID).
+		First(&job).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrAnalysisJobNotFound
+		}
+		return nil, err
+	}
+	return &job, nil
+}
+
+// Subscribe streams the stages of a job processed by this instance until
+// the returned cancel function is called. Jobs taken by workers in other
+// instances publish nothing here; their status is only visible in the
+// database.
+func (s *AnalysisJobService) Subscribe(jobID uuid.UUID) (<-chan AnalysisJobEvent, func()) {
+	return s.progress.subscribe(jobID)
+}
+
+func (s *AnalysisJobService) findByIdempotencyKey(userID uuid.UUID, key string) (*models.AnalysisJob, error) {
+	var job models.AnalysisJob
+	err := s.db.Where("user_id = ? AND idempotency_key = ?", userID, key).First(&job).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrAnalysisJobNotFound
+		}
+		return nil, err
+	}
+	return &job, nil
+}
+
+// Run starts the worker pool and blocks until ctx is cancelled and every
+// in-flight job has finished. Jobs already running are allowed to complete so
+// a shutdown never turns a pending AI result into a fallback one.
+func (s *AnalysisJobService) Run(ctx context.Context, interval time.Duration) {
+	var wg sync.WaitGroup
+	for i := 0; i < s.workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			s.work(ctx, interval)
+		}()
+	}
+	wg.Wait()
+}
+
+func (s *AnalysisJobService) work(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		for ctx.Err() == nil {
+			job, err := s.claimNext()
+			if err != nil {
+				log.Printf("Analysis job claim failed: %v", err)
+				break
+			}
+			if job == nil {
+				break
+			}
+			s.process(job)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-s.wake:
+		case <-ticker.C:
+		}
+	}
+}
+
+// claimNext takes the oldest due job, or a running one whose worker let its
+// lease lapse, and marks it running under a fresh lease.
+func (s *AnalysisJobService) claimNext() (*models.AnalysisJob, error) {
+	var claimed *models.AnalysisJob
+
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		now := time.Now()
+		var jobs []models.AnalysisJob
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE", Options: "SKIP LOCKED"}).
+			Where("(status = ? AND next_attempt_at <= ?) OR (status = ? AND lease_expires_at < ?)",
+				models.AnalysisJobQueued, now, models.AnalysisJobRunning, now).
+			Order("next_attempt_at, created_at").
+			Limit(1).
+			Find(&jobs).Error; err != nil {
+			return err
+		}
+		if len(jobs) == 0 {
+			return nil
+		}
+
+		job := jobs[0]
+		lease := now.Add(s.lease)
+		if err := tx.Model(&job).Updates(map[string]interface{}{
+			"status":           models.AnalysisJobRunning,
+			"attempts":         job.Attempts + 1,
+			"lease_expires_at": lease,
+			"started_at":       now,
+		}).Error; err != nil {
+			return err
+		}
+		job.Status = models.AnalysisJobRunning
+		job.Attempts++
+		job.LeaseExpiresAt = &lease
+		job.StartedAt = &now
+		claimed = &job
+		return nil
+	})
+
+	return claimed, err
+}
+
+func (s *AnalysisJobService) process(job *models.AnalysisJob) {
+	defer s.holdLease(job)()
+
+	// Scoring runs detached from the pool's context so a shutdown lets the
+	// LLM call finish instead of falling back mid-flight.
+	s.progress.publish(AnalysisJobEvent{JobID: job.ID, Stage: AnalysisStageScoring, Attempt: job.Attempts})
+	scoringCtx := s.progress.scoringContext(context.Background(), job.ID)
+	analysis := s.ai.scoreImage(scoringCtx, job.UserID, job.ImageBase64, job.Locale)
+	analysis.ID = uuid.New()
+	analysis.ImageSHA256 = job.ImageSHA256
+	analysis.ImagePHash = job.ImagePHash
+
+	// The photo is stored before the analysis so the entry never points at a
+	// missing image; it is discarded again if the analysis is not saved.
+	data, err := base64.StdEncoding.DecodeString(job.ImageBase64)
+	if err != nil {
+		s.fail(job, fmt.Errorf("stored image is not valid base64: %w", err))
+		return
+	}
+	blob, err := s.blobs.upload(context.Background(), job.UserID, models.BlobOwnerFaceAnalysis, analysis.ID, "analyses", imaging.ContentType, data)
+	if err != nil {
+		s.fail(job, fmt.Errorf("failed to store image: %w", err))
+		return
+	}
+	analysis.ImageKey = blob.Key
+	blobs := []*models.Blob{blob}
+
+	// Profile shots of a multi-angle session adjust the summary scores, so
+	// they are scored before the analysis is calibrated and saved
+	var session *models.ScanSession
+	if len(job.ProfileImages) > 0 {
+		var profileBlobs []*models.Blob
+		session, profileBlobs, err = s.scoreSession(scoringCtx, job, analysis)
+		if err != nil {
+			s.blobs.discard(blob)
+			s.fail(job, err)
+			return
+		}
+		blobs = append(blobs, profileBlobs...)
+	}
+	s.calibration.Apply(analysis)
+
+	s.progress.publish(AnalysisJobEvent{JobID: job.ID, Stage: AnalysisStageSaving})
+	err = s.db.Transaction(func(tx *gorm.DB) error {
+		if err := s.ai.saveAnalysis(tx, analysis); err != nil {
+			return err
+		}
+		if session != nil {
+			if err := tx.Omit("Analysis").Create(session).Error; err != nil {
+				return fmt.Errorf("failed to save scan session: %w", err)
+			}
+		}
+		for _, blob := range blobs {
+			if err := s.blobs.attach(tx, blob); err != nil {
+				return err
+			}
+		}
+
+		charged := false
+		if job.UsageReserved {
+			var err error
+			if charged, err = s.usage.withDB(tx).Settle(job.UserID, job.UsageDate, analysis); err != nil {
+				return fmt.Errorf("failed to settle usage: %w", err)
+			}
+		}
+
+		now := time.Now()
+		result := tx.Model(&models.AnalysisJob{}).
+			Where("id = ? AND status = ? AND attempts = ?", job.ID, models.AnalysisJobRunning, job.Attempts).
+			Updates(map[string]interface{}{
+				"status":           models.AnalysisJobSucceeded,
+				"analysis_id":      analysis.ID,
+				"image_base64":     "",
+				"profile_images":   nil,
+				"usage_reserved":   false,
+				"usage_charged":    charged,
+				"lease_expires_at": nil,
+				"last_error":       "",
+				"completed_at":     now,
+			})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			// Rolls back the settlement; the worker holding the job settles it
+			return errAnalysisJobLost
+		}
+		return nil
+	})
+	if err == nil {
+		s.progress.publish(AnalysisJobEvent{JobID: job.ID, Stage: AnalysisStageDone})
+		return
+	}
+	s.discardAll(blobs)
+	if errors.Is(err, errAnalysisJobLost) {
+		return
+	}
+	s.fail(job, err)
+}
+
+// holdLease renews the job's lease every third of its length until the
+// returned stop is called. A session's several LLM calls, with retries and
+// failover, can outlast one lease, and another worker reclaiming the job
+// would pay for them again. Renewal ends once the job is no longer this
+// worker's to hold.
+func (s *AnalysisJobService) holdLease(job *models.AnalysisJob) (stop func()) {
+	done := make(chan struct{})
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		ticker := time.NewTicker(s.lease / 3)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-done:
+				return
+			case <-ticker.C:
+			}
+			result := s.db.Model(&models.AnalysisJob{}).
+				Where("id = ? AND status = ? AND attempts = ?", job.ID, models.AnalysisJobRunning, job.Attempts).
+				Update("lease_expires_at", time.Now().Add(s.lease))
+			if result.Error != nil {
+				log.Printf("Analysis job %s: failed to renew lease: %v", job.ID, result.Error)
+				continue
+			}
+			if result.RowsAffected == 0 {
+				return
+			}
+		}
+	}()
+	return func() {
+		close(done)
+		wg.Wait()
+	}
+}
+
+func (s *AnalysisJobService) fail(job *models.AnalysisJob, cause error) {
+	if err := s.recordFailure(job, cause); err != nil {
+		log.Printf("Analysis job %s: failed to record failure: %v", job.ID, err)
+		return
+	}
+	stage := AnalysisStageRetrying
+	if job.Attempts >= s.maxAttempts {
+		stage = AnalysisStageFailed
+	}
+	s.progress.publish(AnalysisJobEvent{JobID: job.ID, Stage: stage, Attempt: job.Attempts})
+}
+
+// recordFailure requeues the job with backoff, or marks it failed and releases
+// its reserved scan once it has used all of its attempts.
+func (s *AnalysisJobService) recordFailure(job *models.AnalysisJob, cause error) error {
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		updates := map[string]interface{}{
+			"last_error":       cause.Error(),
+			"lease_expires_at": nil,
+		}
+
+		final := job.Attempts >= s.maxAttempts
+		if final {
+			now := time.Now()
+			updates["status"] = models.AnalysisJobFailed
+			updates["image_base64"] = ""
+			updates["profile_images"] = nil
+			updates["usage_reserved"] = false
+			updates["completed_at"] = now
+			log.Printf("Analysis job %s failed after %d attempts: %v", job.ID, job.Attempts, cause)
+		} else {
+			updates["status"] = models.AnalysisJobQueued
+			updates["next_attempt_at"] = time.Now().Add(analysisJobBackoff(job.Attempts))
+		}
+
+		result := tx.Model(&models.AnalysisJob{}).
+			Where("id = ? AND status = ? AND attempts = ?", job.ID, models.AnalysisJobRunning, job.Attempts).
+			Updates(updates)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 || !final || !job.UsageReserved {
+			return nil
+		}
+
+		if err := s.usage.withDB(tx).Release(job.UserID, job.UsageDate); err != nil {
+			return fmt.Errorf("failed to release usage: %w", err)
+		}
+		return nil
+	})
+}
+
+func analysisJobBackoff(attempts int) time.Duration {
+	backoff := analysisJobBaseBackoff
+	for i := 1; i < attempts; i++ {
+		backoff *= 2
+		if backoff >= analysisJobMaxBackoff {
+			return analysisJobMaxBackoff
+		}
+	}
+	return backoff
+}
@@ -0,0 +1,271 @@
+package services
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+
+	"github.com/ahmetcoskunkizilkaya/mewify/backend/internal/dto"
+	"github.com/ahmetcoskunkizilkaya/mewify/backend/internal/models"
+)
+
+// Sort keys and orders of the analyses list besides the metric names
+const (
+	SortAnalyzedAt = "analyzed_at"
+	OrderAsc       = "asc"
+	OrderDesc      = "desc"
+)
+
+const (
+	defaultAnalysisPageSize = 10
+	maxAnalysisPageSize     = 100
+	maxAnalysisTags         = 10
+	maxAnalysisTagLength    = 32
+)
+
+var (
+	ErrInvalidCursor         = errors.New("invalid cursor")
+	ErrInvalidSort           = errors.New("sort must be analyzed_at or a metric, order asc or desc")
+	ErrInvalidAnalysisFilter = errors.New("invalid analysis filter")
+	ErrInvalidTags           = errors.New("at most 10 tags of up to 32 characters each")
+)
+
+// analysisCursor is the position after the last analysis of a page: its
+// sort value and ID, which breaks ties. It is handed out base64-encoded and
+// only valid for the sort it was issued for.
+type analysisCursor struct {
+	Sort  string     `json:"s"`
+	Order string     `json:"o"`
+	Time  *time.Time `json:"t,omitempty"`
+	Score *float64   `json:"v,omitempty"`
+	ID    uuid.UUID  `json:"id"`
+}
+
+func (c analysisCursor) encode() string {
+	data, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+func decodeAnalysisCursor(encoded, sort, order string) (*analysisCursor, error) {
+	data, err := base64.RawURLEncoding.DecodeString(encoded)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+	var c analysisCursor
+	if err := json.Unmarshal(data, &c); err != nil || c.ID == uuid.Nil {
+		return nil, ErrInvalidCursor
+	}
+	if c.Sort != sort || c.Order != order {
+		return nil, fmt.Errorf("%w: issued for sort=%s order=%s", ErrInvalidCursor, c.Sort, c.Order)
+	}
+	if (sort == SortAnalyzedAt && c.Time == nil) || (sort != SortAnalyzedAt && c.Score == nil) {
+		return nil, ErrInvalidCursor
+	}
+	return &c, nil
+}
+
+// ListAnalyses returns a page of the user's analyses in keyset order: by the
+// sort column, then ID, so pages stay consistent while scans are added or
+// deleted. Newest first unless asked otherwise; the total is only counted
+// when query.IncludeTotal is set.
+func (s *faceAnalysisService) ListAnalyses(userID uuid.UUID, query dto.AnalysisListQuery) (*dto.AnalysisListResponse, error) {
+	sort, order, column, err := analysisSort(query.Sort, query.Order)
+	if err != nil {
+		return nil, err
+	}
+	filter, err := analysisFilter(userID, query)
+	if err != nil {
+		return nil, err
+	}
+
+	limit := query.Limit
+	if limit < 1 || limit > maxAnalysisPageSize {
+		limit = defaultAnalysisPageSize
+	}
+
+	resp := &dto.AnalysisListResponse{
+		Analyses: []dto.FaceAnalysisResponse{},
+		Limit:    limit,
+		Sort:     sort,
+		Order:    order,
+	}
+
+	if query.IncludeTotal {
+		var total int64
+		if err := s.db.Model(&models.FaceAnalysis{}).Scopes(filter).Count(&total).Error; err != nil {
+			return nil, err
+		}
+		resp.Total = &total
+	}
+
+	page := s.db.Scopes(filter)
+	if query.Cursor != "" {
+		cursor, err := decodeAnalysisCursor(query.Cursor, sort, order)
+		if err != nil {
+			return nil, err
+		}
+		var value interface{}
+		if sort == SortAnalyzedAt {
+			value = *cursor.Time
+		} else {
+			value = *cursor.Score
+		}
+		op := "<"
+		if order == OrderAsc {
+			op = ">"
+		}
+		page = page.Where(fmt.Sprintf("(%s, id) %s (?, ?)", column, op), value, cursor.ID)
+	}
+
+	// One extra row tells whether another page follows
+	var analyses []models.FaceAnalysis
+	if err := page.Order(fmt.Sprintf("%s %s, id %s", column, order, order)).
+		Limit(limit + 1).
+		Find(&analyses).Error; err != nil {
+		return nil, err
+	}
+
+	if len(analyses) > limit {
+		analyses = analyses[:limit]
+		last := analyses[limit-1]
+		next := analysisCursor{Sort: sort, Order: order, ID: last.ID}
+		if sort == SortAnalyzedAt {
+			next.Time = &last.AnalyzedAt
+		} else {
+			score := metricScore(&last, sort)
+			next.Score = &score
+		}
+		resp.NextCursor = next.encode()
+		resp.HasMore = true
+	}
+
+	for i := range analyses {
+		resp.Analyses = append(resp.Analyses, s.toResponse(&analyses[i]))
+	}
+	return resp, nil
+}
+
+// analysisSort validates the sort key and order and returns them with
+// their defaults applied, along with the column to sort on.
+func analysisSort(sort, order string) (string, string, string, error) {
+	if sort == "" {
+		sort = SortAnalyzedAt
+	}
+	if order == "" {
+		order = OrderDesc
+	}
+	if order != OrderAsc && order != OrderDesc {
+		return "", "", "", ErrInvalidSort
+	}
+	if sort == SortAnalyzedAt {
+		return sort, order, "analyzed_at", nil
+	}
+	for _, metric := range AnalysisMetrics {
+		if sort == metric {
+			return sort, order, metric + "_score", nil
+		}
+	}
+	return "", "", "", ErrInvalidSort
+}
+
+// analysisFilter validates the query's filters and returns them as a scope
+// over the user's analyses.
+func analysisFilter(userID uuid.UUID, query dto.AnalysisListQuery) (func(*gorm.DB) *gorm.DB, error) {
+	if !query.From.IsZero() && !query.To.IsZero() && query.From.After(query.To) {
+		return nil, fmt.Errorf("%w: from is after to", ErrInvalidAnalysisFilter)
+	}
+	if query.MinOverall != nil && query.MaxOverall != nil && *query.MinOverall > *query.MaxOverall {
+		return nil, fmt.Errorf("%w: min_overall is above max_overall", ErrInvalidAnalysisFilter)
+	}
+	switch query.Source {
+	case "", models.AnalysisSourceLLM, models.AnalysisSourceDeterministic, models.AnalysisSourceManual:
+	default:
+		return nil, fmt.Errorf("%w: source must be llm, deterministic or manual", ErrInvalidAnalysisFilter)
+	}
+	var tagFilter string
+	if query.Tag != "" {
+		tag, ok := normalizeTag(query.Tag)
+		if !ok {
+			return nil, fmt.Errorf("%w: %v", ErrInvalidAnalysisFilter, ErrInvalidTags)
+		}
+		data, _ := json.Marshal([]string{tag})
+		tagFilter = string(data)
+	}
+
+	return func(db *gorm.DB) *gorm.DB {
+		db = db.Where("user_id = ?", userID)
+		if !query.From.IsZero() {
+			db = db.Where("analyzed_at >= ?", query.From.UTC())
+		}
+		if !query.To.IsZero() {
+			db = db.Where("analyzed_at < ?", query.To.UTC().AddDate(0, 0, 1))
+		}
+		if query.MinOverall != nil {
+			db = db.Where("overall_score >= ?", *query.MinOverall)
+		}
+		if query.MaxOverall != nil {
+			db = db.Where("overall_score <= ?", *query.MaxOverall)
+		}
+		if query.Source != "" {
+			db = db.Where("source = ?", query.Source)
+		}
+		if tagFilter != "" {
+			db = db.Where("tags @> ?::jsonb", tagFilter)
+		}
+		return db
+	}, nil
+}
+
+// UpdateTags replaces the tags of one of the user's analyses.
+func (s *faceAnalysisService) UpdateTags(analysisID, userID uuid.UUID, tags []string) (*models.FaceAnalysis, error) {
+	tags, err := normalizeTags(tags)
+	if err != nil {
+		return nil, err
+	}
+
+	analysis, err := s.GetAnalysisByID(analysisID, userID)
+	if err != nil {
+		return nil, err
+	}
+	analysis.Tags = tags
+	if err := s.db.Model(analysis).Select("tags").Updates(analysis).Error; err != nil {
+		return nil, err
+	}
+	return analysis, nil
+}
+
+// normalizeTags lowercases and trims tags, dropping blanks and repeats.
+func normalizeTags(tags []string) ([]string, error) {
+	out := make([]string, 0, len(tags))
+	seen := make(map[string]bool, len(tags))
+	for _, raw := range tags {
+		if strings.TrimSpace(raw) == "" {
+			continue
+		}
+		tag, ok := normalizeTag(raw)
+		if !ok {
+			return nil, ErrInvalidTags
+		}
+		if seen[tag] {
+			continue
+		}
+		seen[tag] = true
+		out = append(out, tag)
+	}
+	if len(out) > maxAnalysisTags {
+		return nil, ErrInvalidTags
+	}
+	return out, nil
+}
+
+func normalizeTag(raw string) (string, bool) {
+	tag := strings.ToLower(strings.TrimSpace(raw))
+	return tag, tag != "" && utf8.RuneCountInString(tag) <= maxAnalysisTagLength
+}
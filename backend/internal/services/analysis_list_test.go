@@ -0,0 +1,133 @@
+package services
+
+import (
+	"encoding/base64"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+func TestAnalysisCursorRoundTrip(t *testing.T) {
+	at := time.Date(2026, 3, 14, 9, 26, 53, 0, time.UTC)
+	score := 7.5
+
+	tests := []struct {
+		name   string
+		cursor analysisCursor
+	}{
+		{"analyzed_at", analysisCursor{Sort: SortAnalyzedAt, Order: OrderDesc, Time: &at, ID: uuid.New()}},
+		{"metric", analysisCursor{Sort: "jawline", Order: OrderAsc, Score: &score, ID: uuid.New()}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := decodeAnalysisCursor(tt.cursor.encode(), tt.cursor.Sort, tt.cursor.Order)
+			if err != nil {
+				t.Fatalf("decode = %v", err)
+			}
+			if got.ID != tt.cursor.ID {
+				t.Errorf("id = %s, want %s", got.ID, tt.cursor.ID)
+			}
+			if tt.cursor.Time != nil && (got.Time == nil || !got.Time.Equal(*tt.cursor.Time)) {
+				t.Errorf("time = %v, want %v", got.Time, *tt.cursor.Time)
+			}
+			if tt.cursor.Score != nil && (got.Score == nil || *got.Score != *tt.cursor.Score) {
+				t.Errorf("score = %v, want %v", got.Score, *tt.cursor.Score)
+			}
+		})
+	}
+}
+
+func TestDecodeAnalysisCursorRejects(t *testing.T) {
+	at := time.Now()
+	score := 6.0
+	raw := func(json string) string { return base64.RawURLEncoding.EncodeToString([]byte(json)) }
+
+	tests := []struct {
+		name    string
+		encoded string
+		sort    string
+		order   string
+	}{
+		{"not base64", "not a cursor!", SortAnalyzedAt, OrderDesc},
+		{"not json", raw("{"), SortAnalyzedAt, OrderDesc},
+		{"missing id", raw(`{"s":"analyzed_at","o":"desc","t":"2026-01-01T00:00:00Z"}`), SortAnalyzedAt, OrderDesc},
+		{"other sort", analysisCursor{Sort: "skin", Order: OrderDesc, Score: &score, ID: uuid.New()}.encode(), "eye", OrderDesc},
+		{"other order", analysisCursor{Sort: SortAnalyzedAt, Order: OrderAsc, Time: &at, ID: uuid.New()}.encode(), SortAnalyzedAt, OrderDesc},
+		{"time sort without time", analysisCursor{Sort: SortAnalyzedAt, Order: OrderDesc, Score: &score, ID: uuid.New()}.encode(), SortAnalyzedAt, OrderDesc},
+		{"metric sort without score", analysisCursor{Sort: "eye", Order: OrderDesc, Time: &at, ID: uuid.New()}.encode(), "eye", OrderDesc},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := decodeAnalysisCursor(tt.encoded, tt.sort, tt.order); !errors.Is(err, ErrInvalidCursor) {
+				t.Errorf("decode = %v, want %v", err, ErrInvalidCursor)
+			}
+		})
+	}
+}
+
+func TestAnalysisSort(t *testing.T) {
+	tests := []struct {
+		sort, order string
+		wantSort    string
+		wantOrder   string
+		wantColumn  string
+		wantErr     bool
+	}{
+		{"", "", SortAnalyzedAt, OrderDesc, "analyzed_at", false},
+		{SortAnalyzedAt, OrderAsc, SortAnalyzedAt, OrderAsc, "analyzed_at", false},
+		{"overall", "", "overall", OrderDesc, "overall_score", false},
+		{"harmony", OrderAsc, "harmony", OrderAsc, "harmony_score", false},
+		{"created_at", "", "", "", "", true},
+		{"overall_score", "", "", "", "", true},
+		{"", "sideways", "", "", "", true},
+		{"jawline", "DESC", "", "", "", true},
+	}
+	for _, tt := range tests {
+		sort, order, column, err := analysisSort(tt.sort, tt.order)
+		if tt.wantErr {
+			if !errors.Is(err, ErrInvalidSort) {
+				t.Errorf("analysisSort(%q, %q) error = %v, want %v", tt.sort, tt.order, err, ErrInvalidSort)
+			}
+			continue
+		}
+		if err != nil || sort != tt.wantSort || order != tt.wantOrder || column != tt.wantColumn {
+			t.Errorf("analysisSort(%q, %q) = %q, %q, %q, %v, want %q, %q, %q",
+				tt.sort, tt.order, sort, order, column, err, tt.wantSort, tt.wantOrder, tt.wantColumn)
+		}
+	}
+}
+
+func TestNormalizeTags(t *testing.T) {
+	tests := []struct {
+		name    string
+		tags    []string
+		want    []string
+		wantErr bool
+	}{
+		{"empty", nil, []string{}, false},
+		{"trims and lowercases", []string{"  Morning ", "GYM"}, []string{"morning", "gym"}, false},
+		{"drops blanks and repeats", []string{"a", " ", "A", "b", ""}, []string{"a", "b"}, false},
+		{"tag too long", []string{strings.Repeat("x", maxAnalysisTagLength+1)}, nil, true},
+		{"longest tag", []string{strings.Repeat("ü", maxAnalysisTagLength)}, []string{strings.Repeat("ü", maxAnalysisTagLength)}, false},
+		{"too many tags", []string{"1", "2", "3", "4", "5", "6", "7", "8", "9", "10", "11"}, nil, true},
+		{"repeats do not count", []string{"1", "2", "3", "4", "5", "6", "7", "8", "9", "10", "10"}, []string{"1", "2", "3", "4", "5", "6", "7", "8", "9", "10"}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := normalizeTags(tt.tags)
+			if tt.wantErr {
+				if !errors.Is(err, ErrInvalidTags) {
+					t.Fatalf("normalizeTags = %v, want %v", err, ErrInvalidTags)
+				}
+				return
+			}
+			if err != nil || !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("normalizeTags = %q, %v, want %q", got, err, tt.want)
+			}
+		})
+	}
+}
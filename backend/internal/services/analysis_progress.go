@@ -0,0 +1,113 @@
+package services
+
+import (
+	"context"
+	"regexp"
+	"strconv"
+	"sync"
+
+	"github.com/google/uuid"
+
+	"github.com/ahmetcoskunkizilkaya/mewify/backend/internal/llm"
+)
+
+// Analysis job stages, in the order a job passes through them
+const (
+	AnalysisStageValidated = "image_validated"
+	AnalysisStageQueued    = "queued"
+	AnalysisStageScoring   = "scoring"
+	AnalysisStageAttempt   = "provider_attempt"
+	AnalysisStagePartial   = "partial"
+	AnalysisStageRepair    = "repairing"
+	AnalysisStageSaving    = "saving"
+	AnalysisStageRetrying  = "retrying"
+	AnalysisStageDone      = "done"
+	AnalysisStageFailed    = "failed"
+)
+
+const progressBuffer = 32
+
+// AnalysisJobEvent is one step of a running job, published to listeners
+// in this process.
+type AnalysisJobEvent struct {
+	JobID    uuid.UUID
+	Stage    string
+	Provider string
+	Attempt  int
+	// Scores holds the scores parsed so far from a streaming reply
+	Scores map[string]float64
+}
+
+// progressHub fans job events out to subscribers. Events are best effort:
+// a subscriber that falls behind loses events rather than stalling workers.
+type progressHub struct {
+	mu   sync.Mutex
+	subs map[uuid.UUID]map[chan AnalysisJobEvent]struct{}
+}
+
+func newProgressHub() *progressHub {
+	return &progressHub{subs: make(map[uuid.UUID]map[chan AnalysisJobEvent]struct{})}
+}
+
+func (h *progressHub) subscribe(jobID uuid.UUID) (<-chan AnalysisJobEvent, func()) {
+	ch := make(chan AnalysisJobEvent, progressBuffer)
+	h.mu.Lock()
+	if h.subs[jobID] == nil {
+		h.subs[jobID] = make(map[chan AnalysisJobEvent]struct{})
+	}
+	h.subs[jobID][ch] = struct{}{}
+	h.mu.Unlock()
+
+	return ch, func() {
+		h.mu.Lock()
+		delete(h.subs[jobID], ch)
+		if len(h.subs[jobID]) == 0 {
+			delete(h.subs, jobID)
+		}
+		h.mu.Unlock()
+	}
+}
+
+func (h *progressHub) publish(event AnalysisJobEvent) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for ch := range h.subs[event.JobID] {
+		select {
+		case ch <- event:
+		default:
+		}
+	}
+}
+
+// scoreField matches a finished "<name>_score": <number> pair in a reply
+// still being streamed; the trailing delimiter rules out half-sent numbers.
+var scoreField = regexp.MustCompile(`"([a-z]+_score)"\s*:\s*(-?[0-9]+(?:\.[0-9]+)?)\s*[,}\s]`)
+
+// scoringContext reports provider attempts and partial scores of job's LLM
+// call to the hub.
+func (h *progressHub) scoringContext(ctx context.Context, jobID uuid.UUID) context.Context {
+	seen := 0
+	return llm.WithProgress(ctx, func(p llm.Progress) {
+		switch p.Kind {
+		case llm.ProgressAttempt:
+			seen = 0
+			h.publish(AnalysisJobEvent{JobID: jobID, Stage: AnalysisStageAttempt, Provider: p.Provider, Attempt: p.Attempt})
+		case llm.ProgressRepair:
+			seen = 0
+			h.publish(AnalysisJobEvent{JobID: jobID, Stage: AnalysisStageRepair, Provider: p.Provider})
+		case llm.ProgressDelta:
+			matches := scoreField.FindAllStringSubmatch(p.Content, -1)
+			if len(matches) <= seen {
+				return
+			}
+			seen = len(matches)
+			scores := make(map[string]float64, len(matches))
+			for _, m := range matches {
+				if v, err := strconv.ParseFloat(m[2], 64); err == nil {
+					scores[m[1]] = v
+				}
+			}
+			h.publish(AnalysisJobEvent{JobID: jobID, Stage: AnalysisStagePartial, Provider: p.Provider, Attempt: p.Attempt, Scores: scores})
+		}
+	})
+}
@@ -12,6 +12,7 @@ import (
 
 	"github.com/ahmetcoskunkizilkaya/mewify/backend/internal/config"
 	"github.com/ahmetcoskunkizilkaya/mewify/backend/internal/dto"
+	"github.com/ahmetcoskunkizilkaya/mewify/backend/internal/i18n"
 	"github.com/ahmetcoskunkizilkaya/mewify/backend/internal/models"
 	"github.com/golang-jwt/jwt/v5"
 	"github.com/google/uuid"
@@ -25,15 +26,17 @@ var (
 	ErrInvalidToken       = errors.New("invalid or expired refresh token")
 	ErrUserNotFound       = errors.New("user not found")
 	ErrGuestOnlyAction    = errors.New("guest account required")
+	ErrUnsupportedLocale  = errors.New("locale must be one of en, tr, es")
 )
 
 type AuthService struct {
-	db  *gorm.DB
-	cfg *config.Config
+	db    *gorm.DB
+	cfg   *config.Config
+	blobs *BlobService
 }
 
-func NewAuthService(db *gorm.DB, cfg *config.Config) *AuthService {
-	return &AuthService{db: db, cfg: cfg}
+func NewAuthService(db *gorm.DB, cfg *config.Config, blobs *BlobService) *AuthService {
+	return &AuthService{db: db, cfg: cfg, blobs: blobs}
 }
 
 func (s *AuthService) Register(req *dto.RegisterRequest) (*dto.AuthResponse, error) {
@@ -153,7 +156,7 @@ func (s *AuthService) Logout(req *dto.LogoutRequest) error {
 }
 
 // DeleteAccount implements Apple Guideline 5.1.1(v) - account deletion.
-// Scrubs all user data: tokens, subscriptions, reports, blocks, then soft-deletes user.
+// Scrubs all user data: tokens, subscriptions, reports, blocks, photos, then soft-deletes user.
 func (s *AuthService) DeleteAccount(userID uuid.UUID, password string) error {
 	var user models.User
 	if err := s.db.First(&user, "id = ?", userID).Error; err != nil {
@@ -184,11 +187,42 @@ func (s *AuthService) DeleteAccount(userID uuid.UUID, password string) error {
 		// Remove blocks
 		tx.Where("blocker_id = ? OR blocked_id = ?", userID, userID).Delete(&models.Block{})
 
+		// Remove queued analyses, which still hold their photos
+		if err := tx.Where("user_id = ?", userID).Delete(&models.AnalysisJob{}).Error; err != nil {
+			return err
+		}
+
+		// Delete stored photos once the deletion commits
+		if err := s.blobs.releaseUser(tx, userID); err != nil {
+			return err
+		}
+
 		// Soft-delete the user (GORM DeletedAt)
 		return tx.Delete(&user).Error
 	})
 }
 
+// UpdateLocale stores the user's language preference; an empty locale clears
+// it so the request's Accept-Language applies again.
+func (s *AuthService) UpdateLocale(userID uuid.UUID, locale string) (*dto.UserResponse, error) {
+	locale = strings.TrimSpace(locale)
+	if locale != "" {
+		if locale = i18n.Normalize(locale); locale == "" {
+			return nil, ErrUnsupportedLocale
+		}
+	}
+
+	var user models.User
+	if err := s.db.First(&user, "id = ?", userID).Error; err != nil {
+		return nil, ErrUserNotFound
+	}
+	if err := s.db.Model(&user).Update("locale", locale).Error; err != nil {
+		return nil, err
+	}
+	user.Locale = locale
+	return &dto.UserResponse{ID: user.ID, Email: user.Email, Locale: user.Locale}, nil
+}
+
 // AppleSignIn handles Sign in with Apple (Guideline 4.8).
 // Verifies Apple identity token and creates/finds a user.
 func (s *AuthService) AppleSignIn(req *dto.AppleSignInRequest) (*dto.AuthResponse, error) {
@@ -280,8 +314,9 @@ func (s *AuthService) generateTokenPair(user *models.User) (*dto.AuthResponse, e
 		AccessToken:  accessToken,
 		RefreshToken: refreshToken,
 		User: dto.UserResponse{
-			ID:    user.ID,
-			Email: user.Email,
+			ID:     user.ID,
+			Email:  user.Email,
+			Locale: user.Locale,
 		},
 	}, nil
 }
@@ -0,0 +1,120 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+
+	"github.com/ahmetcoskunkizilkaya/mewify/backend/internal/config"
+	"github.com/ahmetcoskunkizilkaya/mewify/backend/internal/models"
+	"github.com/ahmetcoskunkizilkaya/mewify/backend/internal/storage"
+)
+
+// BlobService ties stored images to the user and entry that own them. Objects
+// are written before the owning transaction and removed through the outbox
+// once the transaction that released them commits, so a rollback never
+// leaves an entry pointing at a deleted image.
+type BlobService struct {
+	db     *gorm.DB
+	store  storage.BlobStore
+	urlTTL time.Duration
+}
+
+func NewBlobService(db *gorm.DB, store storage.BlobStore, cfg *config.Config) *BlobService {
+	ttl := cfg.StorageURLTTL
+	if ttl <= 0 {
+		ttl = 15 * time.Minute
+	}
+	return &BlobService{db: db, store: store, urlTTL: ttl}
+}
+
+// SignedURL returns an expiring URL for key, or "" when there is no object.
+func (s *BlobService) SignedURL(key string) string {
+	if key == "" {
+		return ""
+	}
+	url, err := s.store.SignedURL(context.Background(), key, s.urlTTL)
+	if err != nil {
+		log.Printf("Failed to sign blob URL for %s: %v", key, err)
+		return ""
+	}
+	return url
+}
+
+// upload writes an object under the user's namespace and returns its record,
+// which the caller saves with attach in the owning entry's transaction.
+func (s *BlobService) upload(ctx context.Context, userID uuid.UUID, ownerType string, ownerID uuid.UUID, kind, contentType string, data []byte) (*models.Blob, error) {
+	blob := &models.Blob{
+		ID:          uuid.New(),
+		UserID:      userID,
+		OwnerType:   ownerType,
+		OwnerID:     ownerID,
+		ContentType: contentType,
+		Size:        len(data),
+	}
+	blob.Key = storage.UserKey(userID, kind, blob.ID, ".jpg")
+
+	if err := s.store.Put(ctx, blob.Key, contentType, data); err != nil {
+		return nil, err
+	}
+	return blob, nil
+}
+
+func (s *BlobService) attach(tx *gorm.DB, blob *models.Blob) error {
+	return tx.Create(blob).Error
+}
+
+// discard removes an uploaded object whose owning transaction failed.
+func (s *BlobService) discard(blob *models.Blob) {
+	if err := s.store.Delete(context.Background(), blob.Key); err != nil {
+		log.Printf("Failed to discard orphaned blob %s: %v", blob.Key, err)
+	}
+}
+
+// releaseOwner drops the entry's blobs in tx and schedules their objects for
+// deletion.
+func (s *BlobService) releaseOwner(tx *gorm.DB, ownerType string, ownerID uuid.UUID) error {
+	return s.release(tx, tx.Where("owner_type = ? AND owner_id = ?", ownerType, ownerID))
+}
+
+// releaseUser drops every blob the user owns.
+func (s *BlobService) releaseUser(tx *gorm.DB, userID uuid.UUID) error {
+	return s.release(tx, tx.Where("user_id = ?", userID))
+}
+
+func (s *BlobService) release(tx *gorm.DB, query *gorm.DB) error {
+	var blobs []models.Blob
+	if err := query.Find(&blobs).Error; err != nil {
+		return err
+	}
+	for _, blob := range blobs {
+		if err := enqueueOutbox(tx, OutboxTopicBlobDelete, blob.Key, blobDeletePayload{Key: blob.Key}); err != nil {
+			return err
+		}
+		if err := tx.Delete(&blob).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+type blobDeletePayload struct {
+	Key string `json:"key"`
+}
+
+// RegisterOutboxHandlers registers deletion of released objects.
+func (s *BlobService) RegisterOutboxHandlers(outbox *OutboxService) {
+	outbox.Register(OutboxTopicBlobDelete, s.deliverBlobDelete)
+}
+
+func (s *BlobService) deliverBlobDelete(tx *gorm.DB, payload json.RawMessage) error {
+	var p blobDeletePayload
+	if err := json.Unmarshal(payload, &p); err != nil {
+		return err
+	}
+	return s.store.Delete(context.Background(), p.Key)
+}
@@ -0,0 +1,584 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"math"
+	"sort"
+	"sync"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"github.com/ahmetcoskunkizilkaya/mewify/backend/internal/config"
+	"github.com/ahmetcoskunkizilkaya/mewify/backend/internal/dto"
+	"github.com/ahmetcoskunkizilkaya/mewify/backend/internal/i18n"
+	"github.com/ahmetcoskunkizilkaya/mewify/backend/internal/imaging"
+	"github.com/ahmetcoskunkizilkaya/mewify/backend/internal/llm"
+	"github.com/ahmetcoskunkizilkaya/mewify/backend/internal/models"
+)
+
+// quantileKnots is how many quantiles a quantile mapping matches.
+const quantileKnots = 11
+
+var (
+	ErrCalibrationReferenceExists = errors.New("this image is already in the reference set")
+	ErrInvalidCalibrationMethod   = errors.New("calibration method must be linear or quantile")
+)
+
+type calibrationKey struct {
+	provider string
+	model    string
+}
+
+// CalibrationService puts every score source on one scale. Each provider and
+// model, and the deterministic fallback, scores the same reference images;
+// per metric, a linear or quantile mapping is fitted from each source's raw
+// scores onto the target scale: the consensus (mean of the model providers,
+// leaving out the fallback being calibrated) of every reference image, or one
+// chosen provider's scores. Analyses are calibrated before they are saved,
+// keeping the raw scores alongside.
+type CalibrationService struct {
+	db         *gorm.DB
+	ai         *AiAnalysisService
+	llm        *llm.Client
+	images     *imaging.Pipeline
+	method     string
+	target     string
+	minSamples int
+
+	mu       sync.RWMutex
+	version  int
+	mappings map[calibrationKey]map[string]models.ScoreCalibration
+}
+
+func NewCalibrationService(db *gorm.DB, ai *AiAnalysisService, llmClient *llm.Client, images *imaging.Pipeline, cfg *config.Config) *CalibrationService {
+	s := &CalibrationService{
+		db:         db,
+		ai:         ai,
+		llm:        llmClient,
+		images:     images,
+		method:     cfg.CalibrationMethod,
+		target:     cfg.CalibrationTarget,
+		minSamples: cfg.CalibrationMinSamples,
+		mappings:   make(map[calibrationKey]map[string]models.ScoreCalibration),
+	}
+	if s.minSamples < 2 {
+		s.minSamples = 2
+	}
+	return s
+}
+
+// Load reads the latest calibration version from the database.
+func (s *CalibrationService) Load() error {
+	var version int
+	if err := s.db.Model(&models.ScoreCalibration{}).Select("COALESCE(MAX(version), 0)").Scan(&version).Error; err != nil {
+		return err
+	}
+	var rows []models.ScoreCalibration
+	if err := s.db.Where("version = ?", version).Find(&rows).Error; err != nil {
+		return err
+	}
+
+	mappings := make(map[calibrationKey]map[string]models.ScoreCalibration)
+	for _, row := range rows {
+		if row.Metric == "" {
+			continue
+		}
+		key := calibrationKey{row.Provider, row.Model}
+		if mappings[key] == nil {
+			mappings[key] = make(map[string]models.ScoreCalibration)
+		}
+		mappings[key][row.Metric] = row
+	}
+
+	s.mu.Lock()
+	s.version, s.mappings = version, mappings
+	s.mu.Unlock()
+	return nil
+}
+
+// Watch reloads the calibration whenever another process, such as
+// cmd/calibrate, writes a newer version. Analyses this server saved since
+// that version was written were calibrated with the old one, so they are
+// recalibrated too.
+func (s *CalibrationService) Watch(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		if err := s.reloadIfNewer(); err != nil {
+			log.Printf("Calibration reload failed: %v", err)
+		}
+	}
+}
+
+func (s *CalibrationService) reloadIfNewer() error {
+	var latest struct {
+		Version   int
+		CreatedAt time.Time
+	}
+	if err := s.db.Model(&models.ScoreCalibration{}).
+		Select("version, MIN(created_at) AS created_at").
+		Group("version").
+		Order("version DESC").
+		Limit(1).
+		Scan(&latest).Error; err != nil {
+		return err
+	}
+	s.mu.RLock()
+	current := s.version
+	s.mu.RUnlock()
+	if latest.Version <= current {
+		return nil
+	}
+
+	if err := s.Load(); err != nil {
+		return err
+	}
+	log.Printf("Calibration: loaded version %d", latest.Version)
+	_, err := s.reapply(latest.CreatedAt)
+	return err
+}
+
+// sourceKey identifies the source that scored an analysis; manual entries
+// have none and are never calibrated.
+func sourceKey(a *models.FaceAnalysis) (calibrationKey, bool) {
+	switch a.Source {
+	case models.AnalysisSourceDeterministic:
+		return calibrationKey{provider: models.CalibrationProviderDeterministic}, true
+	case models.AnalysisSourceLLM:
+		return calibrationKey{a.Provider, a.Model}, true
+	}
+	return calibrationKey{}, false
+}
+
+// Apply records a's scores as raw and replaces them with calibrated ones when
+// its source has a mapping. Scores it already calibrated are recalibrated
+// from the raw ones. A nil service leaves a as it is.
+func (s *CalibrationService) Apply(a *models.FaceAnalysis) {
+	if s == nil {
+		return
+	}
+	key, ok := sourceKey(a)
+	if !ok {
+		return
+	}
+	if a.RawScores == nil {
+		a.RawScores = make(map[string]float64, len(AnalysisMetrics))
+		for _, metric := range AnalysisMetrics {
+			a.RawScores[metric] = metricScore(a, metric)
+		}
+	}
+
+	s.mu.RLock()
+	mapping, version := s.mappings[key], s.version
+	s.mu.RUnlock()
+
+	a.CalibrationVersion = 0
+	for _, metric := range AnalysisMetrics {
+		raw := a.RawScores[metric]
+		if m, ok := mapping[metric]; ok {
+			setMetricScore(a, metric, calibrate(m, raw))
+			a.CalibrationVersion = version
+		} else {
+			setMetricScore(a, metric, raw)
+		}
+	}
+}
+
+func calibrate(m models.ScoreCalibration, raw float64) float64 {
+	v := raw
+	switch m.Method {
+	case models.CalibrationLinear:
+		v = m.Slope*raw + m.Intercept
+	case models.CalibrationQuantile:
+		v = interpolateKnots(m.RawKnots, m.TargetKnots, raw)
+	}
+	return clampFloat(round1(v), 1, 10, raw)
+}
+
+// interpolateKnots maps x piecewise linearly between knots; outside them it
+// keeps the offset of the nearest end.
+func interpolateKnots(xs, ys []float64, x float64) float64 {
+	n := len(xs)
+	if n == 0 || n != len(ys) {
+		return x
+	}
+	if x <= xs[0] {
+		return x - xs[0] + ys[0]
+	}
+	if x >= xs[n-1] {
+		return x - xs[n-1] + ys[n-1]
+	}
+	i := sort.SearchFloat64s(xs, x)
+	if xs[i] == x {
+		return ys[i]
+	}
+	t := (x - xs[i-1]) / (xs[i] - xs[i-1])
+	return ys[i-1] + t*(ys[i]-ys[i-1])
+}
+
+// AddReference adds an image to the reference set. It is scored by every
+// source on the next recompute.
+func (s *CalibrationService) AddReference(label, imageBase64 string) (*models.CalibrationReference, error) {
+	img, err := s.images.ProcessBase64(imageBase64)
+	if err != nil {
+		return nil, err
+	}
+	var count int64
+	if err := s.db.Model(&models.CalibrationReference{}).Where("image_sha256 = ?", img.SHA256).Count(&count).Error; err != nil {
+		return nil, err
+	}
+	if count > 0 {
+		return nil, ErrCalibrationReferenceExists
+	}
+
+	ref := &models.CalibrationReference{
+		Label:       label,
+		ImageBase64: img.Base64(),
+		ImageSHA256: img.SHA256,
+	}
+	if err := s.db.Create(ref).Error; err != nil {
+		return nil, err
+	}
+	return ref, nil
+}
+
+// Recompute scores the reference set with every source still missing a
+// sample (every source when rescore is set), fits a new calibration version
+// from all samples and recalibrates saved analyses from their raw scores.
+func (s *CalibrationService) Recompute(ctx context.Context, rescore bool) (*dto.CalibrationRecomputeResult, error) {
+	if s.method != models.CalibrationLinear && s.method != models.CalibrationQuantile {
+		return nil, ErrInvalidCalibrationMethod
+	}
+	result := &dto.CalibrationRecomputeResult{}
+
+	if err := s.scoreReferences(ctx, rescore, result); err != nil {
+		return nil, err
+	}
+
+	var samples []models.CalibrationSample
+	if err := s.db.Find(&samples).Error; err != nil {
+		return nil, err
+	}
+	rows := s.fit(samples)
+
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		var version int
+		if err := tx.Model(&models.ScoreCalibration{}).Select("COALESCE(MAX(version), 0)").Scan(&version).Error; err != nil {
+			return err
+		}
+		result.Version = version + 1
+		for i := range rows {
+			rows[i].Version = result.Version
+		}
+		if len(rows) == 0 {
+			// An empty version still records that nothing is mapped any more
+			rows = append(rows, models.ScoreCalibration{Version: result.Version, Method: s.method})
+		}
+		return tx.Create(&rows).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	if err := s.Load(); err != nil {
+		return nil, err
+	}
+	for _, row := range rows {
+		if row.Metric != "" {
+			result.MappingsFitted++
+		}
+	}
+
+	updated, err := s.reapply(time.Time{})
+	if err != nil {
+		return nil, err
+	}
+	result.AnalysesUpdated = updated
+	result.CompletedAt = time.Now()
+	return result, nil
+}
+
+// scoreReferences fills in the samples of the deterministic fallback and of
+// every vision provider in the chain.
+func (s *CalibrationService) scoreReferences(ctx context.Context, rescore bool, result *dto.CalibrationRecomputeResult) error {
+	var refs []models.CalibrationReference
+	if err := s.db.Find(&refs).Error; err != nil {
+		return err
+	}
+	var existing []models.CalibrationSample
+	if err := s.db.Select("reference_id, provider").Find(&existing).Error; err != nil {
+		return err
+	}
+	have := make(map[string]bool, len(existing))
+	for _, sample := range existing {
+		have[sample.ReferenceID.String()+"/"+sample.Provider] = true
+	}
+
+	var providers []string
+	for _, p := range s.llm.Providers() {
+		if p.SupportsVision() {
+			providers = append(providers, p.Name())
+		}
+	}
+
+	for _, ref := range refs {
+		if rescore || !have[ref.ID.String()+"/"+models.CalibrationProviderDeterministic] {
+			if err := s.saveSample(ref, models.CalibrationProviderDeterministic, "", deterministicAIResult(ref.ImageBase64, i18n.Default).scores()); err != nil {
+				return err
+			}
+			result.SamplesScored++
+		}
+		for _, provider := range providers {
+			if !rescore && have[ref.ID.String()+"/"+provider] {
+				continue
+			}
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			model, scores, err := s.ai.scoreReference(ctx, provider, ref.ImageBase64)
+			if err != nil {
+				result.SampleErrors = append(result.SampleErrors, fmt.Sprintf("%s on %s: %v", provider, ref.ID, err))
+				continue
+			}
+			if err := s.saveSample(ref, provider, model, scores); err != nil {
+				return err
+			}
+			result.SamplesScored++
+		}
+	}
+	return nil
+}
+
+func (s *CalibrationService) saveSample(ref models.CalibrationReference, provider, model string, scores map[string]float64) error {
+	sample := models.CalibrationSample{
+		ReferenceID: ref.ID,
+		Provider:    provider,
+		Model:       model,
+		Scores:      scores,
+	}
+	return s.db.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "reference_id"}, {Name: "provider"}, {Name: "model"}},
+		DoUpdates: clause.AssignmentColumns([]string{"scores", "updated_at"}),
+	}).Create(&sample).Error
+}
+
+// fit builds one mapping per source and metric from the samples of sources
+// that scored at least minSamples references with a target score. The
+// deterministic fallback is mapped like any source but, being an estimate,
+// does not count towards the consensus.
+func (s *CalibrationService) fit(samples []models.CalibrationSample) []models.ScoreCalibration {
+	// Target score of every reference image and metric
+	targets := make(map[string]map[string]float64)
+	counts := make(map[string]int)
+	for _, sample := range samples {
+		if s.target != "" && sample.Provider != s.target {
+			continue
+		}
+		if s.target == "" && sample.Provider == models.CalibrationProviderDeterministic {
+			continue
+		}
+		ref := sample.ReferenceID.String()
+		if targets[ref] == nil {
+			targets[ref] = make(map[string]float64)
+		}
+		for metric, v := range sample.Scores {
+			targets[ref][metric] += v
+		}
+		counts[ref]++
+	}
+	for ref, scores := range targets {
+		for metric := range scores {
+			scores[metric] /= float64(counts[ref])
+		}
+	}
+
+	bySource := make(map[calibrationKey][]models.CalibrationSample)
+	for _, sample := range samples {
+		key := calibrationKey{sample.Provider, sample.Model}
+		bySource[key] = append(bySource[key], sample)
+	}
+
+	var rows []models.ScoreCalibration
+	for key, sourceSamples := range bySource {
+		for _, metric := range AnalysisMetrics {
+			var raw, target []float64
+			for _, sample := range sourceSamples {
+				t, ok := targets[sample.ReferenceID.String()][metric]
+				v, hasRaw := sample.Scores[metric]
+				if ok && hasRaw {
+					raw = append(raw, v)
+					target = append(target, t)
+				}
+			}
+			if len(raw) < s.minSamples {
+				continue
+			}
+
+			row := models.ScoreCalibration{
+				Provider: key.provider,
+				Model:    key.model,
+				Metric:   metric,
+				Method:   s.method,
+				Slope:    1,
+				Samples:  len(raw),
+			}
+			if s.method == models.CalibrationQuantile {
+				row.RawKnots, row.TargetKnots = fitQuantiles(raw, target)
+			} else {
+				row.Slope, row.Intercept = fitLinear(raw, target)
+			}
+			rows = append(rows, row)
+		}
+	}
+	return rows
+}
+
+// fitLinear regresses target on raw by least squares. Without spread in the
+// raw scores it can only shift them.
+func fitLinear(raw, target []float64) (slope, intercept float64) {
+	n := float64(len(raw))
+	var meanX, meanY float64
+	for i := range raw {
+		meanX += raw[i]
+		meanY += target[i]
+	}
+	meanX /= n
+	meanY /= n
+
+	var sxx, sxy float64
+	for i := range raw {
+		sxx += (raw[i] - meanX) * (raw[i] - meanX)
+		sxy += (raw[i] - meanX) * (target[i] - meanY)
+	}
+	if sxx == 0 {
+		return 1, round4(meanY - meanX)
+	}
+	slope = sxy / sxx
+	return round4(slope), round4(meanY - slope*meanX)
+}
+
+// fitQuantiles matches the quantiles of the raw and target distributions,
+// dropping knots where the raw quantile does not increase.
+func fitQuantiles(raw, target []float64) ([]float64, []float64) {
+	xs := append([]float64(nil), raw...)
+	ys := append([]float64(nil), target...)
+	sort.Float64s(xs)
+	sort.Float64s(ys)
+
+	knots := quantileKnots
+	if len(xs) < knots {
+		knots = len(xs)
+	}
+	var rawKnots, targetKnots []float64
+	for k := 0; k < knots; k++ {
+		p := float64(k) / float64(knots-1)
+		x, y := round4(quantile(xs, p)), round4(quantile(ys, p))
+		if len(rawKnots) > 0 && x <= rawKnots[len(rawKnots)-1] {
+			continue
+		}
+		rawKnots = append(rawKnots, x)
+		targetKnots = append(targetKnots, y)
+	}
+	return rawKnots, targetKnots
+}
+
+// quantile interpolates the p-quantile of sorted values.
+func quantile(sorted []float64, p float64) float64 {
+	pos := p * float64(len(sorted)-1)
+	lo := int(math.Floor(pos))
+	hi := int(math.Ceil(pos))
+	return sorted[lo] + (pos-float64(lo))*(sorted[hi]-sorted[lo])
+}
+
+// reapply recalibrates every scored analysis created since the given time
+// (all of them when it is zero) from its raw scores with the loaded version.
+// Summaries of multi-angle scans were blended before calibration, so their
+// raw scores already include the profile metrics.
+func (s *CalibrationService) reapply(since time.Time) (int, error) {
+	updated := 0
+	var batch []models.FaceAnalysis
+	query := s.db.Where("source <> ?", models.AnalysisSourceManual)
+	if !since.IsZero() {
+		query = query.Where("created_at >= ?", since)
+	}
+	err := query.
+		FindInBatches(&batch, 200, func(tx *gorm.DB, _ int) error {
+			for i := range batch {
+				a := &batch[i]
+				s.Apply(a)
+				updates := map[string]interface{}{"raw_scores": a.RawScores, "calibration_version": a.CalibrationVersion}
+				for _, metric := range AnalysisMetrics {
+					updates[metric+"_score"] = metricScore(a, metric)
+				}
+				if err := s.db.Model(&models.FaceAnalysis{}).Where("id = ?", a.ID).Updates(updates).Error; err != nil {
+					return err
+				}
+				updated++
+			}
+			return nil
+		}).Error
+	if err != nil {
+		return updated, err
+	}
+	log.Printf("Calibration: recalibrated %d analyses", updated)
+	return updated, nil
+}
+
+// Status describes the loaded calibration and every source with samples.
+func (s *CalibrationService) Status() (*dto.CalibrationStatus, error) {
+	status := &dto.CalibrationStatus{Method: s.method, Target: s.target, Sources: []dto.CalibrationSource{}}
+	if status.Target == "" {
+		status.Target = "consensus"
+	}
+	if err := s.db.Model(&models.CalibrationReference{}).Count(&status.References).Error; err != nil {
+		return nil, err
+	}
+
+	var sources []struct {
+		Provider string
+		Model    string
+		Samples  int
+	}
+	if err := s.db.Model(&models.CalibrationSample{}).
+		Select("provider, model, COUNT(*) AS samples").
+		Group("provider, model").
+		Order("provider, model").
+		Scan(&sources).Error; err != nil {
+		return nil, err
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	status.Version = s.version
+	for _, src := range sources {
+		entry := dto.CalibrationSource{Provider: src.Provider, Model: src.Model, Samples: src.Samples, Mappings: []dto.CalibrationMapping{}}
+		mapping := s.mappings[calibrationKey{src.Provider, src.Model}]
+		for _, metric := range AnalysisMetrics {
+			m, ok := mapping[metric]
+			if !ok {
+				continue
+			}
+			entry.Mappings = append(entry.Mappings, dto.CalibrationMapping{
+				Metric:      metric,
+				Method:      m.Method,
+				Slope:       m.Slope,
+				Intercept:   m.Intercept,
+				RawKnots:    m.RawKnots,
+				TargetKnots: m.TargetKnots,
+				Samples:     m.Samples,
+			})
+		}
+		status.Sources = append(status.Sources, entry)
+	}
+	return status, nil
+}
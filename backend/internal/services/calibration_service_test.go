@@ -0,0 +1,123 @@
+package services
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/ahmetcoskunkizilkaya/mewify/backend/internal/models"
+)
+
+func TestCalibrate(t *testing.T) {
+	linear := func(slope, intercept float64) models.ScoreCalibration {
+		return models.ScoreCalibration{Method: models.CalibrationLinear, Slope: slope, Intercept: intercept}
+	}
+	quantile := models.ScoreCalibration{
+		Method:      models.CalibrationQuantile,
+		RawKnots:    []float64{2, 5, 8},
+		TargetKnots: []float64{3, 6, 9},
+	}
+
+	tests := []struct {
+		name string
+		m    models.ScoreCalibration
+		raw  float64
+		want float64
+	}{
+		{"linear identity", linear(1, 0), 6.5, 6.5},
+		{"linear", linear(1.2, -1), 7, 7.4},
+		{"linear rounds to one decimal", linear(1, 0.04), 5, 5},
+		{"linear above scale keeps raw", linear(2, 0), 6, 6},
+		{"linear below scale keeps raw", linear(1, -1.1), 1.5, 1.5},
+		{"quantile between knots", quantile, 6.5, 7.5},
+		{"quantile on a knot", quantile, 5, 6},
+		{"quantile below first knot", quantile, 1, 2},
+		{"quantile above last knot", quantile, 9, 10},
+		{"unknown method", models.ScoreCalibration{Method: "none"}, 6.66, 6.7},
+	}
+	for _, tt := range tests {
+		if got := calibrate(tt.m, tt.raw); got != tt.want {
+			t.Errorf("%s: calibrate(%v) = %v, want %v", tt.name, tt.raw, got, tt.want)
+		}
+	}
+}
+
+func TestInterpolateKnots(t *testing.T) {
+	xs := []float64{2, 4, 8}
+	ys := []float64{1, 5, 7}
+
+	tests := []struct {
+		name   string
+		xs, ys []float64
+		x      float64
+		want   float64
+	}{
+		{"no knots", nil, nil, 3.3, 3.3},
+		{"mismatched knots", xs, ys[:2], 3.3, 3.3},
+		{"below first knot", xs, ys, 1, 0},
+		{"on first knot", xs, ys, 2, 1},
+		{"between knots", xs, ys, 3, 3},
+		{"on inner knot", xs, ys, 4, 5},
+		{"between later knots", xs, ys, 6, 6},
+		{"on last knot", xs, ys, 8, 7},
+		{"above last knot", xs, ys, 9.5, 8.5},
+		{"single knot shifts", []float64{5}, []float64{6}, 3, 4},
+	}
+	for _, tt := range tests {
+		if got := interpolateKnots(tt.xs, tt.ys, tt.x); got != tt.want {
+			t.Errorf("%s: interpolateKnots(%v) = %v, want %v", tt.name, tt.x, got, tt.want)
+		}
+	}
+}
+
+func TestFitLinear(t *testing.T) {
+	tests := []struct {
+		name          string
+		raw, target   []float64
+		wantSlope     float64
+		wantIntercept float64
+	}{
+		{"exact line", []float64{2, 4, 6}, []float64{3, 7, 11}, 2, -1},
+		{"identity", []float64{3, 5, 8}, []float64{3, 5, 8}, 1, 0},
+		{"no spread shifts", []float64{5, 5, 5}, []float64{6, 7, 8}, 1, 2},
+	}
+	for _, tt := range tests {
+		slope, intercept := fitLinear(tt.raw, tt.target)
+		if slope != tt.wantSlope || intercept != tt.wantIntercept {
+			t.Errorf("%s: fitLinear = %v, %v, want %v, %v", tt.name, slope, intercept, tt.wantSlope, tt.wantIntercept)
+		}
+	}
+}
+
+func TestFitQuantiles(t *testing.T) {
+	tests := []struct {
+		name        string
+		raw, target []float64
+		wantRaw     []float64
+		wantTarget  []float64
+	}{
+		{"one knot per sample", []float64{3, 1, 2}, []float64{6, 4, 5}, []float64{1, 2, 3}, []float64{4, 5, 6}},
+		{"repeated raw quantiles dropped", []float64{5, 5, 5, 6}, []float64{1, 2, 3, 4}, []float64{5, 6}, []float64{1, 4}},
+	}
+	for _, tt := range tests {
+		gotRaw, gotTarget := fitQuantiles(tt.raw, tt.target)
+		if !reflect.DeepEqual(gotRaw, tt.wantRaw) || !reflect.DeepEqual(gotTarget, tt.wantTarget) {
+			t.Errorf("%s: fitQuantiles = %v, %v, want %v, %v", tt.name, gotRaw, gotTarget, tt.wantRaw, tt.wantTarget)
+		}
+	}
+
+	raw := make([]float64, 101)
+	target := make([]float64, 101)
+	for i := range raw {
+		raw[i] = float64(i) / 10
+		target[i] = float64(i)/10 + 1
+	}
+	xs, ys := fitQuantiles(raw, target)
+	if len(xs) != quantileKnots || len(ys) != quantileKnots {
+		t.Fatalf("fitQuantiles over 101 samples = %d knots, want %d", len(xs), quantileKnots)
+	}
+	for i := range xs {
+		if ys[i]-xs[i] != 1 {
+			t.Errorf("knot %d = %v -> %v, want an offset of 1", i, xs[i], ys[i])
+		}
+	}
+}
@@ -8,32 +8,50 @@ import (
 	"gorm.io/gorm"
 
 	"github.com/ahmetcoskunkizilkaya/mewify/backend/internal/dto"
+	"github.com/ahmetcoskunkizilkaya/mewify/backend/internal/events"
 	"github.com/ahmetcoskunkizilkaya/mewify/backend/internal/models"
 )
 
 type FaceAnalysisService interface {
 	CreateAnalysis(userID uuid.UUID, req dto.CreateFaceAnalysisRequest) (*models.FaceAnalysis, error)
 	GetAnalysisByID(analysisID, userID uuid.UUID) (*models.FaceAnalysis, error)
-	ListAnalyses(userID uuid.UUID, page, limit int) ([]models.FaceAnalysis, int64, error)
+	ListAnalyses(userID uuid.UUID, query dto.AnalysisListQuery) (*dto.AnalysisListResponse, error)
+	UpdateTags(analysisID, userID uuid.UUID, tags []string) (*models.FaceAnalysis, error)
 	DeleteAnalysis(analysisID, userID uuid.UUID) error
 	GetLatestAnalysis(userID uuid.UUID) (*models.FaceAnalysis, error)
 	GetAnalysisStats(userID uuid.UUID) (*dto.AnalysisStatsResponse, error)
+	AdminListAnalyses(filter dto.AdminAnalysisFilter, limit, offset int) ([]models.FaceAnalysis, int64, error)
+	AdminGetAnalysis(analysisID uuid.UUID) (*models.FaceAnalysis, error)
+	DuplicateReport(since time.Time, maxDistance int) ([]dto.DuplicateReportEntry, error)
+	CompareAnalyses(userID, fromID, toID uuid.UUID) (*dto.AnalysisComparisonResponse, error)
+	GetAnalytics(userID uuid.UUID, query dto.AnalyticsQuery) (*dto.AnalyticsResponse, error)
+	GetScanSession(sessionID, userID uuid.UUID) (*dto.ScanSessionResponse, error)
 }
 
 type faceAnalysisService struct {
-	db *gorm.DB
+	db    *gorm.DB
+	bus   *events.Bus
+	blobs *BlobService
 }
 
-func NewFaceAnalysisService(db *gorm.DB) *faceAnalysisService {
+func NewFaceAnalysisService(db *gorm.DB, bus *events.Bus, blobs *BlobService) *faceAnalysisService {
 	return &faceAnalysisService{
-		db: db,
+		db:    db,
+		bus:   bus,
+		blobs: blobs,
 	}
 }
 
 func (s *faceAnalysisService) CreateAnalysis(userID uuid.UUID, req dto.CreateFaceAnalysisRequest) (*models.FaceAnalysis, error) {
+	tags, err := normalizeTags(req.Tags)
+	if err != nil {
+		return nil, err
+	}
+
 	analysis := &models.FaceAnalysis{
 		UserID:        userID,
 		ImageURL:      req.ImageURL,
+		Source:        models.AnalysisSourceManual,
 		OverallScore:  req.OverallScore,
 		SymmetryScore: req.SymmetryScore,
 		JawlineScore:  req.JawlineScore,
@@ -44,6 +62,7 @@ func (s *faceAnalysisService) CreateAnalysis(userID uuid.UUID, req dto.CreateFac
 		HarmonyScore:  req.HarmonyScore,
 		Strengths:     req.Strengths,
 		Improvements:  req.Improvements,
+		Tags:          tags,
 		AnalyzedAt:    req.AnalyzedAt,
 	}
 
@@ -51,7 +70,19 @@ func (s *faceAnalysisService) CreateAnalysis(userID uuid.UUID, req dto.CreateFac
 		analysis.AnalyzedAt = time.Now()
 	}
 
-	if err := s.db.Create(analysis).Error; err != nil {
+	err = s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(analysis).Error; err != nil {
+			return err
+		}
+		return s.bus.Publish(tx, events.AnalysisCreated{
+			UserID:       analysis.UserID,
+			AnalysisID:   analysis.ID,
+			Source:       analysis.Source,
+			OverallScore: analysis.OverallScore,
+			AnalyzedAt:   analysis.AnalyzedAt,
+		})
+	})
+	if err != nil {
 		return nil, err
 	}
 
@@ -74,46 +105,39 @@ func (s *faceAnalysisService) GetAnalysisByID(analysisID, userID uuid.UUID) (*mo
 	return &analysis, nil
 }
 
-func (s *faceAnalysisService) ListAnalyses(userID uuid.UUID, page, limit int) ([]models.FaceAnalysis, int64, error) {
-	var analyses []models.FaceAnalysis
-	var total int64
-
-	offset := (page - 1) * limit
-
-	// Get total count
-	if err := s.db.Model(&models.FaceAnalysis{}).
-		Where("user_id = ?", userID).
-		Count(&total).Error; err != nil {
-		return nil, 0, err
-	}
-
-	// Get paginated results
-	err := s.db.Where("user_id = ?", userID).
-		Order("created_at DESC").
-		Offset(offset).
-		Limit(limit).
-		Find(&analyses).Error
-
-	if err != nil {
-		return nil, 0, err
-	}
-
-	return analyses, total, nil
-}
-
 func (s *faceAnalysisService) DeleteAnalysis(analysisID, userID uuid.UUID) error {
-	result := s.db.Where("id = ? AND user_id = ?", analysisID, userID).
-		Delete(&models.FaceAnalysis{})
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		result := tx.Where("id = ? AND user_id = ?", analysisID, userID).
+			Delete(&models.FaceAnalysis{})
 
-	if result.Error != nil {
-		return result.Error
-	}
+		if result.Error != nil {
+			return result.Error
+		}
 
-	if result.RowsAffected == 0 {
-		return errors.New("analysis not found")
-	}
+		if result.RowsAffected == 0 {
+			return errors.New("analysis not found")
+		}
 
-	return nil
+		// A multi-angle scan goes with its summary
+		var sessionIDs []uuid.UUID
+		if err := tx.Model(&models.ScanSession{}).Where("analysis_id = ?", analysisID).Pluck("id", &sessionIDs).Error; err != nil {
+			return err
+		}
+		if len(sessionIDs) > 0 {
+			if err := tx.Where("session_id IN ?", sessionIDs).Delete(&models.ScanAngleResult{}).Error; err != nil {
+				return err
+			}
+			if err := tx.Where("id IN ?", sessionIDs).Delete(&models.ScanSession{}).Error; err != nil {
+				return err
+			}
+		}
+
+		// The photos go with the analysis
+		if err := s.blobs.releaseOwner(tx, models.BlobOwnerFaceAnalysis, analysisID); err != nil {
+			return err
+		}
+		return s.bus.Publish(tx, events.AnalysisDeleted{UserID: userID, AnalysisID: analysisID})
+	})
 }
 
 func (s *faceAnalysisService) GetLatestAnalysis(userID uuid.UUID) (*models.FaceAnalysis, error) {
@@ -198,3 +222,45 @@ func (s *faceAnalysisService) GetAnalysisStats(userID uuid.UUID) (*dto.AnalysisS
 
 	return &stats, nil
 }
+
+// AdminListAnalyses returns analyses across all users with their provenance,
+// newest first.
+func (s *faceAnalysisService) AdminListAnalyses(filter dto.AdminAnalysisFilter, limit, offset int) ([]models.FaceAnalysis, int64, error) {
+	var analyses []models.FaceAnalysis
+	var total int64
+
+	query := s.db.Model(&models.FaceAnalysis{})
+	if filter.UserID != nil {
+		query = query.Where("user_id = ?", *filter.UserID)
+	}
+	if filter.Source != "" {
+		query = query.Where("source = ?", filter.Source)
+	}
+	if filter.Provider != "" {
+		query = query.Where("provider = ?", filter.Provider)
+	}
+	if filter.FallbackOnly {
+		query = query.Where("fallback_error <> ''")
+	}
+
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+	if err := query.Order("created_at DESC").Limit(limit).Offset(offset).Find(&analyses).Error; err != nil {
+		return nil, 0, err
+	}
+	return analyses, total, nil
+}
+
+// AdminGetAnalysis returns any user's analysis, including deleted ones, so
+// support can explain a score the user has since removed.
+func (s *faceAnalysisService) AdminGetAnalysis(analysisID uuid.UUID) (*models.FaceAnalysis, error) {
+	var analysis models.FaceAnalysis
+	if err := s.db.Unscoped().Where("id = ?", analysisID).First(&analysis).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("analysis not found")
+		}
+		return nil, err
+	}
+	return &analysis, nil
+}
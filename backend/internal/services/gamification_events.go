@@ -0,0 +1,321 @@
+package services
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/ahmetcoskunkizilkaya/mewify/backend/internal/events"
+	"github.com/ahmetcoskunkizilkaya/mewify/backend/internal/i18n"
+	"github.com/ahmetcoskunkizilkaya/mewify/backend/internal/models"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// XP rewards granted from domain events
+const (
+	ScanXP            = 25
+	MewingXPPerMinute = 1
+	PlanItemXP        = 30
+)
+
+// RegisterEventHandlers subscribes gamification to the domain events that
+// drive XP, stats, challenges, achievements and leaderboards. Stat counters are
+// updated in the publisher's transaction; rewards are enqueued to the outbox
+// in that same transaction and applied by the dispatcher.
+func (s *GamificationService) RegisterEventHandlers(bus *events.Bus) {
+	events.On(bus, s.onAnalysisCreated)
+	events.On(bus, s.onAnalysisDeleted)
+	events.On(bus, s.onMewingLogged)
+	events.On(bus, s.onPlanItemCompleted)
+	events.On(bus, s.onSubscriptionChanged)
+}
+
+// withDB returns a copy of the service bound to db, typically an open
+// transaction.
+func (s *GamificationService) withDB(db *gorm.DB) *GamificationService {
+	return &GamificationService{db: db}
+}
+
+func (s *GamificationService) onAnalysisCreated(tx *gorm.DB, e events.AnalysisCreated) error {
+	// Manual entries carry scores the client chose, so they neither earn XP
+	// nor count towards the scan stats
+	if !countsAsScan(e.Source) {
+		return nil
+	}
+	if err := s.withDB(tx).refreshScanStats(e.UserID); err != nil {
+		return err
+	}
+
+	return enqueueOutbox(tx, OutboxTopicActivityReward, e.AnalysisID.String(), activityRewardPayload{
+		UserID:         e.UserID,
+		XP:             ScanXP,
+		Reason:         "scan",
+		ReferenceID:    e.AnalysisID.String(),
+		Description:    "Face scan completed",
+		ChallengeType:  "scan",
+		ChallengeValue: 1,
+		OccurredAt:     time.Now(),
+	})
+}
+
+func (s *GamificationService) onAnalysisDeleted(tx *gorm.DB, e events.AnalysisDeleted) error {
+	return s.withDB(tx).refreshScanStats(e.UserID)
+}
+
+// countsAsScan reports whether an analysis of source was scored by the
+// server and so counts as a scan.
+func countsAsScan(source string) bool {
+	return source == models.AnalysisSourceLLM || source == models.AnalysisSourceDeterministic
+}
+
+// refreshScanStats recounts total_scans, best_score and average_score from
+// the user's remaining scans, so they follow deletions and never drift from
+// the analyses table. The gamification row is locked first, so concurrent
+// refreshes see each other's scans. Call it inside a transaction.
+func (s *GamificationService) refreshScanStats(userID uuid.UUID) error {
+	if _, err := s.lockGamification(userID); err != nil {
+		return err
+	}
+
+	var stats struct {
+		Scans   int
+		Best    float64
+		Average float64
+	}
+	if err := s.db.Model(&models.FaceAnalysis{}).
+		Select("COUNT(*) AS scans, COALESCE(MAX(overall_score), 0) AS best, COALESCE(AVG(overall_score), 0) AS average").
+		Where("user_id = ? AND source IN ?", userID, []string{models.AnalysisSourceLLM, models.AnalysisSourceDeterministic}).
+		Scan(&stats).Error; err != nil {
+		return err
+	}
+
+	return s.db.Model(&models.UserGamification{}).
+		Where("user_id = ?", userID).
+		UpdateColumns(map[string]interface{}{
+			"total_scans":   stats.Scans,
+			"best_score":    stats.Best,
+			"average_score": stats.Average,
+		}).Error
+}
+
+func (s *GamificationService) onMewingLogged(tx *gorm.DB, e events.MewingLogged) error {
+	if err := s.withDB(tx).ensureGamification(e.UserID); err != nil {
+		return err
+	}
+
+	if e.DeltaMinutes != 0 {
+		if err := tx.Model(&models.UserGamification{}).
+			Where("user_id = ?", e.UserID).
+			UpdateColumn("total_mewing_minutes", gorm.Expr("GREATEST(total_mewing_minutes + ?, 0)", e.DeltaMinutes)).Error; err != nil {
+			return err
+		}
+	}
+
+	key := fmt.Sprintf("%s:%d:%t", e.ProgressID, e.TotalMinutes, e.GoalCompleted)
+	return enqueueOutbox(tx, OutboxTopicMewingReward, key, mewingRewardPayload{
+		UserID:        e.UserID,
+		ProgressID:    e.ProgressID,
+		TotalMinutes:  e.TotalMinutes,
+		GoalCompleted: e.GoalCompleted,
+		OccurredAt:    time.Now(),
+	})
+}
+
+func (s *GamificationService) onPlanItemCompleted(tx *gorm.DB, e events.PlanItemCompleted) error {
+	// Keyed by plan item, so toggling completion pays out only once
+	return enqueueOutbox(tx, OutboxTopicActivityReward, e.PlanID.String(), activityRewardPayload{
+		UserID:      e.UserID,
+		XP:          PlanItemXP,
+		Reason:      "glow_plan",
+		ReferenceID: e.PlanID.String(),
+		Description: fmt.Sprintf("Completed %s plan item", e.Category),
+		OccurredAt:  time.Now(),
+	})
+}
+
+func (s *GamificationService) onSubscriptionChanged(tx *gorm.DB, e events.SubscriptionChanged) error {
+	if e.Status == e.PreviousStatus {
+		return nil
+	}
+
+	notification := notificationPayload{
+		UserID:     e.UserID,
+		Type:       "subscription",
+		Icon:       "star",
+		ActionData: e.SubscriptionID.String(),
+	}
+	switch e.Status {
+	case "active":
+		notification.Key = "notification.premium_active"
+	case "expired":
+		notification.Key = "notification.premium_expired"
+	default:
+		return nil
+	}
+	return enqueueOutbox(tx, OutboxTopicNotification, "", notification)
+}
+
+// ============================================
+// OUTBOX DELIVERY
+// ============================================
+
+type activityRewardPayload struct {
+	UserID         uuid.UUID `json:"user_id"`
+	XP             int       `json:"xp"`
+	Reason         string    `json:"reason"`
+	ReferenceID    string    `json:"reference_id"`
+	Description    string    `json:"description"`
+	ChallengeType  string    `json:"challenge_type,omitempty"`
+	ChallengeValue int       `json:"challenge_value,omitempty"`
+	// OccurredAt dates the activity for the streak, however late the
+	// message is delivered
+	OccurredAt time.Time `json:"occurred_at"`
+}
+
+type mewingRewardPayload struct {
+	UserID        uuid.UUID `json:"user_id"`
+	ProgressID    uuid.UUID `json:"progress_id"`
+	TotalMinutes  int       `json:"total_minutes"`
+	GoalCompleted bool      `json:"goal_completed"`
+	OccurredAt    time.Time `json:"occurred_at"`
+}
+
+type xpGrantPayload struct {
+	UserID      uuid.UUID `json:"user_id"`
+	Amount      int       `json:"amount"`
+	Reason      string    `json:"reason"`
+	ReferenceID string    `json:"reference_id"`
+	Description string    `json:"description"`
+}
+
+// notificationPayload is worded on delivery in the recipient's language from
+// the catalog's Key.title and Key.body; payloads without a Key carry the text
+// itself.
+type notificationPayload struct {
+	UserID     uuid.UUID `json:"user_id"`
+	Type       string    `json:"type"`
+	Key        string    `json:"key,omitempty"`
+	Title      string    `json:"title"`
+	Body       string    `json:"body"`
+	Icon       string    `json:"icon"`
+	ActionData string    `json:"action_data"`
+}
+
+// RegisterOutboxHandlers registers delivery for the rewards and notifications
+// gamification enqueues.
+func (s *GamificationService) RegisterOutboxHandlers(outbox *OutboxService) {
+	outbox.Register(OutboxTopicActivityReward, s.deliverActivityReward)
+	outbox.Register(OutboxTopicMewingReward, s.deliverMewingReward)
+	outbox.Register(OutboxTopicXPGrant, s.deliverXPGrant)
+	outbox.Register(OutboxTopicNotification, s.deliverNotification)
+}
+
+func (s *GamificationService) deliverActivityReward(tx *gorm.DB, payload json.RawMessage) error {
+	var p activityRewardPayload
+	if err := json.Unmarshal(payload, &p); err != nil {
+		return err
+	}
+
+	g := s.withDB(tx)
+	if err := g.AddXP(p.UserID, p.XP, p.Reason, p.ReferenceID, p.Description); err != nil {
+		return err
+	}
+	if p.ChallengeType != "" {
+		if err := g.UpdateChallengeProgress(p.UserID, p.ChallengeType, p.ChallengeValue); err != nil {
+			return err
+		}
+	}
+	return g.recordActivity(p.UserID, p.OccurredAt)
+}
+
+func (s *GamificationService) deliverMewingReward(tx *gorm.DB, payload json.RawMessage) error {
+	var p mewingRewardPayload
+	if err := json.Unmarshal(payload, &p); err != nil {
+		return err
+	}
+	g := s.withDB(tx)
+
+	// A day's entry can be rewritten, so XP tracks the highest total logged
+	// for it rather than each change. Lowering and raising it again earns
+	// nothing, and messages delivered out of order settle on the same total.
+	var awarded int
+	if err := tx.Model(&models.XPTransaction{}).
+		Where("user_id = ? AND reason = ? AND reference_id = ?", p.UserID, "mewing", p.ProgressID.String()).
+		Select("COALESCE(SUM(amount), 0)").Scan(&awarded).Error; err != nil {
+		return err
+	}
+
+	newMinutes := p.TotalMinutes - awarded/MewingXPPerMinute
+	if newMinutes > 0 {
+		if err := g.AddXP(p.UserID, newMinutes*MewingXPPerMinute, "mewing", p.ProgressID.String(),
+			fmt.Sprintf("Mewed for %d minutes", newMinutes)); err != nil {
+			return err
+		}
+		if err := g.UpdateChallengeProgress(p.UserID, "mewing_minutes", newMinutes); err != nil {
+			return err
+		}
+	}
+
+	if p.GoalCompleted {
+		if err := g.UpdateChallengeProgress(p.UserID, "streak", 1); err != nil {
+			return err
+		}
+	}
+
+	return g.recordActivity(p.UserID, p.OccurredAt)
+}
+
+func (s *GamificationService) deliverXPGrant(tx *gorm.DB, payload json.RawMessage) error {
+	var p xpGrantPayload
+	if err := json.Unmarshal(payload, &p); err != nil {
+		return err
+	}
+
+	g := s.withDB(tx)
+	if err := g.AddXP(p.UserID, p.Amount, p.Reason, p.ReferenceID, p.Description); err != nil {
+		return err
+	}
+	if _, err := g.CheckAndGrantAchievements(p.UserID); err != nil {
+		return err
+	}
+	return g.UpdateLeaderboard(p.UserID)
+}
+
+func (s *GamificationService) deliverNotification(tx *gorm.DB, payload json.RawMessage) error {
+	var p notificationPayload
+	if err := json.Unmarshal(payload, &p); err != nil {
+		return err
+	}
+	if p.Key != "" {
+		locale := userLocale(tx, p.UserID, "")
+		p.Title = i18n.T(locale, p.Key+".title")
+		p.Body = i18n.T(locale, p.Key+".body")
+	}
+	return s.withDB(tx).createNotification(p.UserID, p.Type, p.Title, p.Body, p.Icon, p.ActionData)
+}
+
+// recordActivity refreshes streak, achievements and leaderboard standings after
+// any XP-earning activity. Messages enqueued before activities were dated
+// count as happening now.
+func (s *GamificationService) recordActivity(userID uuid.UUID, at time.Time) error {
+	if at.IsZero() {
+		at = time.Now()
+	}
+	if _, _, err := s.UpdateStreak(userID, at); err != nil {
+		return err
+	}
+	if _, err := s.CheckAndGrantAchievements(userID); err != nil {
+		return err
+	}
+	return s.UpdateLeaderboard(userID)
+}
+
+func (s *GamificationService) ensureGamification(userID uuid.UUID) error {
+	gamification := models.UserGamification{
+		UserID:        userID,
+		CurrentLevel:  1,
+		XPToNextLevel: 200,
+	}
+	return s.db.Where("user_id = ?", userID).FirstOrCreate(&gamification).Error
+}
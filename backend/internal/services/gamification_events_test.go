@@ -0,0 +1,42 @@
+package services
+
+import (
+	"testing"
+
+	"github.com/ahmetcoskunkizilkaya/mewify/backend/internal/events"
+	"github.com/ahmetcoskunkizilkaya/mewify/backend/internal/models"
+	"github.com/google/uuid"
+)
+
+func TestCountsAsScan(t *testing.T) {
+	tests := []struct {
+		source string
+		want   bool
+	}{
+		{models.AnalysisSourceLLM, true},
+		{models.AnalysisSourceDeterministic, true},
+		{models.AnalysisSourceManual, false},
+		{"", false},
+		{"unknown", false},
+	}
+	for _, tt := range tests {
+		if got := countsAsScan(tt.source); got != tt.want {
+			t.Errorf("countsAsScan(%q) = %v, want %v", tt.source, got, tt.want)
+		}
+	}
+}
+
+// A manual entry must be ignored before the handler touches the database, so
+// the nil DB here would panic if it earned XP or moved the scan stats.
+func TestOnAnalysisCreatedIgnoresManualEntries(t *testing.T) {
+	s := &GamificationService{}
+	err := s.onAnalysisCreated(nil, events.AnalysisCreated{
+		UserID:       uuid.New(),
+		AnalysisID:   uuid.New(),
+		Source:       models.AnalysisSourceManual,
+		OverallScore: 10,
+	})
+	if err != nil {
+		t.Fatalf("onAnalysisCreated(manual) = %v, want nil", err)
+	}
+}
@@ -1,12 +1,15 @@
 package services
 
 import (
+	"errors"
 	"fmt"
 	"time"
 
+	"github.com/ahmetcoskunkizilkaya/mewify/backend/internal/i18n"
 	"github.com/ahmetcoskunkizilkaya/mewify/backend/internal/models"
 	"github.com/google/uuid"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 type GamificationService struct {
@@ -33,118 +36,157 @@ func (s *GamificationService) GetXPForLevel(level int) int {
 	return xp
 }
 
+// AddXP records an XP transaction and applies it with any level-ups. The row
+// is locked for the read-modify-write and only the XP columns are written, so
+// the stat counters event handlers bump concurrently are never overwritten.
 func (s *GamificationService) AddXP(userID uuid.UUID, amount int, reason, referenceID, description string) error {
-	// Create transaction
-	transaction := models.XPTransaction{
-		UserID:      userID,
-		Amount:      amount,
-		Reason:      reason,
-		ReferenceID: referenceID,
-		Description: description,
-	}
-	if err := s.db.Create(&transaction).Error; err != nil {
-		return err
-	}
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		g := s.withDB(tx)
+
+		// Create transaction
+		transaction := models.XPTransaction{
+			UserID:      userID,
+			Amount:      amount,
+			Reason:      reason,
+			ReferenceID: referenceID,
+			Description: description,
+		}
+		if err := tx.Create(&transaction).Error; err != nil {
+			return err
+		}
 
-	// Update user gamification
-	var gamification models.UserGamification
-	if err := s.db.Where("user_id = ?", userID).First(&gamification).Error; err != nil {
-		// Create if not exists
-		gamification = models.UserGamification{
-			UserID:         userID,
-			TotalXP:        0,
-			CurrentLevel:   1,
-			XPToNextLevel:  200,
+		gamification, err := g.lockGamification(userID)
+		if err != nil {
+			return err
 		}
-		s.db.Create(&gamification)
-	}
 
-	gamification.TotalXP += amount
+		totalXP := gamification.TotalXP + amount
+		level := gamification.CurrentLevel
+		toNext := gamification.XPToNextLevel
+
+		// Check for level up
+		for totalXP >= toNext {
+			totalXP -= toNext
+			level++
+			toNext = s.GetXPForLevel(level)
+
+			// Create level up notification
+			locale := userLocale(tx, userID, "")
+			if err := g.createNotification(userID, "level_up",
+				i18n.T(locale, "notification.level_up.title", level),
+				i18n.T(locale, "notification.level_up.body", level),
+				"trophy", ""); err != nil {
+				return err
+			}
+		}
 
-	// Check for level up
-	for gamification.TotalXP >= gamification.XPToNextLevel {
-		gamification.TotalXP -= gamification.XPToNextLevel
-		gamification.CurrentLevel++
-		gamification.XPToNextLevel = s.GetXPForLevel(gamification.CurrentLevel)
+		return tx.Model(gamification).UpdateColumns(map[string]interface{}{
+			"total_xp":         totalXP,
+			"current_level":    level,
+			"xp_to_next_level": toNext,
+		}).Error
+	})
+}
 
-		// Create level up notification
-		s.createNotification(userID, "level_up",
-			fmt.Sprintf("Level %d!", gamification.CurrentLevel),
-			fmt.Sprintf("Congratulations! You've reached level %d!", gamification.CurrentLevel),
-			"trophy", "")
+// lockGamification loads the user's gamification row FOR UPDATE, creating it
+// first if needed. Call it inside a transaction.
+func (s *GamificationService) lockGamification(userID uuid.UUID) (*models.UserGamification, error) {
+	if err := s.ensureGamification(userID); err != nil {
+		return nil, err
 	}
-
-	return s.db.Save(&gamification).Error
+	var gamification models.UserGamification
+	if err := s.db.Clauses(clause.Locking{Strength: "UPDATE"}).
+		Where("user_id = ?", userID).
+		First(&gamification).Error; err != nil {
+		return nil, err
+	}
+	return &gamification, nil
 }
 
 // ============================================
 // STREAK SYSTEM
 // ============================================
 
-func (s *GamificationService) UpdateStreak(userID uuid.UUID) (int, bool, error) {
-	var gamification models.UserGamification
-	if err := s.db.Where("user_id = ?", userID).First(&gamification).Error; err != nil {
-		gamification = models.UserGamification{
-			UserID:         userID,
-			CurrentStreak:  0,
-			LongestStreak:  0,
-			LastActivityAt: time.Now(),
+// UpdateStreak counts activity at the given time toward the daily streak.
+// Activity on a day already counted, including one delivered late, changes
+// nothing. Only the streak columns are written, under a row lock.
+func (s *GamificationService) UpdateStreak(userID uuid.UUID, at time.Time) (int, bool, error) {
+	var streak int
+	var changed bool
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		g := s.withDB(tx)
+		gamification, err := g.lockGamification(userID)
+		if err != nil {
+			return err
 		}
-		s.db.Create(&gamification)
-	}
 
-	now := time.Now()
-	lastActivity := gamification.LastActivityAt
-	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
-	lastActivityDay := time.Date(lastActivity.Year(), lastActivity.Month(), lastActivity.Day(), 0, 0, 0, 0, lastActivity.Location())
-
-	daysDiff := int(today.Sub(lastActivityDay).Hours() / 24)
-
-	streakBroken := false
-	newRecord := false
-
-	if daysDiff == 0 {
-		// Already updated today
-		return gamification.CurrentStreak, false, nil
-	} else if daysDiff == 1 {
-		// Continuing streak
-		gamification.CurrentStreak++
-		if gamification.CurrentStreak > gamification.LongestStreak {
-			gamification.LongestStreak = gamification.CurrentStreak
-			newRecord = true
+		lastActivity := gamification.LastActivityAt
+		day := time.Date(at.Year(), at.Month(), at.Day(), 0, 0, 0, 0, at.Location())
+		lastActivityDay := time.Date(lastActivity.Year(), lastActivity.Month(), lastActivity.Day(), 0, 0, 0, 0, at.Location())
+
+		daysDiff := int(day.Sub(lastActivityDay).Hours() / 24)
+
+		streak = gamification.CurrentStreak
+		if daysDiff <= 0 {
+			// Already counted that day
+			return nil
 		}
-	} else if daysDiff > 1 {
-		// Check for streak freeze
-		if gamification.StreakFreezes > 0 && daysDiff == 2 {
+
+		longest := gamification.LongestStreak
+		freezes := gamification.StreakFreezes
+		streakBroken := false
+		newRecord := false
+
+		if daysDiff == 1 {
+			// Continuing streak
+			streak++
+		} else if freezes > 0 && daysDiff == 2 {
 			// Use streak freeze
-			gamification.StreakFreezes--
-			gamification.CurrentStreak++
-		} else if gamification.StreakShieldUntil != nil && gamification.StreakShieldUntil.After(now) {
+			freezes--
+			streak++
+		} else if gamification.StreakShieldUntil != nil && gamification.StreakShieldUntil.After(at) {
 			// Shielded, continue streak
-			gamification.CurrentStreak++
+			streak++
 		} else {
 			// Streak broken
-			gamification.CurrentStreak = 1
-			streakBroken = true
+			streak = 1
+			streakBroken = gamification.CurrentStreak > 0
+		}
+		if streak > longest {
+			longest = streak
+			newRecord = true
 		}
-	}
-
-	gamification.LastActivityAt = now
-	s.db.Save(&gamification)
 
-	// Check streak achievements
-	s.checkStreakAchievements(userID, gamification.CurrentStreak)
+		if err := tx.Model(gamification).UpdateColumns(map[string]interface{}{
+			"current_streak":   streak,
+			"longest_streak":   longest,
+			"streak_freezes":   freezes,
+			"last_activity_at": at,
+		}).Error; err != nil {
+			return err
+		}
+		changed = streakBroken || newRecord
 
-	return gamification.CurrentStreak, streakBroken || newRecord, nil
+		// Check streak achievements
+		return g.checkStreakAchievements(userID, streak)
+	})
+	if err != nil {
+		return 0, false, err
+	}
+	return streak, changed, nil
 }
 
-func (s *GamificationService) checkStreakAchievements(userID uuid.UUID, streak int) {
+func (s *GamificationService) checkStreakAchievements(userID uuid.UUID, streak int) error {
 	streakMilestones := []int{3, 7, 14, 30, 60, 100, 365}
 	for _, milestone := range streakMilestones {
 		if streak == milestone {
-			s.GrantAchievement(userID, fmt.Sprintf("streak_%d", milestone))
+			// Milestones without a seeded achievement are skipped
+			if _, err := s.GrantAchievement(userID, fmt.Sprintf("streak_%d", milestone)); err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+				return err
+			}
 		}
 	}
+	return nil
 }
 
 // ============================================
@@ -162,6 +204,8 @@ func (s *GamificationService) GrantAchievement(userID uuid.UUID, achievementCode
 	var existing models.UserAchievement
 	if err := s.db.Where("user_id = ? AND achievement_id = ?", userID, achievement.ID).First(&existing).Error; err == nil {
 		return false, nil // Already has it
+	} else if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return false, err
 	}
 
 	// Grant achievement
@@ -175,19 +219,25 @@ func (s *GamificationService) GrantAchievement(userID uuid.UUID, achievementCode
 	}
 
 	// Award XP
-	s.AddXP(userID, achievement.XPReward, "achievement", achievement.ID.String(),
-		fmt.Sprintf("Earned achievement: %s", achievement.Name))
+	if err := s.AddXP(userID, achievement.XPReward, "achievement", achievement.ID.String(),
+		fmt.Sprintf("Earned achievement: %s", achievement.Name)); err != nil {
+		return false, err
+	}
 
 	// Update count
-	s.db.Model(&models.UserGamification{}).
+	if err := s.db.Model(&models.UserGamification{}).
 		Where("user_id = ?", userID).
-		UpdateColumn("achievements_earned", gorm.Expr("achievements_earned + 1"))
+		UpdateColumn("achievements_earned", gorm.Expr("achievements_earned + 1")).Error; err != nil {
+		return false, err
+	}
 
 	// Create notification
-	s.createNotification(userID, "achievement",
-		fmt.Sprintf("🏆 %s", achievement.Name),
+	if err := s.createNotification(userID, "achievement",
+		i18n.T(userLocale(s.db, userID, ""), "notification.achievement.title", achievement.Name),
 		achievement.Description,
-		achievement.Icon, achievement.ID.String())
+		achievement.Icon, achievement.ID.String()); err != nil {
+		return false, err
+	}
 
 	return true, nil
 }
@@ -197,17 +247,26 @@ func (s *GamificationService) CheckAndGrantAchievements(userID uuid.UUID) ([]mod
 
 	// Get user stats
 	var gamification models.UserGamification
-	s.db.Where("user_id = ?", userID).First(&gamification)
+	if err := s.db.Where("user_id = ?", userID).First(&gamification).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil // Nothing earned yet
+		}
+		return nil, err
+	}
 
 	// Get all achievements
 	var achievements []models.Achievement
-	s.db.Where("is_active = ?", true).Order("sort_order").Find(&achievements)
+	if err := s.db.Where("is_active = ?", true).Order("sort_order").Find(&achievements).Error; err != nil {
+		return nil, err
+	}
 
 	// Get user's earned achievements
 	var earnedIDs []uuid.UUID
-	s.db.Model(&models.UserAchievement{}).
+	if err := s.db.Model(&models.UserAchievement{}).
 		Where("user_id = ?", userID).
-		Pluck("achievement_id", &earnedIDs)
+		Pluck("achievement_id", &earnedIDs).Error; err != nil {
+		return nil, err
+	}
 
 	earnedMap := make(map[uuid.UUID]bool)
 	for _, id := range earnedIDs {
@@ -238,7 +297,11 @@ func (s *GamificationService) CheckAndGrantAchievements(userID uuid.UUID) ([]mod
 		}
 
 		if shouldGrant {
-			if granted, _ := s.GrantAchievement(userID, achievement.Code); granted {
+			granted, err := s.GrantAchievement(userID, achievement.Code)
+			if err != nil {
+				return newAchievements, err
+			}
+			if granted {
 				newAchievements = append(newAchievements, achievement)
 			}
 		}
@@ -257,15 +320,23 @@ func (s *GamificationService) GetTodayChallenge(userID uuid.UUID) (*models.UserD
 	// Get or create daily challenge
 	var dailyChallenge models.DailyChallenge
 	if err := s.db.Where("date = ?", today).First(&dailyChallenge).Error; err != nil {
+		if !errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, err
+		}
 		// Generate new challenge
 		dailyChallenge = s.generateDailyChallenge(today)
-		s.db.Create(&dailyChallenge)
+		if err := s.db.Create(&dailyChallenge).Error; err != nil {
+			return nil, err
+		}
 	}
 
 	// Get user progress
 	var userChallenge models.UserDailyChallenge
 	if err := s.db.Where("user_id = ? AND daily_challenge_id = ?", userID, dailyChallenge.ID).
 		Preload("DailyChallenge").First(&userChallenge).Error; err != nil {
+		if !errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, err
+		}
 		// Create user challenge
 		userChallenge = models.UserDailyChallenge{
 			UserID:           userID,
@@ -273,7 +344,10 @@ func (s *GamificationService) GetTodayChallenge(userID uuid.UUID) (*models.UserD
 			CurrentValue:     0,
 			Completed:        false,
 		}
-		s.db.Create(&userChallenge)
+		if err := s.db.Create(&userChallenge).Error; err != nil {
+			return nil, err
+		}
+		userChallenge.DailyChallenge = dailyChallenge
 	}
 
 	return &userChallenge, nil
@@ -330,14 +404,19 @@ func (s *GamificationService) UpdateChallengeProgress(userID uuid.UUID, challeng
 		userChallenge.ClaimedAt = &now
 
 		// Award XP
-		s.AddXP(userID, userChallenge.DailyChallenge.XPReward, "challenge",
-			userChallenge.DailyChallenge.ID.String(), "Daily challenge completed!")
+		if err := s.AddXP(userID, userChallenge.DailyChallenge.XPReward, "challenge",
+			userChallenge.DailyChallenge.ID.String(), "Daily challenge completed!"); err != nil {
+			return err
+		}
 
 		// Notification
-		s.createNotification(userID, "challenge",
-			"✅ Challenge Complete!",
-			fmt.Sprintf("You earned %d XP!", userChallenge.DailyChallenge.XPReward),
-			userChallenge.DailyChallenge.Icon, "")
+		locale := userLocale(s.db, userID, "")
+		if err := s.createNotification(userID, "challenge",
+			i18n.T(locale, "notification.challenge.title"),
+			i18n.T(locale, "notification.challenge.body", userChallenge.DailyChallenge.XPReward),
+			userChallenge.DailyChallenge.Icon, ""); err != nil {
+			return err
+		}
 	}
 
 	return s.db.Save(userChallenge).Error
@@ -349,7 +428,9 @@ func (s *GamificationService) UpdateChallengeProgress(userID uuid.UUID, challeng
 
 func (s *GamificationService) UpdateLeaderboard(userID uuid.UUID) error {
 	var gamification models.UserGamification
-	s.db.Where("user_id = ?", userID).First(&gamification)
+	if err := s.db.Where("user_id = ?", userID).First(&gamification).Error; err != nil {
+		return err
+	}
 
 	periods := []string{"daily", "weekly", "monthly", "all_time"}
 
@@ -360,32 +441,43 @@ func (s *GamificationService) UpdateLeaderboard(userID uuid.UUID) error {
 		case "daily":
 			// Today's XP
 			var todayXP int
-			s.db.Model(&models.XPTransaction{}).
+			if err := s.db.Model(&models.XPTransaction{}).
 				Where("user_id = ? AND created_at >= ?", userID, time.Now().Truncate(24*time.Hour)).
-				Select("COALESCE(SUM(amount), 0)").Scan(&todayXP)
+				Select("COALESCE(SUM(amount), 0)").Scan(&todayXP).Error; err != nil {
+				return err
+			}
 			score = todayXP
 		case "weekly":
 			// This week's XP
 			var weekXP int
-			s.db.Model(&models.XPTransaction{}).
+			if err := s.db.Model(&models.XPTransaction{}).
 				Where("user_id = ? AND created_at >= ?", userID, time.Now().AddDate(0, 0, -7)).
-				Select("COALESCE(SUM(amount), 0)").Scan(&weekXP)
+				Select("COALESCE(SUM(amount), 0)").Scan(&weekXP).Error; err != nil {
+				return err
+			}
 			score = weekXP
 		}
 
 		var entry models.LeaderboardEntry
 		if err := s.db.Where("user_id = ? AND period = ?", userID, period).First(&entry).Error; err != nil {
+			if !errors.Is(err, gorm.ErrRecordNotFound) {
+				return err
+			}
 			entry = models.LeaderboardEntry{
 				UserID:    userID,
 				Period:    period,
 				Score:     score,
 				UpdatedAt: time.Now(),
 			}
-			s.db.Create(&entry)
+			if err := s.db.Create(&entry).Error; err != nil {
+				return err
+			}
 		} else {
 			entry.Score = score
 			entry.UpdatedAt = time.Now()
-			s.db.Save(&entry)
+			if err := s.db.Save(&entry).Error; err != nil {
+				return err
+			}
 		}
 	}
 
@@ -440,10 +532,10 @@ func (s *GamificationService) GetUnreadNotifications(userID uuid.UUID) ([]models
 	return notifications, err
 }
 
-func (s *GamificationService) MarkNotificationRead(notificationID uuid.UUID) error {
+func (s *GamificationService) MarkNotificationRead(userID, notificationID uuid.UUID) error {
 	now := time.Now()
 	return s.db.Model(&models.Notification{}).
-		Where("id = ?", notificationID).
+		Where("id = ? AND user_id = ?", notificationID, userID).
 		Updates(map[string]interface{}{
 			"read":    true,
 			"read_at": now,
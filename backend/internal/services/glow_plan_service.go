@@ -1,29 +1,32 @@
 package services
 
 import (
-	"bytes"
-	"encoding/json"
+	"context"
 	"errors"
 	"fmt"
-	"io"
-	"net/http"
 	"strings"
 	"time"
 
 	"github.com/google/uuid"
 	"gorm.io/gorm"
 
-	"github.com/ahmetcoskunkizilkaya/mewify/backend/internal/config"
+	"github.com/ahmetcoskunkizilkaya/mewify/backend/internal/events"
+	"github.com/ahmetcoskunkizilkaya/mewify/backend/internal/i18n"
+	"github.com/ahmetcoskunkizilkaya/mewify/backend/internal/llm"
 	"github.com/ahmetcoskunkizilkaya/mewify/backend/internal/models"
+	"github.com/ahmetcoskunkizilkaya/mewify/backend/internal/prompts"
 )
 
 type GlowPlanService struct {
-	db  *gorm.DB
-	cfg *config.Config
+	db      *gorm.DB
+	llm     *llm.Client
+	bus     *events.Bus
+	prompts *prompts.Registry
+	spend   *LLMSpendService
 }
 
-func NewGlowPlanService(db *gorm.DB, cfg *config.Config) *GlowPlanService {
-	return &GlowPlanService{db: db, cfg: cfg}
+func NewGlowPlanService(db *gorm.DB, llmClient *llm.Client, bus *events.Bus, registry *prompts.Registry, spend *LLMSpendService) *GlowPlanService {
+	return &GlowPlanService{db: db, llm: llmClient, bus: bus, prompts: registry, spend: spend}
 }
 
 func (s *GlowPlanService) GetUserGlowPlans(userID uuid.UUID) ([]models.GlowPlan, error) {
@@ -34,7 +37,9 @@ func (s *GlowPlanService) GetUserGlowPlans(userID uuid.UUID) ([]models.GlowPlan,
 	return plans, err
 }
 
-func (s *GlowPlanService) GenerateGlowPlan(userID, analysisID uuid.UUID) ([]models.GlowPlan, error) {
+// GenerateGlowPlan replaces the user's plan with recommendations for the
+// analysis, written in the user's stored language, else locale.
+func (s *GlowPlanService) GenerateGlowPlan(userID, analysisID uuid.UUID, locale string) ([]models.GlowPlan, error) {
 	// Get the analysis to check it exists and belongs to user
 	var analysis models.FaceAnalysis
 	if err := s.db.Where("id = ? AND user_id = ?", analysisID, userID).First(&analysis).Error; err != nil {
@@ -48,7 +53,8 @@ func (s *GlowPlanService) GenerateGlowPlan(userID, analysisID uuid.UUID) ([]mode
 	s.db.Where("user_id = ?", userID).Delete(&models.GlowPlan{})
 
 	// Generate AI-powered recommendations based on analysis scores
-	recommendations, err := s.generateAIRecommendations(userID, analysisID, &analysis)
+	locale = userLocale(s.db, userID, locale)
+	recommendations, err := s.generateAIRecommendations(userID, analysisID, &analysis, locale)
 	if err != nil {
 		return nil, fmt.Errorf("failed to generate recommendations: %w", err)
 	}
@@ -74,6 +80,7 @@ func (s *GlowPlanService) MarkAsCompleted(planID, userID uuid.UUID, isCompleted
 		return nil, err
 	}
 
+	wasCompleted := plan.IsCompleted
 	plan.IsCompleted = isCompleted
 	if isCompleted {
 		now := time.Now()
@@ -82,7 +89,20 @@ func (s *GlowPlanService) MarkAsCompleted(planID, userID uuid.UUID, isCompleted
 		plan.CompletedAt = nil
 	}
 
-	if err := s.db.Save(&plan).Error; err != nil {
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Save(&plan).Error; err != nil {
+			return err
+		}
+		if !isCompleted || wasCompleted {
+			return nil
+		}
+		return s.bus.Publish(tx, events.PlanItemCompleted{
+			UserID:   plan.UserID,
+			PlanID:   plan.ID,
+			Category: plan.Category,
+		})
+	})
+	if err != nil {
 		return nil, err
 	}
 
@@ -106,6 +126,34 @@ func (s *GlowPlanService) GetUserProgress(userID uuid.UUID) (int, int, error) {
 
 // AI recommendation types
 
+// glowPlanSchema is the reply contract of the glow-plan prompt.
+var glowPlanSchema = &llm.Schema{
+	Title: "glow-plan",
+	Type:  llm.TypeObject,
+	Properties: map[string]*llm.Schema{
+		"recommendations": {
+			Type:     llm.TypeArray,
+			MinItems: llm.Int(5),
+			MaxItems: llm.Int(7),
+			Items: &llm.Schema{
+				Type: llm.TypeObject,
+				Properties: map[string]*llm.Schema{
+					"category":        {Type: llm.TypeString, Enum: []string{"jawline", "skin", "style", "fitness", "grooming"}},
+					"title":           {Type: llm.TypeString, MinLength: llm.Int(1)},
+					"description":     {Type: llm.TypeString, MinLength: llm.Int(1)},
+					"difficulty":      {Type: llm.TypeString, Enum: []string{"easy", "medium", "hard"}},
+					"timeframe_weeks": {Type: llm.TypeInteger, Minimum: llm.Float(1), Maximum: llm.Float(12)},
+					"priority":        {Type: llm.TypeInteger, Minimum: llm.Float(1), Maximum: llm.Float(5)},
+				},
+				Required:             []string{"category", "title", "description", "difficulty", "timeframe_weeks", "priority"},
+				AdditionalProperties: llm.Closed,
+			},
+		},
+	},
+	Required:             []string{"recommendations"},
+	AdditionalProperties: llm.Closed,
+}
+
 type glowPlanAIRecommendation struct {
 	Category       string `json:"category"`
 	Title          string `json:"title"`
@@ -115,148 +163,82 @@ type glowPlanAIRecommendation struct {
 	Priority       int    `json:"priority"`
 }
 
-func (s *GlowPlanService) generateAIRecommendations(userID, analysisID uuid.UUID, analysis *models.FaceAnalysis) ([]models.GlowPlan, error) {
-	if llmPlans, err := s.generateLLMRecommendations(userID, analysisID, analysis); err == nil && len(llmPlans) > 0 {
+func (s *GlowPlanService) generateAIRecommendations(userID, analysisID uuid.UUID, analysis *models.FaceAnalysis, locale string) ([]models.GlowPlan, error) {
+	if llmPlans, err := s.generateLLMRecommendations(userID, analysisID, analysis, locale); err == nil && len(llmPlans) > 0 {
 		return llmPlans, nil
 	}
 
 	// Fallback keeps core flow functional even if upstream LLM provider is unavailable.
-	return s.generateDeterministicRecommendations(userID, analysisID, analysis), nil
+	return s.generateDeterministicRecommendations(userID, analysisID, analysis, locale), nil
 }
 
-func (s *GlowPlanService) generateLLMRecommendations(userID, analysisID uuid.UUID, analysis *models.FaceAnalysis) ([]models.GlowPlan, error) {
-	strengthsList := strings.Join(analysis.Strengths, ", ")
-	improvementsList := strings.Join(analysis.Improvements, ", ")
-
-	prompt := fmt.Sprintf(`User face analysis scores: overall=%.1f, symmetry=%.1f, jawline=%.1f, skin=%.1f, eye=%.1f, nose=%.1f, lips=%.1f, harmony=%.1f. Strengths: %s. Improvements: %s. Generate 5-7 personalized improvement recommendations as a JSON array with fields: category (one of: jawline, skin, style, fitness, grooming), title (short actionable title), description (2-3 sentence detailed advice), difficulty (one of: easy, medium, hard), timeframe_weeks (integer 1-12), priority (integer 1-5 where 5 is highest). Focus recommendations on the lowest-scoring areas. Return ONLY the JSON array, no markdown formatting, no code fences, no extra text.`,
-		analysis.OverallScore,
-		analysis.SymmetryScore,
-		analysis.JawlineScore,
-		analysis.SkinScore,
-		analysis.EyeScore,
-		analysis.NoseScore,
-		analysis.LipsScore,
-		analysis.HarmonyScore,
-		strengthsList,
-		improvementsList,
-	)
-
-	providers := []struct {
-		url   string
-		key   string
-		model string
-	}{
-		{url: strings.TrimSpace(s.cfg.GLMAPIURL), key: strings.TrimSpace(s.cfg.GLMAPIKey), model: strings.TrimSpace(s.cfg.GLMModel)},
-		{url: strings.TrimSpace(s.cfg.DeepSeekAPIURL), key: strings.TrimSpace(s.cfg.DeepSeekAPIKey), model: strings.TrimSpace(s.cfg.DeepSeekModel)},
+// generateLLMRecommendations renders the glow-plan variant assigned to the
+// user and tags the plans with it.
+func (s *GlowPlanService) generateLLMRecommendations(userID, analysisID uuid.UUID, analysis *models.FaceAnalysis, locale string) ([]models.GlowPlan, error) {
+	tmpl, err := s.prompts.Assign(prompts.GlowPlan, userID)
+	if err != nil {
+		return nil, err
 	}
-
-	timeout := s.cfg.LLMTimeout
-	if timeout <= 0 {
-		timeout = 20 * time.Second
+	prompt, err := tmpl.Render(withLanguage(tmpl, prompts.Vars{
+		"overall":      analysis.OverallScore,
+		"symmetry":     analysis.SymmetryScore,
+		"jawline":      analysis.JawlineScore,
+		"skin":         analysis.SkinScore,
+		"eye":          analysis.EyeScore,
+		"nose":         analysis.NoseScore,
+		"lips":         analysis.LipsScore,
+		"harmony":      analysis.HarmonyScore,
+		"strengths":    []string(analysis.Strengths),
+		"improvements": []string(analysis.Improvements),
+	}, locale))
+	if err != nil {
+		return nil, err
 	}
 
-	var lastErr error
-	for _, p := range providers {
-		if p.url == "" || p.key == "" || p.model == "" {
-			continue
-		}
-
-		reqBody := openAIRequest{
-			Model: p.model,
-			Messages: []openAIMessage{
-				{
-					Role:    "system",
-					Content: "You are a personalized beauty and self-improvement advisor. Always return valid JSON only.",
-				},
-				{
-					Role:    "user",
-					Content: prompt,
-				},
-			},
-			MaxTokens:      2000,
-			Temperature:    0.2,
-			ResponseFormat: map[string]string{"type": "json_object"},
-		}
-
-		jsonBody, err := json.Marshal(reqBody)
-		if err != nil {
-			lastErr = err
-			continue
-		}
-
-		client := &http.Client{Timeout: timeout}
-		httpReq, err := http.NewRequest("POST", p.url, bytes.NewReader(jsonBody))
-		if err != nil {
-			lastErr = err
-			continue
-		}
-		httpReq.Header.Set("Content-Type", "application/json")
-		httpReq.Header.Set("Authorization", "Bearer "+p.key)
-
-		resp, err := client.Do(httpReq)
-		if err != nil {
-			lastErr = err
-			continue
-		}
-
-		body, readErr := io.ReadAll(resp.Body)
-		resp.Body.Close()
-		if readErr != nil {
-			lastErr = readErr
-			continue
-		}
-		if resp.StatusCode < 200 || resp.StatusCode >= 300 {
-			lastErr = fmt.Errorf("provider status=%d", resp.StatusCode)
-			continue
-		}
-
-		var llmResp openAIResponse
-		if err := json.Unmarshal(body, &llmResp); err != nil {
-			lastErr = err
-			continue
-		}
-		if len(llmResp.Choices) == 0 {
-			lastErr = errors.New("empty llm choices")
-			continue
-		}
+	// Over budget, the deterministic plan stands in
+	if err := s.spend.CheckBudget(userID); err != nil {
+		return nil, err
+	}
 
-		content := strings.TrimSpace(llmResp.Choices[0].Message.Content)
-		content = strings.TrimPrefix(content, "```json")
-		content = strings.TrimPrefix(content, "```")
-		content = strings.TrimSuffix(content, "```")
-		content = strings.TrimSpace(content)
-
-		var aiRecs []glowPlanAIRecommendation
-		if err := json.Unmarshal([]byte(content), &aiRecs); err != nil {
-			start := strings.Index(content, "[")
-			end := strings.LastIndex(content, "]")
-			if start >= 0 && end > start {
-				if nestedErr := json.Unmarshal([]byte(content[start:end+1]), &aiRecs); nestedErr != nil {
-					lastErr = nestedErr
-					continue
-				}
-			} else {
-				lastErr = err
-				continue
-			}
-		}
+	// Recommendations still invalid after the repair round-trip keep
+	// their valid fields; convertGlowPlanRecommendations fills the rest.
+	resp, err := s.llm.CompleteStructured(context.Background(), llm.Request{
+		System:      prompt.System,
+		Messages:    []llm.Message{{Role: "user", Text: prompt.User}},
+		MaxTokens:   2000,
+		Temperature: 0.2,
+	}, glowPlanSchema)
+	if resp != nil {
+		s.spend.Record(userID, LLMFeatureGlowPlan, resp.Response)
+	}
+	if err != nil {
+		return nil, err
+	}
 
-		plans := convertGlowPlanRecommendations(userID, analysisID, aiRecs)
-		if len(plans) > 0 {
-			return plans, nil
-		}
-		lastErr = errors.New("empty recommendations")
+	var reply struct {
+		Recommendations []glowPlanAIRecommendation `json:"recommendations"`
+	}
+	if err := resp.Decode(&reply); err != nil {
+		return nil, fmt.Errorf("%s: %w", resp.Provider, err)
 	}
 
-	if lastErr != nil {
-		return nil, lastErr
+	plans := convertGlowPlanRecommendations(userID, analysisID, reply.Recommendations, locale)
+	if len(plans) == 0 {
+		return nil, errors.New("empty recommendations")
 	}
-	return nil, errors.New("no llm provider configured")
+	for i := range plans {
+		plans[i].PromptVersion = prompt.ID
+	}
+	return plans, nil
 }
 
-func convertGlowPlanRecommendations(userID, analysisID uuid.UUID, recs []glowPlanAIRecommendation) []models.GlowPlan {
+func convertGlowPlanRecommendations(userID, analysisID uuid.UUID, recs []glowPlanAIRecommendation, locale string) []models.GlowPlan {
 	plans := make([]models.GlowPlan, 0, len(recs))
 	for _, rec := range recs {
+		// Dropped whole by schema validation
+		if rec == (glowPlanAIRecommendation{}) {
+			continue
+		}
 		category := rec.Category
 		if category != "jawline" && category != "skin" && category != "style" && category != "fitness" && category != "grooming" {
 			category = "grooming"
@@ -282,11 +264,11 @@ func convertGlowPlanRecommendations(userID, analysisID uuid.UUID, recs []glowPla
 
 		title := strings.TrimSpace(rec.Title)
 		if title == "" {
-			title = "Personalized improvement step"
+			title = i18n.T(locale, "glow.fallback.title")
 		}
 		description := strings.TrimSpace(rec.Description)
 		if description == "" {
-			description = "Focus on this area consistently and review your progress weekly."
+			description = i18n.T(locale, "glow.fallback.description")
 		}
 
 		plans = append(plans, models.GlowPlan{
@@ -304,14 +286,14 @@ func convertGlowPlanRecommendations(userID, analysisID uuid.UUID, recs []glowPla
 	return plans
 }
 
-func (s *GlowPlanService) generateDeterministicRecommendations(userID, analysisID uuid.UUID, analysis *models.FaceAnalysis) []models.GlowPlan {
+func (s *GlowPlanService) generateDeterministicRecommendations(userID, analysisID uuid.UUID, analysis *models.FaceAnalysis, locale string) []models.GlowPlan {
 	plans := []models.GlowPlan{
 		{
 			UserID:         userID,
 			AnalysisID:     analysisID,
 			Category:       "jawline",
-			Title:          "Daily neck posture routine",
-			Description:    "Spend 10 minutes on neck posture and tongue placement exercises each day. Track consistency for visible structural improvement over time.",
+			Title:          i18n.T(locale, "glow.neck_posture.title"),
+			Description:    i18n.T(locale, "glow.neck_posture.description"),
 			Priority:       5,
 			Difficulty:     "medium",
 			TimeframeWeeks: 8,
@@ -321,8 +303,8 @@ func (s *GlowPlanService) generateDeterministicRecommendations(userID, analysisI
 			UserID:         userID,
 			AnalysisID:     analysisID,
 			Category:       "skin",
-			Title:          "Simple AM/PM skin protocol",
-			Description:    "Use a minimal cleanser-moisturizer-sunscreen stack in the morning and cleanse-moisturize at night. Keep it consistent before adding actives.",
+			Title:          i18n.T(locale, "glow.skin_protocol.title"),
+			Description:    i18n.T(locale, "glow.skin_protocol.description"),
 			Priority:       5,
 			Difficulty:     "easy",
 			TimeframeWeeks: 6,
@@ -332,8 +314,8 @@ func (s *GlowPlanService) generateDeterministicRecommendations(userID, analysisI
 			UserID:         userID,
 			AnalysisID:     analysisID,
 			Category:       "fitness",
-			Title:          "Sleep and hydration baseline",
-			Description:    "Target 7-8 hours of sleep and 2-2.5L daily water intake. Improved recovery directly supports skin quality and facial definition.",
+			Title:          i18n.T(locale, "glow.sleep_hydration.title"),
+			Description:    i18n.T(locale, "glow.sleep_hydration.description"),
 			Priority:       4,
 			Difficulty:     "easy",
 			TimeframeWeeks: 4,
@@ -343,8 +325,8 @@ func (s *GlowPlanService) generateDeterministicRecommendations(userID, analysisI
 			UserID:         userID,
 			AnalysisID:     analysisID,
 			Category:       "grooming",
-			Title:          "Weekly grooming calibration",
-			Description:    "Refine eyebrow shape, maintain consistent beard or clean-shave lines, and keep haircut edges fresh. Small grooming details compound visual impact.",
+			Title:          i18n.T(locale, "glow.grooming.title"),
+			Description:    i18n.T(locale, "glow.grooming.description"),
 			Priority:       3,
 			Difficulty:     "easy",
 			TimeframeWeeks: 4,
@@ -354,8 +336,8 @@ func (s *GlowPlanService) generateDeterministicRecommendations(userID, analysisI
 			UserID:         userID,
 			AnalysisID:     analysisID,
 			Category:       "style",
-			Title:          "Lighting and photo angle routine",
-			Description:    "Practice front-facing natural light photos and a slight above-eye camera angle. Use one repeatable setup to track facial progress reliably.",
+			Title:          i18n.T(locale, "glow.photo_routine.title"),
+			Description:    i18n.T(locale, "glow.photo_routine.description"),
 			Priority:       3,
 			Difficulty:     "easy",
 			TimeframeWeeks: 3,
@@ -0,0 +1,202 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+
+	"github.com/ahmetcoskunkizilkaya/mewify/backend/internal/config"
+	"github.com/ahmetcoskunkizilkaya/mewify/backend/internal/dto"
+	"github.com/ahmetcoskunkizilkaya/mewify/backend/internal/llm"
+	"github.com/ahmetcoskunkizilkaya/mewify/backend/internal/models"
+)
+
+// AI features whose LLM calls are metered
+const (
+	LLMFeatureFaceAnalysis = "face_analysis"
+	LLMFeatureGlowPlan     = "glow_plan"
+	LLMFeatureCalibration  = "calibration"
+)
+
+const llmSpendTopUsers = 10
+
+var ErrLLMBudgetExceeded = errors.New("llm budget exceeded")
+
+// LLMSpendService records the tokens and estimated cost of every AI feature
+// call and enforces the daily and monthly spend caps, globally and per user.
+type LLMSpendService struct {
+	db     *gorm.DB
+	prices llm.PriceTable
+
+	dailyLimit       float64
+	monthlyLimit     float64
+	userDailyLimit   float64
+	userMonthlyLimit float64
+}
+
+func NewLLMSpendService(db *gorm.DB, cfg *config.Config) *LLMSpendService {
+	prices, err := llm.ParsePrices(cfg.LLMPrices)
+	if err != nil {
+		log.Printf("Ignoring LLM_PRICES, every call is recorded at zero cost: %v", err)
+		prices = llm.PriceTable{}
+	}
+	return &LLMSpendService{
+		db:               db,
+		prices:           prices,
+		dailyLimit:       cfg.LLMBudgetDailyUSD,
+		monthlyLimit:     cfg.LLMBudgetMonthlyUSD,
+		userDailyLimit:   cfg.LLMUserBudgetDailyUSD,
+		userMonthlyLimit: cfg.LLMUserBudgetMonthlyUSD,
+	}
+}
+
+// CheckBudget returns ErrLLMBudgetExceeded once spend in the current UTC day
+// or month has reached a cap, globally or for userID. Callers answer with
+// deterministic output instead of calling a provider.
+func (s *LLMSpendService) CheckBudget(userID uuid.UUID) error {
+	day, month := spendPeriods(time.Now())
+	caps := []struct {
+		limit float64
+		since time.Time
+		user  *uuid.UUID
+		name  string
+	}{
+		{s.dailyLimit, day, nil, "global daily"},
+		{s.monthlyLimit, month, nil, "global monthly"},
+		{s.userDailyLimit, day, &userID, "user daily"},
+		{s.userMonthlyLimit, month, &userID, "user monthly"},
+	}
+	for _, c := range caps {
+		if c.limit <= 0 {
+			continue
+		}
+		spent, err := s.spent(c.since, c.user)
+		if err != nil {
+			// Metering trouble should not take AI features down with it
+			log.Printf("LLM budget check failed: %v", err)
+			return nil
+		}
+		if spent >= c.limit {
+			return fmt.Errorf("%w: %s cap of $%.2f reached", ErrLLMBudgetExceeded, c.name, c.limit)
+		}
+	}
+	return nil
+}
+
+// Record stores the usage of one call. Failures are logged, never returned:
+// the call has already been paid for.
+func (s *LLMSpendService) Record(userID uuid.UUID, feature string, resp *llm.Response) {
+	if resp == nil {
+		return
+	}
+	cost, priced := s.prices.Cost(resp.Model, resp.Usage)
+	usage := models.LLMUsage{
+		Feature:          feature,
+		Provider:         resp.Provider,
+		Model:            resp.Model,
+		PromptTokens:     resp.Usage.PromptTokens,
+		CompletionTokens: resp.Usage.CompletionTokens,
+		CostUSD:          cost,
+		Priced:           priced,
+	}
+	if userID != uuid.Nil {
+		usage.UserID = &userID
+	}
+	if err := s.db.Create(&usage).Error; err != nil {
+		log.Printf("Failed to record LLM usage for %s: %v", feature, err)
+	}
+}
+
+// Summary totals spend since the given time, broken down by feature,
+// provider and model, day and top users, alongside the global caps.
+func (s *LLMSpendService) Summary(since time.Time) (*dto.LLMSpendSummary, error) {
+	summary := &dto.LLMSpendSummary{Since: since}
+
+	if err := s.db.Model(&models.LLMUsage{}).
+		Select(`COUNT(*) AS calls,
+			COALESCE(SUM(prompt_tokens), 0) AS prompt_tokens,
+			COALESCE(SUM(completion_tokens), 0) AS completion_tokens,
+			COALESCE(SUM(cost_usd), 0) AS cost_usd,
+			COUNT(*) FILTER (WHERE NOT priced) AS unpriced_calls`).
+		Where("created_at >= ?", since).
+		Scan(&summary.LLMSpendTotals).Error; err != nil {
+		return nil, err
+	}
+
+	groups := []struct {
+		key   string
+		order string
+		limit int
+		dst   *[]dto.LLMSpendGroup
+	}{
+		{"feature", "cost_usd DESC", 0, &summary.ByFeature},
+		{"provider || '/' || model", "cost_usd DESC", 0, &summary.ByModel},
+		{"TO_CHAR(created_at, 'YYYY-MM-DD')", "key", 0, &summary.ByDay},
+		{"CAST(user_id AS text)", "cost_usd DESC", llmSpendTopUsers, &summary.TopUsers},
+	}
+	for _, g := range groups {
+		query := s.db.Model(&models.LLMUsage{}).
+			Select(g.key+` AS key,
+				COUNT(*) AS calls,
+				SUM(prompt_tokens) AS prompt_tokens,
+				SUM(completion_tokens) AS completion_tokens,
+				SUM(cost_usd) AS cost_usd`).
+			Where("created_at >= ?", since).
+			Group("1").
+			Order(g.order)
+		if g.limit > 0 {
+			query = query.Where("user_id IS NOT NULL").Limit(g.limit)
+		}
+		*g.dst = []dto.LLMSpendGroup{}
+		if err := query.Scan(g.dst).Error; err != nil {
+			return nil, err
+		}
+	}
+
+	day, month := spendPeriods(time.Now())
+	for _, b := range []struct {
+		period string
+		limit  float64
+		since  time.Time
+	}{
+		{"daily", s.dailyLimit, day},
+		{"monthly", s.monthlyLimit, month},
+	} {
+		spent, err := s.spent(b.since, nil)
+		if err != nil {
+			return nil, err
+		}
+		summary.Budgets = append(summary.Budgets, dto.LLMBudgetStatus{
+			Period:   b.period,
+			LimitUSD: b.limit,
+			SpentUSD: spent,
+			Exceeded: b.limit > 0 && spent >= b.limit,
+		})
+	}
+	summary.UserDailyLimitUSD = s.userDailyLimit
+	summary.UserMonthlyLimitUSD = s.userMonthlyLimit
+
+	return summary, nil
+}
+
+func (s *LLMSpendService) spent(since time.Time, userID *uuid.UUID) (float64, error) {
+	query := s.db.Model(&models.LLMUsage{}).Where("created_at >= ?", since)
+	if userID != nil {
+		query = query.Where("user_id = ?", *userID)
+	}
+	var total float64
+	err := query.Select("COALESCE(SUM(cost_usd), 0)").Scan(&total).Error
+	return total, err
+}
+
+// spendPeriods returns the start of the current UTC day and month.
+func spendPeriods(now time.Time) (time.Time, time.Time) {
+	now = now.UTC()
+	day := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+	month := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, time.UTC)
+	return day, month
+}
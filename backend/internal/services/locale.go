@@ -0,0 +1,39 @@
+package services
+
+import (
+	"log"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+
+	"github.com/ahmetcoskunkizilkaya/mewify/backend/internal/i18n"
+	"github.com/ahmetcoskunkizilkaya/mewify/backend/internal/models"
+	"github.com/ahmetcoskunkizilkaya/mewify/backend/internal/prompts"
+)
+
+// promptLanguageVar is the variable prompts declare to be answered in the
+// user's language.
+const promptLanguageVar = "language"
+
+// userLocale returns the language stored on the user's profile, else
+// requested (typically the Accept-Language match), else the default. Work
+// done for someone other than the caller passes "" and uses their profile.
+func userLocale(db *gorm.DB, userID uuid.UUID, requested string) string {
+	var stored []string
+	if err := db.Model(&models.User{}).Where("id = ?", userID).Pluck("locale", &stored).Error; err != nil {
+		log.Printf("Failed to load locale of user %s: %v", userID, err)
+	}
+	if len(stored) > 0 {
+		return i18n.Resolve(stored[0], requested)
+	}
+	return i18n.Resolve(requested)
+}
+
+// withLanguage adds the answer language to vars when tmpl declares it, so
+// templates loaded from PROMPT_TEMPLATE_DIR without one keep rendering.
+func withLanguage(tmpl *prompts.Template, vars prompts.Vars, locale string) prompts.Vars {
+	if tmpl.Declares(promptLanguageVar) {
+		vars[promptLanguageVar] = i18n.LanguageName(locale)
+	}
+	return vars
+}
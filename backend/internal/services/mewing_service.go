@@ -1,6 +1,7 @@
 package services
 
 import (
+	"context"
 	"errors"
 	"time"
 
@@ -8,11 +9,13 @@ import (
 	"gorm.io/gorm"
 
 	"github.com/ahmetcoskunkizilkaya/mewify/backend/internal/dto"
+	"github.com/ahmetcoskunkizilkaya/mewify/backend/internal/events"
+	"github.com/ahmetcoskunkizilkaya/mewify/backend/internal/imaging"
 	"github.com/ahmetcoskunkizilkaya/mewify/backend/internal/models"
 )
 
 type MewingService interface {
-	LogProgress(userID uuid.UUID, req dto.LogMewingRequest) (*models.MewingProgress, error)
+	LogProgress(userID uuid.UUID, req dto.LogMewingRequest) (*dto.MewingProgressResponse, error)
 	GetTodayProgress(userID uuid.UUID) (*dto.MewingProgressResponse, error)
 	GetHistory(userID uuid.UUID, days int) ([]dto.HistoryResponse, error)
 	GetStreakInfo(userID uuid.UUID) (*dto.StreakInfoResponse, error)
@@ -21,14 +24,27 @@ type MewingService interface {
 }
 
 type mewingService struct {
-	db *gorm.DB
+	db     *gorm.DB
+	bus    *events.Bus
+	images *imaging.Pipeline
+	blobs  *BlobService
 }
 
-func NewMewingService(db *gorm.DB) MewingService {
-	return &mewingService{db: db}
+func NewMewingService(db *gorm.DB, bus *events.Bus, images *imaging.Pipeline, blobs *BlobService) MewingService {
+	return &mewingService{db: db, bus: bus, images: images, blobs: blobs}
 }
 
-func (s *mewingService) LogProgress(userID uuid.UUID, req dto.LogMewingRequest) (*models.MewingProgress, error) {
+func (s *mewingService) LogProgress(userID uuid.UUID, req dto.LogMewingRequest) (*dto.MewingProgressResponse, error) {
+	// Reject bad photos before touching the day's entry
+	var photo *imaging.Image
+	if req.JawlinePhotoBase64 != "" {
+		img, err := s.images.ProcessBase64(req.JawlinePhotoBase64)
+		if err != nil {
+			return nil, err
+		}
+		photo = img
+	}
+
 	tx := s.db.Begin()
 	defer func() {
 		if r := recover(); r != nil {
@@ -60,12 +76,15 @@ func (s *mewingService) LogProgress(userID uuid.UUID, req dto.LogMewingRequest)
 	err := tx.Where("user_id = ? AND date = ?", userID, today).First(&existingProgress).Error
 
 	var progress models.MewingProgress
+	previousMinutes := 0
+	wasCompleted := false
 	if err == nil {
 		// Update existing
 		progress = existingProgress
+		previousMinutes = existingProgress.MewingMinutes
+		wasCompleted = existingProgress.Completed
 		progress.MewingMinutes = req.MewingMinutes
 		progress.Notes = req.Notes
-		progress.JawlinePhotoURL = req.JawlinePhotoURL
 		progress.Completed = req.MewingMinutes >= goal.DailyMinutesGoal
 
 		// Update streak if status changed to completed
@@ -83,12 +102,11 @@ func (s *mewingService) LogProgress(userID uuid.UUID, req dto.LogMewingRequest)
 	} else if errors.Is(err, gorm.ErrRecordNotFound) {
 		// Create new
 		progress = models.MewingProgress{
-			UserID:          userID,
-			Date:            today,
-			MewingMinutes:   req.MewingMinutes,
-			Notes:           req.Notes,
-			JawlinePhotoURL: req.JawlinePhotoURL,
-			Completed:       req.MewingMinutes >= goal.DailyMinutesGoal,
+			UserID:        userID,
+			Date:          today,
+			MewingMinutes: req.MewingMinutes,
+			Notes:         req.Notes,
+			Completed:     req.MewingMinutes >= goal.DailyMinutesGoal,
 		}
 
 		if err := tx.Create(&progress).Error; err != nil {
@@ -108,11 +126,77 @@ func (s *mewingService) LogProgress(userID uuid.UUID, req dto.LogMewingRequest)
 		return nil, err
 	}
 
+	var blob *models.Blob
+	if photo != nil {
+		if blob, err = s.attachPhoto(tx, &progress, photo); err != nil {
+			tx.Rollback()
+			return nil, err
+		}
+	}
+
+	if err := s.bus.Publish(tx, events.MewingLogged{
+		UserID:        userID,
+		ProgressID:    progress.ID,
+		Date:          progress.Date,
+		TotalMinutes:  progress.MewingMinutes,
+		DeltaMinutes:  progress.MewingMinutes - previousMinutes,
+		GoalCompleted: progress.Completed && !wasCompleted,
+	}); err != nil {
+		tx.Rollback()
+		if blob != nil {
+			s.blobs.discard(blob)
+		}
+		return nil, err
+	}
+
 	if err := tx.Commit().Error; err != nil {
+		if blob != nil {
+			s.blobs.discard(blob)
+		}
+		return nil, err
+	}
+
+	return s.toProgressResponse(&progress), nil
+}
+
+// attachPhoto stores the day's jawline photo, replacing the previous one.
+func (s *mewingService) attachPhoto(tx *gorm.DB, progress *models.MewingProgress, photo *imaging.Image) (*models.Blob, error) {
+	if err := s.blobs.releaseOwner(tx, models.BlobOwnerMewingProgress, progress.ID); err != nil {
+		return nil, err
+	}
+
+	blob, err := s.blobs.upload(context.Background(), progress.UserID, models.BlobOwnerMewingProgress, progress.ID, "jawline", imaging.ContentType, photo.Data)
+	if err != nil {
 		return nil, err
 	}
+	if err := s.blobs.attach(tx, blob); err != nil {
+		s.blobs.discard(blob)
+		return nil, err
+	}
+
+	progress.JawlinePhotoKey = blob.Key
+	if err := tx.Model(progress).Update("jawline_photo_key", blob.Key).Error; err != nil {
+		s.blobs.discard(blob)
+		return nil, err
+	}
+	return blob, nil
+}
 
-	return &progress, nil
+func (s *mewingService) toProgressResponse(progress *models.MewingProgress) *dto.MewingProgressResponse {
+	photoURL := progress.JawlinePhotoURL
+	if progress.JawlinePhotoKey != "" {
+		photoURL = s.blobs.SignedURL(progress.JawlinePhotoKey)
+	}
+
+	return &dto.MewingProgressResponse{
+		ID:              progress.ID,
+		Date:            progress.Date.Format("2006-01-02"),
+		MewingMinutes:   progress.MewingMinutes,
+		Completed:       progress.Completed,
+		Notes:           progress.Notes,
+		JawlinePhotoURL: photoURL,
+		CreatedAt:       progress.CreatedAt,
+	}
 }
 
 func (s *mewingService) updateStreak(tx *gorm.DB, userID uuid.UUID, completedToday bool) error {
@@ -163,15 +247,7 @@ func (s *mewingService) GetTodayProgress(userID uuid.UUID) (*dto.MewingProgressR
 		return nil, err
 	}
 
-	return &dto.MewingProgressResponse{
-		ID:              progress.ID,
-		Date:            progress.Date.Format("2006-01-02"),
-		MewingMinutes:   progress.MewingMinutes,
-		Completed:       progress.Completed,
-		Notes:           progress.Notes,
-		JawlinePhotoURL: progress.JawlinePhotoURL,
-		CreatedAt:       progress.CreatedAt,
-	}, nil
+	return s.toProgressResponse(&progress), nil
 }
 
 func (s *mewingService) GetHistory(userID uuid.UUID, days int) ([]dto.HistoryResponse, error) {
@@ -0,0 +1,52 @@
+package services
+
+import (
+	"encoding/json"
+
+	"github.com/ahmetcoskunkizilkaya/mewify/backend/internal/events"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// RegisterEventHandlers resets decay whenever the user does something that
+// counts as activity.
+func (s *MonetizationService) RegisterEventHandlers(bus *events.Bus) {
+	events.On(bus, func(tx *gorm.DB, e events.AnalysisCreated) error {
+		_, err := s.withDB(tx).UpdateDecayStatus(e.UserID)
+		return err
+	})
+	events.On(bus, func(tx *gorm.DB, e events.MewingLogged) error {
+		_, err := s.withDB(tx).UpdateDecayStatus(e.UserID)
+		return err
+	})
+	events.On(bus, func(tx *gorm.DB, e events.PlanItemCompleted) error {
+		_, err := s.withDB(tx).UpdateDecayStatus(e.UserID)
+		return err
+	})
+}
+
+// withDB returns a copy of the service bound to db, typically an open
+// transaction.
+func (s *MonetizationService) withDB(db *gorm.DB) *MonetizationService {
+	return &MonetizationService{db: db, gamification: s.gamification.withDB(db)}
+}
+
+type gemGrantPayload struct {
+	UserID      uuid.UUID  `json:"user_id"`
+	Amount      int        `json:"amount"`
+	Reason      string     `json:"reason"`
+	Description string     `json:"description"`
+	RelatedID   *uuid.UUID `json:"related_id,omitempty"`
+	RelatedType string     `json:"related_type,omitempty"`
+}
+
+// RegisterOutboxHandlers registers delivery for gem grants.
+func (s *MonetizationService) RegisterOutboxHandlers(outbox *OutboxService) {
+	outbox.Register(OutboxTopicGemGrant, func(tx *gorm.DB, payload json.RawMessage) error {
+		var p gemGrantPayload
+		if err := json.Unmarshal(payload, &p); err != nil {
+			return err
+		}
+		return s.withDB(tx).AddGems(p.UserID, p.Amount, p.Reason, p.Description, p.RelatedID, p.RelatedType)
+	})
+}
@@ -1,21 +1,31 @@
 package services
 
 import (
+	"errors"
 	"fmt"
 	"math/rand"
 	"time"
 
+	"github.com/ahmetcoskunkizilkaya/mewify/backend/internal/i18n"
 	"github.com/ahmetcoskunkizilkaya/mewify/backend/internal/models"
 	"github.com/google/uuid"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var (
+	ErrChestAlreadyOpened    = errors.New("daily chest already opened today")
+	ErrSelfFriendRequest     = errors.New("cannot send a friend request to yourself")
+	ErrFriendRequestNotFound = errors.New("friend request not found")
 )
 
 type MonetizationService struct {
-	db *gorm.DB
+	db           *gorm.DB
+	gamification *GamificationService
 }
 
-func NewMonetizationService(db *gorm.DB) *MonetizationService {
-	return &MonetizationService{db: db}
+func NewMonetizationService(db *gorm.DB, gamification *GamificationService) *MonetizationService {
+	return &MonetizationService{db: db, gamification: gamification}
 }
 
 func (s *MonetizationService) GetDB() *gorm.DB {
@@ -230,7 +240,28 @@ var chestRewards = []struct {
 	{"avatar_item", 3, 1, 14, true}, // Epic avatar item (premium only can be legendary)
 }
 
+// HasOpenedDailyChest reports whether the user already opened today's daily chest.
+func (s *MonetizationService) HasOpenedDailyChest(userID uuid.UUID) (bool, error) {
+	today := time.Now().Truncate(24 * time.Hour)
+
+	var count int64
+	if err := s.db.Model(&models.UserChest{}).
+		Where("user_id = ? AND chest_type = ? AND opened_at >= ?", userID, models.ChestDaily, today).
+		Count(&count).Error; err != nil {
+		return false, err
+	}
+	return count > 0, nil
+}
+
 func (s *MonetizationService) OpenDailyChest(userID uuid.UUID, currentStreak int, isPremium bool) (*models.UserChest, error) {
+	opened, err := s.HasOpenedDailyChest(userID)
+	if err != nil {
+		return nil, err
+	}
+	if opened {
+		return nil, ErrChestAlreadyOpened
+	}
+
 	// Calculate total weight
 	totalWeight := 0
 	eligibleRewards := []struct {
@@ -258,58 +289,84 @@ func (s *MonetizationService) OpenDailyChest(userID uuid.UUID, currentStreak int
 	}
 
 	// Random selection
-	rand.Seed(time.Now().UnixNano())
 	roll := rand.Intn(totalWeight)
 	cumulative := 0
-	var selectedReward struct {
-		RewardType  string
-		RewardValue int
-	}
+	selectedReward := eligibleRewards[0]
 
-	for i, r := range chestRewards {
+	eligibleIdx := 0
+	for _, r := range chestRewards {
 		if r.MinStreak <= currentStreak && (!r.IsPremium || isPremium) {
 			cumulative += r.Weight
 			if roll < cumulative {
-				selectedReward = eligibleRewards[i]
+				selectedReward = eligibleRewards[eligibleIdx]
 				break
 			}
+			eligibleIdx++
 		}
 	}
 
 	// Create chest record
+	now := time.Now()
+	today := now.UTC().Truncate(24 * time.Hour)
 	chest := models.UserChest{
 		UserID:    userID,
 		ChestType: models.ChestDaily,
-		OpenedAt:  time.Now(),
-	}
-
-	if err := s.db.Create(&chest).Error; err != nil {
-		return nil, err
-	}
-
-	// Create reward result
-	result := models.ChestRewardResult{
-		UserChestID: chest.ID,
-		RewardType:  selectedReward.RewardType,
-		RewardValue: selectedReward.RewardValue,
-	}
+		ChestDate: &today,
+		OpenedAt:  now,
+	}
+	var result models.ChestRewardResult
+
+	// The chest, its result and the reward grant commit together; the grant
+	// itself is applied by the outbox dispatcher.
+	// A concurrent request that opened today's chest first wins the unique
+	// index, and this one gets nothing.
+	err = s.db.Transaction(func(tx *gorm.DB) error {
+		created := tx.Clauses(clause.OnConflict{
+			Columns:   []clause.Column{{Name: "user_id"}, {Name: "chest_type"}, {Name: "chest_date"}},
+			DoNothing: true,
+		}).Create(&chest)
+		if created.Error != nil {
+			return created.Error
+		}
+		if created.RowsAffected == 0 {
+			return ErrChestAlreadyOpened
+		}
 
-	if err := s.db.Create(&result).Error; err != nil {
-		return nil, err
-	}
+		result = models.ChestRewardResult{
+			UserChestID: chest.ID,
+			RewardType:  selectedReward.RewardType,
+			RewardValue: selectedReward.RewardValue,
+		}
+		if err := tx.Create(&result).Error; err != nil {
+			return err
+		}
 
-	// Grant the reward
-	switch selectedReward.RewardType {
-	case "gems":
-		if err := s.AddGems(userID, selectedReward.RewardValue, "daily_chest", "Daily chest reward", &chest.ID, "chest"); err != nil {
-			return nil, err
+		switch selectedReward.RewardType {
+		case "gems":
+			return enqueueOutbox(tx, OutboxTopicGemGrant, chest.ID.String(), gemGrantPayload{
+				UserID:      userID,
+				Amount:      selectedReward.RewardValue,
+				Reason:      "daily_chest",
+				Description: "Daily chest reward",
+				RelatedID:   &chest.ID,
+				RelatedType: "chest",
+			})
+		case "xp":
+			return enqueueOutbox(tx, OutboxTopicXPGrant, chest.ID.String(), xpGrantPayload{
+				UserID:      userID,
+				Amount:      selectedReward.RewardValue,
+				Reason:      "daily_chest",
+				ReferenceID: chest.ID.String(),
+				Description: "Daily chest reward",
+			})
+		case "premium_pass":
+			// Grant temporary premium access
+			// This would update user's temporary premium status
 		}
-	case "xp":
-		// Add XP through gamification service
-		// This would call the gamification service
-	case "premium_pass":
-		// Grant temporary premium access
-		// This would update user's temporary premium status
+		return nil
+	})
+	if err != nil {
+		return nil, err
 	}
 
 	chest.Rewards = []models.ChestRewardResult{result}
@@ -376,14 +433,12 @@ func (s *MonetizationService) CheckDecayStatus(userID uuid.UUID) (*models.UserDe
 	return &status, nil
 }
 
-func (s *MonetizationService) GetDecayMessage(decayLevel int) string {
+// GetDecayMessage returns the warning for a decay level in the user's stored
+// language, else locale.
+func (s *MonetizationService) GetDecayMessage(userID uuid.UUID, decayLevel int, locale string) string {
 	switch decayLevel {
-	case 1:
-		return "⏰ You haven't mewed today! Keep your streak alive!"
-	case 2:
-		return "⚠️ Your muscles are starting to relax. Don't lose your progress!"
-	case 3:
-		return "🚨 Your progress is fading! Your level may decrease if you don't practice soon!"
+	case 1, 2, 3:
+		return i18n.T(userLocale(s.db, userID, locale), fmt.Sprintf("decay.%d", decayLevel))
 	default:
 		return ""
 	}
@@ -394,6 +449,10 @@ func (s *MonetizationService) GetDecayMessage(decayLevel int) string {
 // ==========================================
 
 func (s *MonetizationService) SendFriendRequest(requesterID, receiverID uuid.UUID) error {
+	if requesterID == receiverID {
+		return ErrSelfFriendRequest
+	}
+
 	// Check if already connected
 	var existing models.FriendConnection
 	if err := s.db.Where("(requester_id = ? AND receiver_id = ?) OR (requester_id = ? AND receiver_id = ?)",
@@ -409,14 +468,31 @@ func (s *MonetizationService) SendFriendRequest(requesterID, receiverID uuid.UUI
 	return s.db.Create(&connection).Error
 }
 
-func (s *MonetizationService) AcceptFriendRequest(connectionID uuid.UUID) error {
-	now := time.Now()
-	return s.db.Model(&models.FriendConnection{}).
-		Where("id = ?", connectionID).
-		Updates(map[string]interface{}{
+// AcceptFriendRequest accepts a pending request addressed to receiverID and
+// bumps both users' friend counts for the social achievements.
+func (s *MonetizationService) AcceptFriendRequest(receiverID, connectionID uuid.UUID) error {
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		var connection models.FriendConnection
+		if err := tx.Where("id = ? AND receiver_id = ? AND status = ?", connectionID, receiverID, "pending").
+			First(&connection).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return ErrFriendRequestNotFound
+			}
+			return err
+		}
+
+		now := time.Now()
+		if err := tx.Model(&connection).Updates(map[string]interface{}{
 			"status":      "accepted",
 			"accepted_at": now,
-		}).Error
+		}).Error; err != nil {
+			return err
+		}
+
+		return tx.Model(&models.UserGamification{}).
+			Where("user_id IN ?", []uuid.UUID{connection.RequesterID, connection.ReceiverID}).
+			UpdateColumn("friends_count", gorm.Expr("friends_count + 1")).Error
+	})
 }
 
 func (s *MonetizationService) SendNudge(senderID, receiverID uuid.UUID, nudgeType string) error {
@@ -424,7 +500,7 @@ func (s *MonetizationService) SendNudge(senderID, receiverID uuid.UUID, nudgeTyp
 		SenderID:   senderID,
 		ReceiverID: receiverID,
 		NudgeType:  nudgeType,
-		Message:    s.getNudgeMessage(nudgeType),
+		Message:    s.getNudgeMessage(nudgeType, userLocale(s.db, receiverID, "")),
 	}
 
 	if err := s.db.Create(&nudge).Error; err != nil {
@@ -441,16 +517,13 @@ func (s *MonetizationService) SendNudge(senderID, receiverID uuid.UUID, nudgeTyp
 		}).Error
 }
 
-func (s *MonetizationService) getNudgeMessage(nudgeType string) string {
+// getNudgeMessage words a nudge in the receiver's language.
+func (s *MonetizationService) getNudgeMessage(nudgeType, locale string) string {
 	switch nudgeType {
-	case "missed_session":
-		return "Hey! You missed your mewing session today. Let's get back on track! 💪"
-	case "streak_at_risk":
-		return "Your streak is at risk! Don't lose your progress! 🔥"
-	case "encouragement":
-		return "You're doing great! Keep up the good work! ⭐"
+	case "missed_session", "streak_at_risk", "encouragement":
+		return i18n.T(locale, "nudge."+nudgeType)
 	default:
-		return "Time to mew! Your friend is waiting for you! 😤"
+		return i18n.T(locale, "nudge.default")
 	}
 }
 
@@ -558,7 +631,9 @@ func (s *MonetizationService) SeedIntroOffers() error {
 	}
 
 	for _, offer := range offers {
-		s.db.FirstOrCreate(&offer, models.IntroOffer{OfferType: offer.OfferType})
+		if err := s.db.FirstOrCreate(&offer, models.IntroOffer{OfferType: offer.OfferType}).Error; err != nil {
+			return err
+		}
 	}
 
 	return nil
@@ -586,7 +661,9 @@ func (s *MonetizationService) SeedAvatarItems() error {
 	}
 
 	for _, item := range items {
-		s.db.FirstOrCreate(&item, models.AvatarItem{Name: item.Name})
+		if err := s.db.FirstOrCreate(&item, models.AvatarItem{Name: item.Name}).Error; err != nil {
+			return err
+		}
 	}
 
 	return nil
@@ -0,0 +1,259 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"github.com/ahmetcoskunkizilkaya/mewify/backend/internal/config"
+	"github.com/ahmetcoskunkizilkaya/mewify/backend/internal/models"
+)
+
+// Outbox topics
+const (
+	OutboxTopicActivityReward = "gamification.activity_reward"
+	OutboxTopicMewingReward   = "gamification.mewing_reward"
+	OutboxTopicXPGrant        = "gamification.xp_grant"
+	OutboxTopicGemGrant       = "monetization.gem_grant"
+	OutboxTopicNotification   = "notification.create"
+	OutboxTopicBlobDelete     = "storage.blob_delete"
+)
+
+const (
+	outboxBaseBackoff = 5 * time.Second
+	outboxMaxBackoff  = time.Hour
+)
+
+var (
+	ErrOutboxMessageNotFound = errors.New("outbox message not found")
+)
+
+// OutboxHandler delivers one message. It runs inside the transaction that
+// marks the message delivered, so database side effects are applied exactly
+// once.
+type OutboxHandler func(tx *gorm.DB, payload json.RawMessage) error
+
+type OutboxService struct {
+	db          *gorm.DB
+	batchSize   int
+	maxAttempts int
+
+	mu       sync.RWMutex
+	handlers map[string]OutboxHandler
+}
+
+func NewOutboxService(db *gorm.DB, cfg *config.Config) *OutboxService {
+	s := &OutboxService{
+		db:          db,
+		batchSize:   cfg.OutboxBatchSize,
+		maxAttempts: cfg.OutboxMaxAttempts,
+		handlers:    make(map[string]OutboxHandler),
+	}
+	if s.batchSize <= 0 {
+		s.batchSize = 50
+	}
+	if s.maxAttempts <= 0 {
+		s.maxAttempts = 1
+	}
+	return s
+}
+
+// enqueueOutbox records a side effect in tx. Messages sharing an idempotency
+// key are recorded once, so replaying the same business change is harmless.
+// An empty key always enqueues.
+func enqueueOutbox(tx *gorm.DB, topic, key string, payload interface{}) error {
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return fmt.Errorf("failed to encode %s payload: %w", topic, err)
+	}
+	if key == "" {
+		key = uuid.NewString()
+	}
+
+	msg := models.OutboxMessage{
+		Topic:          topic,
+		IdempotencyKey: topic + ":" + key,
+		Payload:        data,
+		Status:         models.OutboxPending,
+		NextAttemptAt:  time.Now(),
+	}
+	return tx.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "idempotency_key"}},
+		DoNothing: true,
+	}).Create(&msg).Error
+}
+
+// Register sets the handler for a topic.
+func (s *OutboxService) Register(topic string, handler OutboxHandler) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.handlers[topic] = handler
+}
+
+// Run polls for due messages until ctx is cancelled.
+func (s *OutboxService) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		if _, err := s.DispatchPending(ctx); err != nil {
+			log.Printf("Outbox dispatch failed: %v", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// DispatchPending delivers up to one batch of due messages and returns how
+// many were processed.
+func (s *OutboxService) DispatchPending(ctx context.Context) (int, error) {
+	processed := 0
+	for processed < s.batchSize {
+		if ctx.Err() != nil {
+			return processed, nil
+		}
+
+		ok, err := s.dispatchNext()
+		if err != nil {
+			return processed, err
+		}
+		if !ok {
+			break
+		}
+		processed++
+	}
+	return processed, nil
+}
+
+// dispatchNext claims the oldest due message and delivers it. SKIP LOCKED lets
+// several instances run the dispatcher without handing out the same message.
+func (s *OutboxService) dispatchNext() (bool, error) {
+	found := false
+
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		var messages []models.OutboxMessage
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE", Options: "SKIP LOCKED"}).
+			Where("status = ? AND next_attempt_at <= ?", models.OutboxPending, time.Now()).
+			Order("next_attempt_at, created_at").
+			Limit(1).
+			Find(&messages).Error; err != nil {
+			return err
+		}
+		if len(messages) == 0 {
+			return nil
+		}
+		found = true
+		msg := messages[0]
+
+		// The handler runs under a savepoint so a failure undoes its writes
+		// while the failed attempt itself is still recorded.
+		deliverErr := tx.Transaction(func(htx *gorm.DB) error {
+			return s.deliver(htx, &msg)
+		})
+		if deliverErr != nil {
+			return s.recordFailure(tx, &msg, deliverErr)
+		}
+
+		now := time.Now()
+		return tx.Model(&msg).Updates(map[string]interface{}{
+			"status":       models.OutboxDelivered,
+			"attempts":     msg.Attempts + 1,
+			"delivered_at": now,
+			"last_error":   "",
+		}).Error
+	})
+
+	return found, err
+}
+
+func (s *OutboxService) deliver(tx *gorm.DB, msg *models.OutboxMessage) (err error) {
+	s.mu.RLock()
+	handler, ok := s.handlers[msg.Topic]
+	s.mu.RUnlock()
+	if !ok {
+		return fmt.Errorf("no handler registered for topic %q", msg.Topic)
+	}
+
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("handler panic: %v", r)
+		}
+	}()
+	return handler(tx, msg.Payload)
+}
+
+// recordFailure schedules a retry with exponential backoff, or dead-letters
+// the message once it has used all of its attempts.
+func (s *OutboxService) recordFailure(tx *gorm.DB, msg *models.OutboxMessage, cause error) error {
+	attempts := msg.Attempts + 1
+	updates := map[string]interface{}{
+		"attempts":   attempts,
+		"last_error": cause.Error(),
+	}
+
+	if attempts >= s.maxAttempts {
+		updates["status"] = models.OutboxDead
+		log.Printf("Outbox message %s (%s) dead-lettered after %d attempts: %v", msg.ID, msg.Topic, attempts, cause)
+	} else {
+		updates["next_attempt_at"] = time.Now().Add(outboxBackoff(attempts))
+	}
+
+	return tx.Model(msg).Updates(updates).Error
+}
+
+func outboxBackoff(attempts int) time.Duration {
+	backoff := outboxBaseBackoff
+	for i := 1; i < attempts; i++ {
+		backoff *= 2
+		if backoff >= outboxMaxBackoff {
+			return outboxMaxBackoff
+		}
+	}
+	return backoff
+}
+
+// ListDeadLetters returns dead-lettered messages, newest first.
+func (s *OutboxService) ListDeadLetters(limit, offset int) ([]models.OutboxMessage, int64, error) {
+	var messages []models.OutboxMessage
+	var total int64
+
+	query := s.db.Model(&models.OutboxMessage{}).Where("status = ?", models.OutboxDead)
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+	if err := query.Order("updated_at DESC").Limit(limit).Offset(offset).Find(&messages).Error; err != nil {
+		return nil, 0, err
+	}
+	return messages, total, nil
+}
+
+// RetryDeadLetter puts a dead-lettered message back in the queue with a fresh
+// set of attempts.
+func (s *OutboxService) RetryDeadLetter(id uuid.UUID) error {
+	result := s.db.Model(&models.OutboxMessage{}).
+		Where("id = ? AND status = ?", id, models.OutboxDead).
+		Updates(map[string]interface{}{
+			"status":          models.OutboxPending,
+			"attempts":        0,
+			"next_attempt_at": time.Now(),
+		})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrOutboxMessageNotFound
+	}
+	return nil
+}
@@ -1,6 +1,7 @@
 package services
 
 import (
+	"errors"
 	"fmt"
 	"time"
 
@@ -9,12 +10,15 @@ import (
 	"gorm.io/gorm"
 )
 
+var ErrExerciseNotFound = errors.New("exercise not found")
+
 type PremiumContentService struct {
-	db *gorm.DB
+	db            *gorm.DB
+	subscriptions *SubscriptionService
 }
 
-func NewPremiumContentService(db *gorm.DB) *PremiumContentService {
-	return &PremiumContentService{db: db}
+func NewPremiumContentService(db *gorm.DB, subscriptions *SubscriptionService) *PremiumContentService {
+	return &PremiumContentService{db: db, subscriptions: subscriptions}
 }
 
 // ==========================================
@@ -222,12 +226,15 @@ func (s *PremiumContentService) generatePlanInsights(focusAreas []string, level
 // PERSONALIZED TIPS
 // ==========================================
 
-func (s *PremiumContentService) GetPersonalizedTips(userID uuid.UUID, limit int) ([]models.PersonalizedTip, error) {
+func (s *PremiumContentService) GetPersonalizedTips(userID uuid.UUID, limit int, isPremium bool) ([]models.PersonalizedTip, error) {
 	var tips []models.PersonalizedTip
 
 	// Get existing tips that haven't been shown recently
-	err := s.db.Where("user_id = ? AND is_active = ?", userID, true).
-		Order("priority DESC, RANDOM()").
+	query := s.db.Where("user_id = ? AND is_active = ?", userID, true)
+	if !isPremium {
+		query = query.Where("is_premium = ?", false)
+	}
+	err := query.Order("priority DESC, RANDOM()").
 		Limit(limit).
 		Find(&tips).Error
 
@@ -237,8 +244,12 @@ func (s *PremiumContentService) GetPersonalizedTips(userID uuid.UUID, limit int)
 
 	// Generate new tips if needed
 	if len(tips) < limit {
-		newTips := s.generatePersonalizedTips(userID, limit-len(tips))
-		tips = append(tips, newTips...)
+		for _, tip := range s.generatePersonalizedTips(userID, limit-len(tips)) {
+			if tip.IsPremium && !isPremium {
+				continue
+			}
+			tips = append(tips, tip)
+		}
 	}
 
 	return tips, nil
@@ -252,9 +263,9 @@ func (s *PremiumContentService) generatePersonalizedTips(userID uuid.UUID, count
 	s.db.Where("user_id = ?", userID).First(&gamification)
 
 	var mewingToday struct{ Minutes int }
-	s.db.Table("mewing_logs").
+	s.db.Model(&models.MewingProgress{}).
 		Select("COALESCE(SUM(mewing_minutes), 0) as minutes").
-		Where("user_id = ? AND date = ?", userID, time.Now().Truncate(24*time.Hour)).
+		Where("user_id = ? AND date = ?", userID, time.Now().UTC().Truncate(24*time.Hour)).
 		Scan(&mewingToday)
 
 	// Generate contextual tips
@@ -285,6 +296,7 @@ func (s *PremiumContentService) generatePersonalizedTips(userID uuid.UUID, count
 	}
 
 	// Technique tips
+	showAt := []models.TimeOfDay{models.Morning, models.Afternoon, models.Evening, models.Night}
 	techniqueTips := []struct {
 		title, desc, icon string
 	}{
@@ -308,7 +320,7 @@ func (s *PremiumContentService) generatePersonalizedTips(userID uuid.UUID, count
 			Category:    "technique",
 			Priority:    3,
 			BasedOn:     "rotating_technique",
-			ShowAt:      models.TimeOfDay((i % 4) + 1),
+			ShowAt:      showAt[i%len(showAt)],
 			IsPremium:   i >= 3, // First 3 free, rest premium
 		})
 	}
@@ -347,8 +359,16 @@ func (s *PremiumContentService) GetExercises(exerciseType models.ExerciseType, i
 
 func (s *PremiumContentService) GetExerciseByID(exerciseID uuid.UUID) (*models.MewingExercise, error) {
 	var exercise models.MewingExercise
-	err := s.db.Preload("Instructions").First(&exercise, exerciseID).Error
-	return &exercise, err
+	err := s.db.Preload("Instructions", func(db *gorm.DB) *gorm.DB {
+		return db.Order("step_number")
+	}).Where("id = ? AND is_active = ?", exerciseID, true).First(&exercise).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrExerciseNotFound
+		}
+		return nil, err
+	}
+	return &exercise, nil
 }
 
 // ==========================================
@@ -356,28 +376,11 @@ func (s *PremiumContentService) GetExerciseByID(exerciseID uuid.UUID) (*models.M
 // ==========================================
 
 func (s *PremiumContentService) IsPremium(userID uuid.UUID) bool {
-	var subscription models.UserSubscription
-	err := s.db.Where("user_id = ? AND is_active = ? AND (end_date IS NULL OR end_date > ?)",
-		userID, true, time.Now()).
-		First(&subscription).Error
-	return err == nil
+	return s.subscriptions.IsPremium(userID)
 }
 
 func (s *PremiumContentService) GetSubscriptionTier(userID uuid.UUID) (*models.SubscriptionTier, error) {
-	var subscription models.UserSubscription
-	err := s.db.Where("user_id = ? AND is_active = ? AND (end_date IS NULL OR end_date > ?)",
-		userID, true, time.Now()).
-		Preload("Tier").
-		First(&subscription).Error
-
-	if err != nil {
-		// Return free tier
-		var freeTier models.SubscriptionTier
-		s.db.Where("code = ?", "free").First(&freeTier)
-		return &freeTier, nil
-	}
-
-	return &subscription.Tier, nil
+	return s.subscriptions.Tier(userID)
 }
 
 // ==========================================
@@ -487,7 +490,9 @@ func (s *PremiumContentService) SeedExercises() error {
 	}
 
 	for _, exercise := range exercises {
-		s.db.FirstOrCreate(&exercise, models.MewingExercise{Name: exercise.Name})
+		if err := s.db.FirstOrCreate(&exercise, models.MewingExercise{Name: exercise.Name}).Error; err != nil {
+			return err
+		}
 	}
 
 	return nil
@@ -542,7 +547,9 @@ func (s *PremiumContentService) SeedSubscriptionTiers() error {
 	}
 
 	for _, tier := range tiers {
-		s.db.FirstOrCreate(&tier, models.SubscriptionTier{Code: tier.Code})
+		if err := s.db.FirstOrCreate(&tier, models.SubscriptionTier{Code: tier.Code}).Error; err != nil {
+			return err
+		}
 	}
 
 	return nil
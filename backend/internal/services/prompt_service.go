@@ -0,0 +1,104 @@
+package services
+
+import (
+	"gorm.io/gorm"
+
+	"github.com/ahmetcoskunkizilkaya/mewify/backend/internal/dto"
+	"github.com/ahmetcoskunkizilkaya/mewify/backend/internal/models"
+	"github.com/ahmetcoskunkizilkaya/mewify/backend/internal/prompts"
+)
+
+// PromptService loads database-managed prompt templates into the registry
+// and reports how each variant performs.
+type PromptService struct {
+	db       *gorm.DB
+	registry *prompts.Registry
+}
+
+func NewPromptService(db *gorm.DB, registry *prompts.Registry) *PromptService {
+	return &PromptService{db: db, registry: registry}
+}
+
+// Load replaces the registry's overrides with the active template rows, so a
+// reload also drops rows that were deactivated or deleted. Rows shadow file
+// templates with the same name and version. Rows that fail to compile are
+// reported and the rest still load.
+func (s *PromptService) Load() error {
+	var rows []models.PromptTemplate
+	if err := s.db.Where("active = ?", true).Order("name, version").Find(&rows).Error; err != nil {
+		return err
+	}
+
+	templates := make([]prompts.Template, 0, len(rows))
+	for _, row := range rows {
+		templates = append(templates, prompts.Template{
+			Name:      row.Name,
+			Version:   row.Version,
+			Weight:    row.Weight,
+			System:    row.System,
+			User:      row.User,
+			Variables: row.Variables,
+		})
+	}
+	return s.registry.Override(templates)
+}
+
+// Templates lists every registered variant with its weight.
+func (s *PromptService) Templates() []dto.PromptTemplateResponse {
+	var templates []dto.PromptTemplateResponse
+	for _, name := range s.registry.Names() {
+		for _, t := range s.registry.Variants(name) {
+			templates = append(templates, dto.PromptTemplateResponse{
+				ID:        t.ID(),
+				Name:      t.Name,
+				Version:   t.Version,
+				Weight:    t.Weight,
+				Variables: t.Variables,
+			})
+		}
+	}
+	return templates
+}
+
+// Stats compares prompt variants: the score distribution of AI-scored
+// analyses per face-analysis version, and the completion rate of plans per
+// glow-plan version.
+func (s *PromptService) Stats() (*dto.PromptStatsResponse, error) {
+	stats := &dto.PromptStatsResponse{
+		Analyses: []dto.AnalysisPromptStats{},
+		Plans:    []dto.PlanPromptStats{},
+	}
+
+	if err := s.db.Model(&models.FaceAnalysis{}).
+		Select(`prompt_version,
+			COUNT(*) AS analyses,
+			AVG(overall_score) AS avg_overall,
+			COALESCE(STDDEV_POP(overall_score), 0) AS stddev_overall,
+			MIN(overall_score) AS min_overall,
+			MAX(overall_score) AS max_overall`).
+		Where("source = ? AND prompt_version <> ''", models.AnalysisSourceLLM).
+		Group("prompt_version").
+		Order("prompt_version").
+		Scan(&stats.Analyses).Error; err != nil {
+		return nil, err
+	}
+
+	if err := s.db.Model(&models.GlowPlan{}).
+		Select(`prompt_version,
+			COUNT(*) AS plans,
+			COUNT(DISTINCT user_id) AS users,
+			COUNT(*) FILTER (WHERE is_completed) AS completed`).
+		Where("prompt_version <> ''").
+		Group("prompt_version").
+		Order("prompt_version").
+		Scan(&stats.Plans).Error; err != nil {
+		return nil, err
+	}
+	for i := range stats.Plans {
+		if stats.Plans[i].Plans > 0 {
+			stats.Plans[i].CompletionRate = float64(stats.Plans[i].Completed) / float64(stats.Plans[i].Plans)
+		}
+	}
+
+	return stats, nil
+}
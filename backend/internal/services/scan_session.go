@@ -0,0 +1,161 @@
+package services
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+
+	"github.com/ahmetcoskunkizilkaya/mewify/backend/internal/dto"
+
+	"github.com/ahmetcoskunkizilkaya/mewify/backend/internal/imaging"
+	"github.com/ahmetcoskunkizilkaya/mewify/backend/internal/models"
+)
+
+// ScanImageError is an ingestion or quality rejection of one photo of a
+// multi-angle scan, naming its angle.
+type ScanImageError struct {
+	Angle string
+	Err   error
+}
+
+func (e *ScanImageError) Error() string {
+	return fmt.Sprintf("%s photo: %v", e.Angle, e.Err)
+}
+
+func (e *ScanImageError) Unwrap() error {
+	return e.Err
+}
+
+func isScanAngle(angle string) bool {
+	for _, a := range models.ScanAngles {
+		if a == angle {
+			return true
+		}
+	}
+	return false
+}
+
+// scoreSession scores and stores the profile photos of a multi-angle job
+// whose front photo has been scored into analysis and stored, then folds them
+// into the analysis' summary scores. Profile photos belong to the summary
+// analysis, so deleting it deletes them too. Like the profile results, the
+// front result keeps the raw scores; only the summary is calibrated. On error
+// the profile photos already uploaded are discarded.
+func (s *AnalysisJobService) scoreSession(ctx context.Context, job *models.AnalysisJob, analysis *models.FaceAnalysis) (*models.ScanSession, []*models.Blob, error) {
+	session := &models.ScanSession{
+		ID:         uuid.New(),
+		UserID:     job.UserID,
+		AnalysisID: analysis.ID,
+	}
+	session.Angles = append(session.Angles, models.ScanAngleResult{
+		ID:          uuid.New(),
+		SessionID:   session.ID,
+		Angle:       models.ScanAngleFront,
+		ImageKey:    analysis.ImageKey,
+		ImageSHA256: analysis.ImageSHA256,
+		Scores: map[string]float64{
+			"overall":  analysis.OverallScore,
+			"symmetry": analysis.SymmetryScore,
+			"jawline":  analysis.JawlineScore,
+			"skin":     analysis.SkinScore,
+			"eye":      analysis.EyeScore,
+			"nose":     analysis.NoseScore,
+			"lips":     analysis.LipsScore,
+			"harmony":  analysis.HarmonyScore,
+		},
+		Source:        analysis.Source,
+		Provider:      analysis.Provider,
+		Model:         analysis.Model,
+		PromptVersion: analysis.PromptVersion,
+		FallbackError: analysis.FallbackError,
+	})
+
+	var blobs []*models.Blob
+	var profiles []*models.ScanAngleResult
+	for _, angle := range models.ScanAngles {
+		encoded, ok := job.ProfileImages[angle]
+		if !ok || angle == models.ScanAngleFront {
+			continue
+		}
+		data, err := base64.StdEncoding.DecodeString(encoded)
+		if err != nil {
+			s.discardAll(blobs)
+			return nil, nil, fmt.Errorf("stored %s image is not valid base64: %w", angle, err)
+		}
+
+		result := s.ai.scoreProfile(ctx, job.UserID, angle, encoded, job.Locale)
+		blob, err := s.blobs.upload(context.Background(), job.UserID, models.BlobOwnerFaceAnalysis, analysis.ID, "analyses", imaging.ContentType, data)
+		if err != nil {
+			s.discardAll(blobs)
+			return nil, nil, fmt.Errorf("failed to store %s image: %w", angle, err)
+		}
+		blobs = append(blobs, blob)
+
+		sum := sha256.Sum256(data)
+		result.SessionID = session.ID
+		result.ImageKey = blob.Key
+		result.ImageSHA256 = hex.EncodeToString(sum[:])
+		profiles = append(profiles, result)
+	}
+	for _, p := range profiles {
+		session.Angles = append(session.Angles, *p)
+	}
+
+	summarizeSession(session, analysis, profiles)
+	analysis.SessionID = &session.ID
+	return session, blobs, nil
+}
+
+func (s *AnalysisJobService) discardAll(blobs []*models.Blob) {
+	for _, blob := range blobs {
+		s.blobs.discard(blob)
+	}
+}
+
+var ErrScanSessionNotFound = errors.New("scan session not found")
+
+// GetScanSession returns one of a user's multi-angle scans with its summary
+// analysis and per-angle results, front first.
+func (s *faceAnalysisService) GetScanSession(sessionID, userID uuid.UUID) (*dto.ScanSessionResponse, error) {
+	var session models.ScanSession
+	err := s.db.Preload("Analysis").Preload("Angles").
+		Where("id = ? AND user_id = ?", sessionID, userID).
+		First(&session).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrScanSessionNotFound
+		}
+		return nil, err
+	}
+
+	response := &dto.ScanSessionResponse{
+		ID:                  session.ID,
+		Summary:             s.toResponse(&session.Analysis),
+		JawAngleScore:       session.JawAngleScore,
+		ChinProjectionScore: session.ChinProjectionScore,
+		NeckPostureScore:    session.NeckPostureScore,
+		Angles:              make([]dto.ScanAngleResponse, 0, len(session.Angles)),
+		CreatedAt:           session.CreatedAt,
+	}
+	for _, angle := range models.ScanAngles {
+		for _, result := range session.Angles {
+			if result.Angle != angle {
+				continue
+			}
+			response.Angles = append(response.Angles, dto.ScanAngleResponse{
+				Angle:        result.Angle,
+				ImageURL:     s.blobs.SignedURL(result.ImageKey),
+				Scores:       result.Scores,
+				Observations: result.Observations,
+				IsEstimate:   result.Source == models.AnalysisSourceDeterministic,
+			})
+		}
+	}
+	return response, nil
+}
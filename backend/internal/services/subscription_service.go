@@ -3,21 +3,49 @@ package services
 import (
 	"errors"
 	"fmt"
+	"log"
 	"strings"
 	"time"
 
+	"github.com/ahmetcoskunkizilkaya/mewify/backend/internal/config"
 	"github.com/ahmetcoskunkizilkaya/mewify/backend/internal/dto"
+	"github.com/ahmetcoskunkizilkaya/mewify/backend/internal/events"
 	"github.com/ahmetcoskunkizilkaya/mewify/backend/internal/models"
 	"github.com/google/uuid"
 	"gorm.io/gorm"
 )
 
+// Subscription tier codes
+const (
+	TierFree    = "free"
+	TierPremium = "premium"
+	TierVIP     = "vip"
+)
+
 type SubscriptionService struct {
-	db *gorm.DB
+	db  *gorm.DB
+	bus *events.Bus
+	// tierProducts maps RevenueCat product IDs to tier codes
+	tierProducts map[string]string
 }
 
-func NewSubscriptionService(db *gorm.DB) *SubscriptionService {
-	return &SubscriptionService{db: db}
+func NewSubscriptionService(db *gorm.DB, bus *events.Bus, cfg *config.Config) *SubscriptionService {
+	return &SubscriptionService{db: db, bus: bus, tierProducts: parseTierProducts(cfg.RevenueCatProducts)}
+}
+
+// parseTierProducts reads "product:tier" pairs separated by commas, skipping
+// malformed ones.
+func parseTierProducts(list string) map[string]string {
+	products := make(map[string]string)
+	for _, pair := range strings.Split(list, ",") {
+		product, tier, ok := strings.Cut(pair, ":")
+		product, tier = strings.TrimSpace(product), strings.ToLower(strings.TrimSpace(tier))
+		if !ok || product == "" || tier == "" {
+			continue
+		}
+		products[product] = tier
+	}
+	return products
 }
 
 func (s *SubscriptionService) HandleWebhookEvent(event *dto.RevenueCatEvent) error {
@@ -33,18 +61,80 @@ func (s *SubscriptionService) HandleWebhookEvent(event *dto.RevenueCatEvent) err
 		// Ignore unknown event types (RevenueCat adds new ones over time).
 		return nil
 	}
-	return s.upsertSubscription(event, status)
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		return s.upsertSubscription(tx, event, status)
+	})
+}
+
+// TierCode resolves the user's subscription tier. It is the one entitlement
+// check: premium status, scan quotas and tier-gated content all follow it.
+// An active tier assignment wins; otherwise the active RevenueCat
+// subscription written by the webhook decides through the configured product
+// mapping. Products missing from it still get premium, since the user is
+// paying.
+func (s *SubscriptionService) TierCode(userID uuid.UUID) (string, error) {
+	var assigned models.UserSubscription
+	err := s.db.Where("user_id = ? AND is_active = ? AND (end_date IS NULL OR end_date > ?)",
+		userID, true, time.Now()).
+		Preload("Tier").
+		First(&assigned).Error
+	if err == nil && assigned.Tier.Code != "" {
+		return assigned.Tier.Code, nil
+	}
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		return "", err
+	}
+
+	var sub models.Subscription
+	err = s.db.Where("user_id = ? AND status = ? AND current_period_end > ?", userID, "active", time.Now().UTC()).
+		Order("current_period_end DESC").
+		First(&sub).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return TierFree, nil
+	}
+	if err != nil {
+		return "", err
+	}
+	if tier, ok := s.tierProducts[sub.ProductID]; ok {
+		return tier, nil
+	}
+	log.Printf("Product %q has no tier in REVENUECAT_TIER_PRODUCTS; granting premium", sub.ProductID)
+	return TierPremium, nil
+}
+
+// IsPremium reports whether the user is on a paid tier. Lookup failures
+// count as free.
+func (s *SubscriptionService) IsPremium(userID uuid.UUID) bool {
+	code, err := s.TierCode(userID)
+	if err != nil {
+		log.Printf("Failed to resolve tier of user %s: %v", userID, err)
+		return false
+	}
+	return code != TierFree
 }
 
-func (s *SubscriptionService) upsertSubscription(event *dto.RevenueCatEvent, status string) error {
+// Tier returns the SubscriptionTier row of the user's tier.
+func (s *SubscriptionService) Tier(userID uuid.UUID) (*models.SubscriptionTier, error) {
+	code, err := s.TierCode(userID)
+	if err != nil {
+		return nil, err
+	}
+	var tier models.SubscriptionTier
+	if err := s.db.Where("code = ?", code).First(&tier).Error; err != nil {
+		return nil, err
+	}
+	return &tier, nil
+}
+
+func (s *SubscriptionService) upsertSubscription(tx *gorm.DB, event *dto.RevenueCatEvent, status string) error {
 	var sub models.Subscription
 	var err error
 
 	originalTx := strings.TrimSpace(event.OriginalTransactionID)
 	if originalTx != "" {
-		err = s.db.Where("original_transaction_id = ?", originalTx).First(&sub).Error
+		err = tx.Where("original_transaction_id = ?", originalTx).First(&sub).Error
 	} else {
-		err = s.db.Where("revenuecat_id = ?", event.AppUserID).First(&sub).Error
+		err = tx.Where("revenuecat_id = ?", event.AppUserID).First(&sub).Error
 	}
 
 	if errors.Is(err, gorm.ErrRecordNotFound) {
@@ -68,7 +158,10 @@ func (s *SubscriptionService) upsertSubscription(event *dto.RevenueCatEvent, sta
 			sub.UserID = userID
 		}
 
-		return s.db.Create(&sub).Error
+		if err := tx.Create(&sub).Error; err != nil {
+			return err
+		}
+		return s.publishChange(tx, &sub, "")
 	}
 	if err != nil {
 		return fmt.Errorf("failed to lookup subscription: %w", err)
@@ -94,7 +187,31 @@ func (s *SubscriptionService) upsertSubscription(event *dto.RevenueCatEvent, sta
 		updates["user_id"] = *userID
 	}
 
-	return s.db.Model(&sub).Updates(updates).Error
+	previousStatus := sub.Status
+	if err := tx.Model(&sub).Updates(updates).Error; err != nil {
+		return err
+	}
+	sub.Status = status
+	sub.ProductID = event.ProductID
+	if userID, ok := updates["user_id"].(uuid.UUID); ok {
+		sub.UserID = &userID
+	}
+	return s.publishChange(tx, &sub, previousStatus)
+}
+
+// publishChange raises SubscriptionChanged once the subscription is linked to a
+// user; anonymous RevenueCat IDs have nobody to notify yet.
+func (s *SubscriptionService) publishChange(tx *gorm.DB, sub *models.Subscription, previousStatus string) error {
+	if sub.UserID == nil {
+		return nil
+	}
+	return s.bus.Publish(tx, events.SubscriptionChanged{
+		UserID:         *sub.UserID,
+		SubscriptionID: sub.ID,
+		ProductID:      sub.ProductID,
+		PreviousStatus: previousStatus,
+		Status:         sub.Status,
+	})
 }
 
 func (s *SubscriptionService) lookupUserID(appUserID, originalAppUserID string) *uuid.UUID {
@@ -1,79 +1,107 @@
 package services
 
 import (
+	"errors"
 	"time"
 
+	"github.com/ahmetcoskunkizilkaya/mewify/backend/internal/config"
 	"github.com/ahmetcoskunkizilkaya/mewify/backend/internal/models"
 	"github.com/google/uuid"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 const FreeDailyLimit = 3
 
+var ErrUsageLimitReached = errors.New("daily scan limit reached")
+
+// UsageService meters daily scans. A scan is reserved when an analysis
+// starts, then committed if the user got a result worth paying for or
+// released if not, so outages never eat into the free quota.
 type UsageService struct {
-	db *gorm.DB
+	db            *gorm.DB
+	subscriptions *SubscriptionService
+	// fallbackConsumesQuota charges scans answered by the deterministic
+	// fallback instead of an AI provider.
+	fallbackConsumesQuota bool
+}
+
+func NewUsageService(db *gorm.DB, subscriptions *SubscriptionService, cfg *config.Config) *UsageService {
+	return &UsageService{db: db, subscriptions: subscriptions, fallbackConsumesQuota: cfg.FallbackConsumesQuota}
 }
 
-func NewUsageService(db *gorm.DB) *UsageService {
-	return &UsageService{db: db}
+// withDB returns a copy of the service bound to db, typically an open
+// transaction.
+func (s *UsageService) withDB(db *gorm.DB) *UsageService {
+	return &UsageService{db: db, subscriptions: s.subscriptions, fallbackConsumesQuota: s.fallbackConsumesQuota}
 }
 
-// CheckAndIncrementUsage checks if the user can perform an analysis and increments the count.
-// Returns (allowed bool, currentCount int, err error).
-func (s *UsageService) CheckAndIncrementUsage(userID uuid.UUID) (bool, int, error) {
+// Reserve holds one of today's scans for userID and returns the usage date
+// to commit or release it against. Free users past the daily limit, counting
+// scans already reserved, get ErrUsageLimitReached. Call it inside a
+// transaction so the usage row stays locked until the caller commits.
+func (s *UsageService) Reserve(userID uuid.UUID) (time.Time, error) {
 	today := time.Now().UTC().Truncate(24 * time.Hour)
 
-	var usage models.DailyUsage
-	err := s.db.Where("user_id = ? AND date = ?", userID, today).First(&usage).Error
+	usage := models.DailyUsage{UserID: userID, Date: today}
+	if err := s.db.Clauses(clause.OnConflict{DoNothing: true}).Create(&usage).Error; err != nil {
+		return today, err
+	}
+	if err := s.db.Clauses(clause.Locking{Strength: "UPDATE"}).
+		Where("user_id = ? AND date = ?", userID, today).
+		First(&usage).Error; err != nil {
+		return today, err
+	}
 
-	if err == gorm.ErrRecordNotFound {
-		usage = models.DailyUsage{
-			UserID:        userID,
-			Date:          today,
-			AnalysisCount: 1,
+	if usage.AnalysisCount+usage.ReservedCount >= FreeDailyLimit {
+		premium, err := s.isPremium(userID)
+		if err != nil {
+			return today, err
 		}
-		if err := s.db.Create(&usage).Error; err != nil {
-			return false, 0, err
+		if !premium {
+			return today, ErrUsageLimitReached
 		}
-		return true, 1, nil
 	}
 
-	if err != nil {
-		return false, 0, err
-	}
+	err := s.db.Model(&usage).
+		UpdateColumn("reserved_count", gorm.Expr("reserved_count + 1")).Error
+	return today, err
+}
 
-	if usage.AnalysisCount < FreeDailyLimit {
-		usage.AnalysisCount++
-		if err := s.db.Save(&usage).Error; err != nil {
-			return false, 0, err
-		}
-		return true, usage.AnalysisCount, nil
-	}
+// Commit turns a reserved scan into a used one.
+func (s *UsageService) Commit(userID uuid.UUID, date time.Time) error {
+	return s.db.Model(&models.DailyUsage{}).
+		Where("user_id = ? AND date = ?", userID, date).
+		UpdateColumns(map[string]interface{}{
+			"reserved_count": gorm.Expr("GREATEST(reserved_count - 1, 0)"),
+			"analysis_count": gorm.Expr("analysis_count + 1"),
+		}).Error
+}
 
-	// Limit reached — check if user has active subscription
-	var sub models.Subscription
-	err = s.db.Where("user_id = ? AND status = ? AND current_period_end > ?", userID, "active", time.Now().UTC()).First(&sub).Error
-	if err == nil {
-		// Premium user — allow unlimited
-		usage.AnalysisCount++
-		if err := s.db.Save(&usage).Error; err != nil {
-			return false, 0, err
-		}
-		return true, usage.AnalysisCount, nil
-	}
+// Release gives a reserved scan back, for work that was never delivered.
+func (s *UsageService) Release(userID uuid.UUID, date time.Time) error {
+	return s.db.Model(&models.DailyUsage{}).
+		Where("user_id = ? AND date = ?", userID, date).
+		UpdateColumn("reserved_count", gorm.Expr("GREATEST(reserved_count - 1, 0)")).Error
+}
 
-	// Not subscribed — deny
-	return false, usage.AnalysisCount, nil
+// Settle commits or releases the scan reserved for analysis according to
+// the fallback policy, and reports whether the user was charged.
+func (s *UsageService) Settle(userID uuid.UUID, date time.Time, analysis *models.FaceAnalysis) (bool, error) {
+	if analysis.Source == models.AnalysisSourceDeterministic && !s.fallbackConsumesQuota {
+		return false, s.Release(userID, date)
+	}
+	return true, s.Commit(userID, date)
 }
 
-// GetRemainingUses returns the number of remaining free uses for today.
+// GetRemainingUses returns the number of remaining free uses for today,
+// with scans still in flight counted as used.
 // Returns -1 for premium users (unlimited).
 func (s *UsageService) GetRemainingUses(userID uuid.UUID) (int, bool, error) {
-	// Check subscription status
-	var sub models.Subscription
-	err := s.db.Where("user_id = ? AND status = ? AND current_period_end > ?", userID, "active", time.Now().UTC()).First(&sub).Error
-	isPremium := err == nil
-
+	isPremium, err := s.isPremium(userID)
+	if err != nil {
+		return 0, false, err
+	}
 	if isPremium {
 		return -1, true, nil
 	}
@@ -89,10 +117,20 @@ func (s *UsageService) GetRemainingUses(userID uuid.UUID) (int, bool, error) {
 		return 0, false, err
 	}
 
-	remaining := FreeDailyLimit - usage.AnalysisCount
+	remaining := FreeDailyLimit - usage.AnalysisCount - usage.ReservedCount
 	if remaining < 0 {
 		remaining = 0
 	}
 
 	return remaining, false, nil
 }
+
+// isPremium resolves the user's tier through the subscription service, so the
+// quota agrees with premium status and tier gating.
+func (s *UsageService) isPremium(userID uuid.UUID) (bool, error) {
+	code, err := s.subscriptions.TierCode(userID)
+	if err != nil {
+		return false, err
+	}
+	return code != TierFree, nil
+}
@@ -0,0 +1,31 @@
+package storage
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/ahmetcoskunkizilkaya/mewify/backend/internal/config"
+)
+
+// NewFromConfig builds the store selected by STORAGE_DRIVER.
+func NewFromConfig(cfg *config.Config) (BlobStore, error) {
+	switch strings.ToLower(strings.TrimSpace(cfg.StorageDriver)) {
+	case "", "local":
+		secret := cfg.StorageSigningSecret
+		if secret == "" {
+			secret = cfg.JWTSecret
+		}
+		return NewLocalStore(cfg.StorageLocalPath, strings.TrimRight(cfg.StoragePublicURL, "/")+"/api/blobs", secret)
+	case "s3":
+		return NewS3Store(S3Options{
+			Endpoint:  cfg.S3Endpoint,
+			Region:    cfg.S3Region,
+			Bucket:    cfg.S3Bucket,
+			AccessKey: cfg.S3AccessKey,
+			SecretKey: cfg.S3SecretKey,
+			PathStyle: cfg.S3PathStyle,
+		})
+	default:
+		return nil, fmt.Errorf("unknown storage driver %q", cfg.StorageDriver)
+	}
+}
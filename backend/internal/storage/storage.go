@@ -0,0 +1,30 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+var (
+	ErrBlobNotFound     = errors.New("blob not found")
+	ErrInvalidSignature = errors.New("invalid or expired signature")
+)
+
+// BlobStore keeps uploaded images. Objects are private; clients only ever see
+// them through expiring signed URLs.
+type BlobStore interface {
+	Put(ctx context.Context, key, contentType string, data []byte) error
+	// Delete removes an object. Deleting a missing object is not an error.
+	Delete(ctx context.Context, key string) error
+	SignedURL(ctx context.Context, key string, ttl time.Duration) (string, error)
+}
+
+// UserKey namespaces every object under its owner, e.g.
+// users/<user>/analyses/<id>.jpg.
+func UserKey(userID uuid.UUID, kind string, id uuid.UUID, ext string) string {
+	return fmt.Sprintf("users/%s/%s/%s%s", userID, kind, id, ext)
+}
@@ -38,6 +38,7 @@ import * as Haptics from 'expo-haptics';
 import Svg, { Circle, Defs, Stop, LinearGradient as SvgGradient } from 'react-native-svg';
 import ScoreRing from '../../../components/home/ScoreRing';
 import api from '../../../lib/api';
+import { runAnalysisJob } from '../../../lib/analysisJobs';
 import { useAuth } from '../../../contexts/AuthContext';
 import { hapticSuccess, hapticSelection, hapticError } from '../../../lib/haptics';
 import { evaluateImageQuality } from '../../../lib/imageQualityGate';
@@ -655,7 +656,7 @@ export default function HomeScreen() {
           const base64 = capturedAsset.base64 || (await FileSystem.readAsStringAsync(capturedAsset.uri, { encoding: 'base64' as any }));
           if (isGuest) await incrementGuestUsage();
 
-          const analysisResponse = await api.post('/analyses/ai', {
+          const { analysis, remainingUses } = await runAnalysisJob({
             image_base64: base64,
             quality_score: quality.score,
             quality_metrics: quality.metrics,
@@ -664,26 +665,16 @@ export default function HomeScreen() {
           hapticSuccess();
           Haptics.notificationAsync(Haptics.NotificationFeedbackType.Success);
 
-          if (analysisResponse.data.data?.id) {
+          if (analysis?.id) {
             if (isGuest) {
-              const normalized = normalizeGuestAnalysis(analysisResponse.data.data);
+              const normalized = normalizeGuestAnalysis(analysis);
               await saveGuestAnalysis(normalized);
             }
-
-            // Check for new achievements
-            if (analysisResponse.data.achievements?.length > 0) {
-              Alert.alert(
-                '🏆 Achievement Unlocked!',
-                analysisResponse.data.achievements.map((a: any) => a.name).join('\n'),
-                [{ text: 'Awesome!', onPress: () => router.push(`/(protected)/analysis/${analysisResponse.data.data.id}`) }]
-              );
-            } else {
-              router.push(`/(protected)/analysis/${analysisResponse.data.data.id}`);
-            }
+            router.push(`/(protected)/analysis/${analysis.id}`);
           }
 
-          if (analysisResponse.data.remaining_uses !== undefined) {
-            setRemainingUses(analysisResponse.data.remaining_uses);
+          if (remainingUses !== undefined) {
+            setRemainingUses(remainingUses);
           }
         } catch (err: any) {
           hapticError();
@@ -0,0 +1,53 @@
+import api from './api';
+
+const POLL_INTERVAL_MS = 1500;
+const MAX_WAIT_MS = 3 * 60 * 1000;
+
+const sleep = (ms: number) => new Promise((resolve) => setTimeout(resolve, ms));
+
+const newIdempotencyKey = () =>
+  `${Date.now().toString(36)}-${Math.random().toString(36).slice(2, 10)}`;
+
+/**
+ * Queues an AI analysis and polls its job until the result is ready.
+ * The submission is retried with the same idempotency key, so a dropped
+ * response never charges the scan twice.
+ */
+export async function runAnalysisJob(body: Record<string, unknown>) {
+  const idempotencyKey = newIdempotencyKey();
+  const submit = () => api.post('/analyses/ai', body, { headers: { 'Idempotency-Key': idempotencyKey } });
+
+  let submitted;
+  try {
+    submitted = await submit();
+  } catch (err: any) {
+    if (err.response) throw err;
+    submitted = await submit();
+  }
+
+  // A recently scanned photo comes back with the earlier result
+  if (submitted.data.data.status === 'succeeded') {
+    return { analysis: submitted.data.data.result, remainingUses: submitted.data.remaining_uses };
+  }
+
+  const jobId = submitted.data.data.id;
+  const startedAt = Date.now();
+  while (Date.now() - startedAt < MAX_WAIT_MS) {
+    await sleep(POLL_INTERVAL_MS);
+    let job;
+    try {
+      ({ data: { data: job } } = await api.get(`/analyses/jobs/${jobId}`));
+    } catch (err: any) {
+      // Keep polling through network blips; the job keeps running server-side
+      if (err.response) throw err;
+      continue;
+    }
+    if (job.status === 'succeeded') {
+      return { analysis: job.result, remainingUses: submitted.data.remaining_uses };
+    }
+    if (job.status === 'failed') {
+      throw new Error(job.error_message || 'Analysis failed');
+    }
+  }
+  throw new Error('Analysis timed out');
+}